@@ -0,0 +1,217 @@
+package accounts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"gokub/auth"
+	"gokub/utils"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dvgamerr-app/go-bitkub/bitkub"
+	"github.com/rs/zerolog/log"
+)
+
+const DefaultName = "default"
+
+type profile struct {
+	name   string
+	apiKey string
+	secret string
+}
+
+var (
+	mu          sync.Mutex
+	profiles    = map[string]*profile{}
+	defaultName string
+	active      string
+)
+
+// Init loads account profiles from the environment. BTK_APIKEY/BTK_SECRET
+// form the "default" profile, and every name listed in BTK_ACCOUNTS reads
+// BTK_APIKEY_<NAME>/BTK_SECRET_<NAME>. Profiles already added from the
+// keystore take precedence.
+func Init() error {
+	if key, secret := os.Getenv("BTK_APIKEY"), os.Getenv("BTK_SECRET"); key != "" && secret != "" && !exists(DefaultName) {
+		Add(DefaultName, key, secret)
+	}
+
+	for _, name := range strings.Split(os.Getenv("BTK_ACCOUNTS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || exists(name) {
+			continue
+		}
+
+		suffix := strings.ToUpper(name)
+		key, secret := os.Getenv("BTK_APIKEY_"+suffix), os.Getenv("BTK_SECRET_"+suffix)
+		if key == "" || secret == "" {
+			return fmt.Errorf("account %q: BTK_APIKEY_%s and BTK_SECRET_%s must be set", name, suffix, suffix)
+		}
+		Add(name, key, secret)
+	}
+
+	if len(profiles) == 0 {
+		log.Warn().Msg("No Bitkub account configured")
+		log.Info().Msg("Please set BTK_APIKEY and BTK_SECRET (or BTK_ACCOUNTS) to use Bitkub API features")
+		return nil
+	}
+
+	defaultName = strings.ToLower(os.Getenv("BTK_DEFAULT_ACCOUNT"))
+	if defaultName == "" {
+		if _, ok := profiles[DefaultName]; ok {
+			defaultName = DefaultName
+		} else {
+			defaultName = Names()[0]
+		}
+	}
+	if _, ok := profiles[defaultName]; !ok {
+		return fmt.Errorf("default account %q is not configured", defaultName)
+	}
+
+	if err := Use(defaultName, func() error { return nil }); err != nil {
+		return err
+	}
+
+	log.Info().Strs("accounts", Names()).Str("default", defaultName).Msg("Bitkub client initialized successfully")
+	return nil
+}
+
+func Add(name, apiKey, secret string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	profiles[name] = &profile{name: name, apiKey: apiKey, secret: secret}
+	if active == name {
+		active = ""
+	}
+}
+
+func exists(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	_, ok := profiles[name]
+	return ok
+}
+
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func Default() string {
+	return defaultName
+}
+
+// Resolve picks the account for a call: the requested name, else the first
+// account bound to the caller's token, else the server default. Tokens bound
+// to accounts may only use those accounts.
+func Resolve(ctx context.Context, requested string) (string, error) {
+	requested = strings.ToLower(strings.TrimSpace(requested))
+	allowed := Allowed(ctx)
+
+	name := requested
+	if name == "" {
+		name = defaultName
+		if len(allowed) > 0 {
+			name = allowed[0]
+		}
+	}
+
+	if name == "" {
+		return "", utils.NewError(utils.CodeAuthRequired, "no Bitkub account configured")
+	}
+	if !exists(name) {
+		return "", utils.NewError(utils.CodeInvalidArgument, "unknown account %q", name).With("available", Names())
+	}
+	if allowed != nil && !slices.Contains(allowed, name) {
+		return "", utils.NewError(utils.CodePermissionDenied, "account %q is not available to this session", name)
+	}
+
+	return name, nil
+}
+
+// Allowed returns the accounts the caller may use, or nil when it may use
+// all of them. OAuth tokens carry no account binding, so they are limited to
+// the default account.
+func Allowed(ctx context.Context) []string {
+	p := auth.FromContext(ctx)
+	switch {
+	case p == nil:
+		return nil
+	case len(p.Accounts) > 0:
+		return p.Accounts
+	case p.Method == auth.MethodOAuth:
+		return []string{defaultName}
+	}
+	return nil
+}
+
+// Permits reports whether the caller may see and manage orders and bots
+// that belong to account. An empty account is the default one.
+func Permits(ctx context.Context, account string) bool {
+	allowed := Allowed(ctx)
+	if account == "" {
+		account = defaultName
+	}
+	return allowed == nil || slices.Contains(allowed, account)
+}
+
+// Use runs fn with the go-bitkub client bound to the named account. The
+// client is process-global, so authenticated calls are serialized.
+func Use(name string, fn func() error) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	p, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown account %q", name)
+	}
+
+	if active != name {
+		if err := bitkub.Initlizer(p.apiKey, p.secret); err != nil {
+			return fmt.Errorf("initialize account %q: %w", name, err)
+		}
+		active = name
+	}
+
+	return fn()
+}
+
+// Sign adds the Bitkub API v3 authentication headers for the named account to
+// a request go-bitkub has no call for. body is the JSON payload, or nil for a
+// GET whose parameters are in the query string.
+func Sign(name string, req *http.Request, body []byte) error {
+	mu.Lock()
+	p, ok := profiles[name]
+	mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown account %q", name)
+	}
+
+	ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(p.secret))
+	mac.Write([]byte(ts + req.Method + req.URL.RequestURI()))
+	mac.Write(body)
+
+	req.Header.Set("X-BTK-APIKEY", p.apiKey)
+	req.Header.Set("X-BTK-TIMESTAMP", ts)
+	req.Header.Set("X-BTK-SIGN", hex.EncodeToString(mac.Sum(nil)))
+	return nil
+}
@@ -0,0 +1,373 @@
+package alerts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+type Condition string
+
+const (
+	PriceAbove  Condition = "price_above"
+	PriceBelow  Condition = "price_below"
+	PercentMove Condition = "percent_move"
+	SpreadAbove Condition = "spread_above"
+	RSIAbove    Condition = "rsi_above"
+	RSIBelow    Condition = "rsi_below"
+	Breakout    Condition = "breakout"
+)
+
+var Conditions = []Condition{PriceAbove, PriceBelow, PercentMove, SpreadAbove, RSIAbove, RSIBelow, Breakout}
+
+const (
+	StatusActive    = "active"
+	StatusTriggered = "triggered"
+)
+
+// Alert fires when its condition starts to hold, not on every poll while it
+// keeps holding. Threshold is the price, percent, RSI level or breakout volume
+// ratio depending on the condition.
+type Alert struct {
+	ID            string    `json:"id"`
+	Symbol        string    `json:"symbol"`
+	Condition     Condition `json:"condition"`
+	Threshold     float64   `json:"threshold"`
+	WindowMinutes int       `json:"window_minutes,omitempty"`
+	Resolution    int       `json:"resolution,omitempty"`
+	Period        int       `json:"period,omitempty"`
+	Repeat        bool      `json:"repeat"`
+	Note          string    `json:"note,omitempty"`
+	Status        string    `json:"status"`
+	CreatedAt     int64     `json:"created_at"`
+	CreatedBy     string    `json:"created_by,omitempty"`
+	Owner         string    `json:"owner,omitempty"`
+	Matched       bool      `json:"matched"`
+	LastValue     float64   `json:"last_value"`
+	LastCheckedAt int64     `json:"last_checked_at,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	TriggeredAt   int64     `json:"triggered_at,omitempty"`
+	TriggerCount  int       `json:"trigger_count"`
+}
+
+type Trigger struct {
+	AlertID     string    `json:"alert_id"`
+	Symbol      string    `json:"symbol"`
+	Condition   Condition `json:"condition"`
+	Threshold   float64   `json:"threshold"`
+	Value       float64   `json:"value"`
+	Message     string    `json:"message"`
+	Note        string    `json:"note,omitempty"`
+	Owner       string    `json:"owner,omitempty"`
+	TriggeredAt int64     `json:"triggered_at"`
+}
+
+// Result is one evaluation of an alert's condition.
+type Result struct {
+	Matched bool
+	Value   float64
+	Message string
+}
+
+type Evaluator func(ctx context.Context, a Alert) (Result, error)
+
+const recentTriggers = 50
+
+var (
+	mu         sync.RWMutex
+	alerts     = map[string]*Alert{}
+	triggers   []Trigger
+	handlers   []func(Trigger)
+	evaluate   Evaluator
+	alertsFile = "alerts.json"
+	interval   = 30 * time.Second
+)
+
+// Init loads persisted alerts and starts polling them with evaluator.
+func Init(evaluator Evaluator) {
+	if path := os.Getenv("ALERTS_FILE"); path != "" {
+		alertsFile = path
+	}
+	if d, err := time.ParseDuration(os.Getenv("ALERTS_POLL_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+
+	loaded, err := readAlertsFile()
+	if err != nil {
+		log.Error().Err(err).Str("file", alertsFile).Msg("Failed to load alerts")
+	}
+
+	mu.Lock()
+	evaluate = evaluator
+	for _, a := range loaded {
+		alerts[a.ID] = a
+	}
+	mu.Unlock()
+
+	if len(loaded) > 0 {
+		log.Info().Int("alerts", len(loaded)).Str("file", alertsFile).Msg("Alerts loaded")
+	}
+
+	go poll()
+}
+
+// OnTrigger registers fn to be called for every alert that fires.
+func OnTrigger(fn func(Trigger)) {
+	mu.Lock()
+	handlers = append(handlers, fn)
+	mu.Unlock()
+}
+
+func Interval() time.Duration {
+	return interval
+}
+
+func Add(a Alert) (Alert, error) {
+	id, err := newID()
+	if err != nil {
+		return Alert{}, err
+	}
+	a.ID = id
+	a.Status = StatusActive
+	a.CreatedAt = time.Now().Unix()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	alerts[a.ID] = &a
+	if err := writeAlertsFile(); err != nil {
+		delete(alerts, a.ID)
+		return Alert{}, err
+	}
+
+	log.Info().Str("alert", a.ID).Str("symbol", a.Symbol).Str("condition", string(a.Condition)).Float64("threshold", a.Threshold).Msg("Alert created")
+	return a, nil
+}
+
+func Get(id string) (Alert, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	a, ok := alerts[id]
+	if !ok {
+		return Alert{}, false
+	}
+	return *a, true
+}
+
+// List returns the alerts owner may see oldest first, optionally only those
+// for symbol.
+func List(owner, symbol string) []Alert {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if !Sees(owner, a.Owner) || symbol != "" && a.Symbol != symbol {
+			continue
+		}
+		list = append(list, *a)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt != list[j].CreatedAt {
+			return list[i].CreatedAt < list[j].CreatedAt
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+func Delete(id string) (Alert, bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	a, ok := alerts[id]
+	if !ok {
+		return Alert{}, false, nil
+	}
+	delete(alerts, id)
+	if err := writeAlertsFile(); err != nil {
+		alerts[id] = a
+		return Alert{}, true, err
+	}
+
+	log.Info().Str("alert", id).Msg("Alert deleted")
+	return *a, true, nil
+}
+
+// Recent returns the latest triggers owner may see, newest first.
+func Recent(owner string) []Trigger {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	recent := make([]Trigger, 0, len(triggers))
+	for i := len(triggers) - 1; i >= 0; i-- {
+		if Sees(owner, triggers[i].Owner) {
+			recent = append(recent, triggers[i])
+		}
+	}
+	return recent
+}
+
+func poll() {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		check()
+	}
+}
+
+func check() {
+	mu.RLock()
+	pending := make([]Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if a.Status == StatusActive {
+			pending = append(pending, *a)
+		}
+	}
+	eval := evaluate
+	mu.RUnlock()
+
+	if len(pending) == 0 || eval == nil {
+		return
+	}
+
+	var fired []Trigger
+	results := make(map[string]Result, len(pending))
+	failures := make(map[string]error)
+	for _, a := range pending {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		res, err := eval(ctx, a)
+		cancel()
+		if err != nil {
+			log.Warn().Err(err).Str("alert", a.ID).Str("symbol", a.Symbol).Msg("Failed to evaluate alert")
+			failures[a.ID] = err
+			continue
+		}
+		results[a.ID] = res
+	}
+
+	now := time.Now().Unix()
+
+	mu.Lock()
+	for id, err := range failures {
+		if a, ok := alerts[id]; ok {
+			a.LastCheckedAt = now
+			a.LastError = err.Error()
+		}
+	}
+	for id, res := range results {
+		a, ok := alerts[id]
+		if !ok || a.Status != StatusActive {
+			continue
+		}
+		wasMatched := a.Matched
+		a.Matched = res.Matched
+		a.LastValue = res.Value
+		a.LastCheckedAt = now
+		a.LastError = ""
+
+		if !res.Matched || wasMatched {
+			continue
+		}
+
+		a.TriggeredAt = now
+		a.TriggerCount++
+		if !a.Repeat {
+			a.Status = StatusTriggered
+		}
+		fired = append(fired, Trigger{
+			AlertID:     a.ID,
+			Symbol:      a.Symbol,
+			Condition:   a.Condition,
+			Threshold:   a.Threshold,
+			Value:       res.Value,
+			Message:     res.Message,
+			Note:        a.Note,
+			Owner:       a.Owner,
+			TriggeredAt: now,
+		})
+	}
+	triggers = append(triggers, fired...)
+	if len(triggers) > recentTriggers {
+		triggers = triggers[len(triggers)-recentTriggers:]
+	}
+	if err := writeAlertsFile(); err != nil {
+		log.Error().Err(err).Str("file", alertsFile).Msg("Failed to persist alerts")
+	}
+	notify := handlers
+	mu.Unlock()
+
+	for _, t := range fired {
+		log.Info().Str("alert", t.AlertID).Str("symbol", t.Symbol).Str("condition", string(t.Condition)).Float64("value", t.Value).Msg(t.Message)
+		for _, fn := range notify {
+			fn(t)
+		}
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate alert id: %w", err)
+	}
+	return "alt_" + hex.EncodeToString(b), nil
+}
+
+func readAlertsFile() ([]*Alert, error) {
+	data, err := os.ReadFile(alertsFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var list []*Alert
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode alerts: %w", err)
+	}
+	return list, nil
+}
+
+// writeAlertsFile must be called with mu held. The file is replaced
+// atomically so a crash mid-write never loses every alert.
+func writeAlertsFile() error {
+	if dir := filepath.Dir(alertsFile); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	list := make([]*Alert, 0, len(alerts))
+	for _, a := range alerts {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt < list[j].CreatedAt
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := alertsFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, alertsFile)
+}
@@ -0,0 +1,103 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"gokub/auth"
+	"gokub/notify"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const ResourceURI = "bitkub://alerts"
+
+var (
+	sessionsMu sync.Mutex
+	// sessionOwners maps each connected session to the principal that opened it.
+	sessionOwners = map[string]string{}
+)
+
+// Owner names the authenticated principal behind ctx, or "" for a caller
+// that did not authenticate (stdio, or HTTP with authentication disabled).
+func Owner(ctx context.Context) string {
+	if p := auth.FromContext(ctx); p != nil {
+		return p.Name
+	}
+	return ""
+}
+
+// Sees reports whether a caller identified as owner may see an alert created
+// by alertOwner. Callers that did not authenticate see every alert.
+func Sees(owner, alertOwner string) bool {
+	return owner == "" || owner == alertOwner
+}
+
+// TrackSessions records the principal behind each session as it connects, so
+// NotifySessions can reach only the sessions allowed to see an alert.
+func TrackSessions(hooks *server.Hooks) {
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+		sessionsMu.Lock()
+		sessionOwners[session.SessionID()] = Owner(ctx)
+		sessionsMu.Unlock()
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		sessionsMu.Lock()
+		delete(sessionOwners, session.SessionID())
+		sessionsMu.Unlock()
+	})
+}
+
+// NotifySessions pushes every trigger to the sessions that may see its alert
+// as a logging message and marks the alerts resource as updated, so clients
+// that subscribed to it can re-read the alert list.
+func NotifySessions(s *server.MCPServer) {
+	OnTrigger(func(t Trigger) {
+		sessionsMu.Lock()
+		var ids []string
+		for id, owner := range sessionOwners {
+			if Sees(owner, t.Owner) {
+				ids = append(ids, id)
+			}
+		}
+		sessionsMu.Unlock()
+
+		for _, id := range ids {
+			s.SendNotificationToSpecificClient(id, "notifications/message", map[string]any{
+				"level":  mcp.LoggingLevelNotice,
+				"logger": "alerts",
+				"data":   t,
+			})
+			s.SendNotificationToSpecificClient(id, mcp.MethodNotificationResourceUpdated, map[string]any{
+				"uri": ResourceURI,
+			})
+		}
+	})
+}
+
+// NotifySinks forwards triggers to the outbound notifier (webhooks, chat,
+// email) configured through NOTIFY_* settings.
+func NotifySinks() {
+	OnTrigger(func(t Trigger) {
+		title := fmt.Sprintf("%s %s", strings.ToUpper(t.Symbol), t.Condition)
+		if t.Note != "" {
+			title += " (" + t.Note + ")"
+		}
+		notify.Send(notify.Event{
+			Kind:    notify.KindAlert,
+			Title:   title,
+			Message: t.Message,
+			Symbol:  t.Symbol,
+			Fields: map[string]any{
+				"alert_id":  t.AlertID,
+				"condition": t.Condition,
+				"threshold": t.Threshold,
+				"value":     t.Value,
+			},
+			Time: time.Unix(t.TriggeredAt, 0),
+		})
+	})
+}
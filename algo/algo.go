@@ -0,0 +1,425 @@
+package algo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gokub/orders"
+	"gokub/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const (
+	TWAP = "twap"
+	VWAP = "vwap"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusPaused    = "paused"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+// A slice is written as pending before its order goes out, so a crash never
+// sends the same slice twice. A sent slice is placed until the exchange
+// confirms what it filled.
+const (
+	SlicePending     = "pending"
+	SlicePlaced      = "placed"
+	SliceFilled      = "filled"
+	SliceSkipped     = "skipped"
+	SliceFailed      = "failed"
+	SliceInterrupted = "interrupted"
+)
+
+// Slice is one child order. Estimate is the fill price expected from walking
+// the book just before it was sent; Filled, Price and THB are what the
+// exchange reports it executed.
+type Slice struct {
+	At        int64   `json:"at"`
+	Status    string  `json:"status"`
+	Amount    float64 `json:"amount"`
+	Estimate  float64 `json:"estimated_price,omitempty"`
+	Filled    float64 `json:"filled,omitempty"`
+	Price     float64 `json:"price,omitempty"`
+	THB       float64 `json:"thb,omitempty"`
+	MarketVol float64 `json:"market_volume,omitempty"`
+	OrderType string  `json:"order_type,omitempty"`
+	OrderID   string  `json:"order_id,omitempty"`
+	Note      string  `json:"note,omitempty"`
+}
+
+type Order struct {
+	ID       string  `json:"id"`
+	Strategy string  `json:"strategy"`
+	Symbol   string  `json:"symbol"`
+	Side     string  `json:"side"`
+	Amount   float64 `json:"amount"`
+	// LimitPrice caps what a buy pays and floors what a sell accepts; slices
+	// are sized to the liquidity inside it and sent as limit orders at it.
+	LimitPrice float64 `json:"limit_price,omitempty"`
+	StartAt    int64   `json:"start_at"`
+	EndAt      int64   `json:"end_at"`
+	// Slices and Randomize shape a TWAP: the target number of child orders
+	// and how much, in percent, each slice's size and timing is jittered.
+	Slices    int     `json:"slices,omitempty"`
+	Randomize float64 `json:"randomize_percent,omitempty"`
+	// Participation is the VWAP share of market volume, in percent, checked
+	// every Interval seconds.
+	Participation float64 `json:"participation_percent,omitempty"`
+	Interval      int64   `json:"interval_seconds"`
+	// Volume counted towards the participation target starts at VolumeSince,
+	// on top of VolumeBase already filled; both move on resume so a pause
+	// does not build up a backlog.
+	VolumeSince  int64   `json:"volume_since,omitempty"`
+	VolumeBase   float64 `json:"volume_base,omitempty"`
+	ArrivalPrice float64 `json:"arrival_price"`
+	Account      string  `json:"account"`
+	Note         string  `json:"note,omitempty"`
+	Status       string  `json:"status"`
+	CreatedAt    int64   `json:"created_at"`
+	CreatedBy    string  `json:"created_by,omitempty"`
+	NextSliceAt  int64   `json:"next_slice_at,omitempty"`
+	Filled       float64 `json:"filled"`
+	FilledTHB    float64 `json:"filled_thb"`
+	History      []Slice `json:"history"`
+	ClosedAt     int64   `json:"closed_at,omitempty"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+func (o Order) Remaining() float64 {
+	return max(o.Amount-o.Filled, 0)
+}
+
+func (o Order) Closed() bool {
+	return o.Status != StatusRunning && o.Status != StatusPaused
+}
+
+// inFlight reports whether the latest slice was sent and its fill is not
+// confirmed yet. No other slice is sized until it is.
+func (o Order) inFlight() bool {
+	n := len(o.History)
+	return n > 0 && o.History[n-1].Status == SlicePlaced
+}
+
+// sending reports whether the latest slice is being sent or in flight, so
+// what it fills is not known yet.
+func (o Order) sending() bool {
+	n := len(o.History)
+	return o.inFlight() || n > 0 && o.History[n-1].Status == SlicePending
+}
+
+// Report compares what the order achieved with the mid price when it was
+// created.
+type Report struct {
+	Filled        float64 `json:"filled"`
+	Remaining     float64 `json:"remaining"`
+	FilledPercent float64 `json:"filled_percent"`
+	FilledTHB     float64 `json:"filled_thb"`
+	AvgPrice      float64 `json:"avg_price"`
+	ArrivalPrice  float64 `json:"arrival_price"`
+	// SlippageBps is positive when the average price is worse than arrival
+	// for the order's side.
+	SlippageBps float64 `json:"slippage_bps"`
+	CostTHB     float64 `json:"cost_thb"`
+	SlicesDone  int     `json:"slices_filled"`
+	Skipped     int     `json:"slices_skipped"`
+	Failed      int     `json:"slices_failed"`
+	Elapsed     string  `json:"elapsed"`
+}
+
+func (o Order) Report() Report {
+	r := Report{
+		Filled:       o.Filled,
+		Remaining:    o.Remaining(),
+		FilledTHB:    o.FilledTHB,
+		ArrivalPrice: o.ArrivalPrice,
+	}
+	if o.Amount > 0 {
+		r.FilledPercent = o.Filled / o.Amount * 100
+	}
+	if o.Filled > 0 {
+		r.AvgPrice = utils.Round(o.FilledTHB / o.Filled)
+		r.SlippageBps = utils.Round(orders.SlippageBps(o.Side, r.AvgPrice, o.ArrivalPrice), 2)
+		r.CostTHB = utils.Round(r.SlippageBps/10000*o.ArrivalPrice*o.Filled, 2)
+	}
+	for _, s := range o.History {
+		switch s.Status {
+		case SliceFilled:
+			r.SlicesDone++
+		case SliceSkipped:
+			r.Skipped++
+		case SliceFailed, SliceInterrupted:
+			r.Failed++
+		}
+	}
+	end := time.Now().Unix()
+	if o.ClosedAt > 0 {
+		end = o.ClosedAt
+	}
+	r.Elapsed = (time.Duration(end-o.StartAt) * time.Second).String()
+	return r
+}
+
+// VolumeFunc returns the market volume, in coin, traded on symbol since t.
+type VolumeFunc func(ctx context.Context, symbol string, since time.Time) (float64, error)
+
+const historyLimit = 500
+
+var (
+	mu        sync.RWMutex
+	algos     = map[string]*Order{}
+	marketVol VolumeFunc
+	algoFile  = "algo.json"
+	interval  = 5 * time.Second
+)
+
+// Init loads persisted orders and starts slicing. An order caught while a
+// slice was being sent is paused so its fill can be checked before it goes
+// on; slices already placed are confirmed on the next poll.
+func Init(volume VolumeFunc) {
+	if path := os.Getenv("ALGO_FILE"); path != "" {
+		algoFile = path
+	}
+	if d, err := time.ParseDuration(os.Getenv("ALGO_POLL_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+
+	loaded, err := readAlgoFile()
+	if err != nil {
+		log.Error().Err(err).Str("file", algoFile).Msg("Failed to load algo orders")
+	}
+
+	mu.Lock()
+	marketVol = volume
+	for _, o := range loaded {
+		for i := range o.History {
+			if o.History[i].Status == SlicePending {
+				o.History[i].Status = SliceInterrupted
+				o.History[i].Note = "server stopped while the slice was in flight, check the order history"
+				if o.Status == StatusRunning {
+					o.Status, o.Reason = StatusPaused, "paused after restart: a slice was interrupted, check the account and resume with algo_pause"
+				}
+			}
+		}
+		algos[o.ID] = o
+	}
+	if len(loaded) > 0 {
+		if err := writeAlgoFile(); err != nil {
+			log.Error().Err(err).Str("file", algoFile).Msg("Failed to persist algo orders")
+		}
+	}
+	mu.Unlock()
+
+	if len(loaded) > 0 {
+		log.Info().Int("orders", len(loaded)).Str("file", algoFile).Msg("Algo orders loaded")
+	}
+
+	go run()
+}
+
+func Interval() time.Duration {
+	return interval
+}
+
+func Create(o Order) (Order, error) {
+	id, err := newID()
+	if err != nil {
+		return Order{}, err
+	}
+	now := time.Now().Unix()
+	o.ID = id
+	o.Status = StatusRunning
+	o.CreatedAt, o.StartAt = now, now
+	o.VolumeSince = now
+	o.NextSliceAt = now
+	o.History = []Slice{}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	algos[o.ID] = &o
+	if err := writeAlgoFile(); err != nil {
+		delete(algos, o.ID)
+		return Order{}, err
+	}
+
+	log.Info().Str("algo", o.ID).Str("strategy", o.Strategy).Str("symbol", o.Symbol).Str("side", o.Side).Float64("amount", o.Amount).Str("account", o.Account).Msg("Algo order created")
+	return o, nil
+}
+
+func Get(id string) (Order, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	o, ok := algos[id]
+	if !ok {
+		return Order{}, false
+	}
+	return clone(o), true
+}
+
+// List returns orders oldest first; active leaves out closed ones.
+func List(active bool) []Order {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]Order, 0, len(algos))
+	for _, o := range algos {
+		if active && o.Closed() {
+			continue
+		}
+		list = append(list, clone(o))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt != list[j].CreatedAt {
+			return list[i].CreatedAt < list[j].CreatedAt
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// SetPaused pauses or resumes an order. A resumed TWAP spreads what is left
+// over the time left; a resumed VWAP counts volume from now on.
+func SetPaused(id string, paused bool) (Order, bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	o, ok := algos[id]
+	if !ok {
+		return Order{}, false, nil
+	}
+	if o.Closed() {
+		return Order{}, true, fmt.Errorf("algo order %s is already %s", id, o.Status)
+	}
+	prev := clone(o)
+
+	now := time.Now().Unix()
+	if paused {
+		o.Status = StatusPaused
+	} else {
+		o.Status, o.Reason = StatusRunning, ""
+		o.NextSliceAt = now
+		o.VolumeSince, o.VolumeBase = now, o.Filled
+	}
+	if err := writeAlgoFile(); err != nil {
+		*o = prev
+		return Order{}, true, err
+	}
+
+	log.Info().Str("algo", id).Str("status", o.Status).Msg("Algo order updated")
+	return clone(o), true, nil
+}
+
+// Cancel stops an order. Filled slices are not undone. A slice in flight is
+// settled on the next poll, a resting limit slice cancelled first, and the
+// report goes out once its fill is known.
+func Cancel(id, reason string) (Order, bool, error) {
+	mu.Lock()
+	o, ok := algos[id]
+	if !ok {
+		mu.Unlock()
+		return Order{}, false, nil
+	}
+	if o.Closed() {
+		mu.Unlock()
+		return Order{}, true, fmt.Errorf("algo order %s is already %s", id, o.Status)
+	}
+	prev := clone(o)
+	finish(o, StatusCancelled, reason)
+	if err := writeAlgoFile(); err != nil {
+		*o = prev
+		mu.Unlock()
+		return Order{}, true, err
+	}
+	snapshot := clone(o)
+	mu.Unlock()
+
+	log.Info().Str("algo", id).Msg("Algo order cancelled")
+	if !snapshot.sending() {
+		announce(snapshot)
+	}
+	return snapshot, true, nil
+}
+
+// finish must be called with mu held.
+func finish(o *Order, status, reason string) {
+	o.Status = status
+	o.ClosedAt = time.Now().Unix()
+	o.NextSliceAt = 0
+	if reason != "" {
+		o.Reason = reason
+	}
+}
+
+func clone(o *Order) Order {
+	c := *o
+	c.History = append([]Slice{}, o.History...)
+	return c
+}
+
+func newID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate algo id: %w", err)
+	}
+	return "alg_" + hex.EncodeToString(b), nil
+}
+
+func readAlgoFile() ([]*Order, error) {
+	data, err := os.ReadFile(algoFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var list []*Order
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode algo orders: %w", err)
+	}
+	return list, nil
+}
+
+// writeAlgoFile must be called with mu held. The file is replaced atomically.
+func writeAlgoFile() error {
+	if dir := filepath.Dir(algoFile); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	list := make([]*Order, 0, len(algos))
+	for _, o := range algos {
+		list = append(list, o)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt < list[j].CreatedAt
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := algoFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, algoFile)
+}
@@ -0,0 +1,458 @@
+package algo
+
+import (
+	"context"
+	"fmt"
+	"gokub/killswitch"
+	"gokub/notify"
+	"gokub/orders"
+	"gokub/symbols"
+	"gokub/tracing"
+	"gokub/utils"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	"github.com/rs/zerolog/log"
+)
+
+func run() {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		confirm(ctx, time.Now())
+		cancel()
+		for _, id := range due(time.Now()) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			step(ctx, id)
+			cancel()
+		}
+	}
+}
+
+// due lists running orders whose next slice time has passed. While trading
+// is halted nothing is sliced, and orders whose end passes meanwhile expire.
+func due(now time.Time) []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	halted := killswitch.IsHalted()
+	var ids []string
+	var expired []Order
+	for _, o := range algos {
+		if o.Status != StatusRunning || o.NextSliceAt == 0 || now.Unix() < o.NextSliceAt || o.inFlight() {
+			continue
+		}
+		if !halted {
+			ids = append(ids, o.ID)
+			continue
+		}
+		if o.EndAt > 0 && now.Unix() >= o.EndAt {
+			finish(o, StatusExpired, "trading halted through the end time: "+killswitch.Status().Reason)
+			expired = append(expired, clone(o))
+		}
+	}
+	if len(expired) > 0 {
+		if err := writeAlgoFile(); err != nil {
+			log.Error().Err(err).Str("file", algoFile).Msg("Failed to persist algo orders")
+		}
+	}
+	for _, o := range expired {
+		go announce(o)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// step sizes and sends one slice of an order, then schedules the next.
+func step(ctx context.Context, id string) {
+	o, ok := Get(id)
+	if !ok || o.Status != StatusRunning {
+		return
+	}
+	now := time.Now()
+
+	pair, ok := symbols.Get(o.Symbol)
+	if !ok {
+		stop(id, StatusExpired, fmt.Sprintf("%s is no longer listed", strings.ToUpper(o.Symbol)))
+		return
+	}
+
+	depth, err := tracing.Exchange(ctx, "market.GetDepth", o.Symbol, func() (*market.Depth, error) {
+		return market.GetDepth(o.Symbol, 100)
+	})
+	if err != nil || depth == nil {
+		log.Warn().Err(err).Str("algo", id).Msg("Failed to read order book for algo slice")
+		reschedule(id, now, o.Interval)
+		return
+	}
+	levels := depth.Asks
+	if o.Side == orders.Sell {
+		levels = depth.Bids
+	}
+	if len(levels) == 0 || len(levels[0]) < 2 {
+		reschedule(id, now, o.Interval)
+		return
+	}
+	touch := levels[0][0]
+
+	remaining := pair.SnapAmount(o.Remaining())
+	if remaining < pair.AmountStep() || remaining*touch < pair.MinOrderValue() {
+		stop(id, StatusCompleted, fmt.Sprintf("remaining %.8g is below the %.2f THB minimum order", o.Remaining(), pair.MinOrderValue()))
+		return
+	}
+
+	var size, volume float64
+	var wait int64
+	switch o.Strategy {
+	case TWAP:
+		size, wait = twapSlice(o, now, remaining)
+	case VWAP:
+		vol := marketVol
+		if vol == nil {
+			return
+		}
+		volume, err = vol(ctx, o.Symbol, time.Unix(o.VolumeSince, 0))
+		if err != nil {
+			log.Warn().Err(err).Str("algo", id).Msg("Failed to read market volume for algo slice")
+			reschedule(id, now, o.Interval)
+			return
+		}
+		size, wait = o.VolumeBase+volume*o.Participation/100-o.Filled, o.Interval
+	}
+	size = pair.SnapAmount(min(size, remaining))
+	last := o.EndAt > 0 && now.Unix() >= o.EndAt
+
+	if size*touch < pair.MinOrderValue() {
+		if o.Strategy == VWAP {
+			// Not enough volume has traded yet for a slice worth sending.
+			if last {
+				stop(id, StatusExpired, "end time reached before the participation target filled the order")
+				return
+			}
+			reschedule(id, now, wait)
+			return
+		}
+		size = pair.SnapAmount(min(math.Ceil(pair.MinOrderValue()/touch/pair.AmountStep())*pair.AmountStep(), remaining))
+	}
+
+	slice := Slice{At: now.Unix(), Status: SlicePending, Amount: size, MarketVol: volume}
+	fill := orders.WalkBook(inside(levels, o.Side, o.LimitPrice), size, false)
+	if fill.Coin < size {
+		size = pair.SnapAmount(fill.Coin)
+		if size*fill.VWAP() < pair.MinOrderValue() {
+			slice.Status = SliceSkipped
+			switch {
+			case o.LimitPrice <= 0:
+				slice.Note = fmt.Sprintf("the book holds only %.8g", fill.Coin)
+			case fill.Coin > 0:
+				slice.Note = fmt.Sprintf("only %.8g available inside the limit %.8g", fill.Coin, o.LimitPrice)
+			default:
+				slice.Note = fmt.Sprintf("best price %.8g is beyond the limit %.8g", touch, o.LimitPrice)
+			}
+		} else {
+			slice.Amount = size
+			fill = orders.WalkBook(levels, size, false)
+			slice.Note = "reduced to the size the book holds"
+			if o.LimitPrice > 0 {
+				slice.Note = "reduced to the size available inside the limit"
+			}
+		}
+	}
+	slice.Estimate = utils.Round(fill.VWAP())
+
+	if slice.Status == SliceSkipped {
+		record(id, slice, now, wait, last)
+		return
+	}
+
+	// A limit order cannot trade past the limit even if the book moves
+	// before it arrives. What it leaves unfilled is cancelled before the
+	// next slice.
+	req := orders.Request{Symbol: o.Symbol, Side: o.Side, Type: orders.Market, Rate: slice.Estimate, Amount: slice.Amount}
+	if o.LimitPrice > 0 {
+		req.Type, req.Rate = orders.Limit, o.LimitPrice
+	}
+	slice.OrderType = req.Type
+
+	// Claim the slice on disk before sending it.
+	if !claim(id, slice) {
+		return
+	}
+
+	if terr := pair.CheckOrder(o.Side, req.Rate, slice.Amount); terr != nil {
+		slice.Status, slice.Note = SliceFailed, terr.Message
+	} else if placed, err := orders.Live(o.Account).Place(ctx, req); err != nil {
+		slice.Status, slice.Note = SliceFailed, err.Error()
+		log.Error().Err(err).Str("algo", id).Str("symbol", o.Symbol).Msg("Algo slice failed")
+	} else {
+		slice.Status, slice.OrderID = SlicePlaced, placed.ID
+		log.Info().Str("algo", id).Str("symbol", o.Symbol).Str("side", o.Side).Str("type", req.Type).Float64("amount", slice.Amount).Float64("estimate", slice.Estimate).Str("order", placed.ID).Msg("Algo slice placed")
+	}
+	record(id, slice, now, wait, last)
+
+	if slice.Status == SlicePlaced {
+		settle(ctx, id, o.Account, o.Symbol, o.Side, slice, false)
+	}
+}
+
+// confirm settles the slice each order has in flight. A slice still open once
+// the next one is due, or after its order stopped, is cancelled and settled
+// with what it filled.
+func confirm(ctx context.Context, now time.Time) {
+	type flight struct {
+		id, account, symbol, side string
+		slice                     Slice
+		due                       bool
+	}
+	mu.RLock()
+	var flights []flight
+	for _, o := range algos {
+		if !o.inFlight() {
+			continue
+		}
+		flights = append(flights, flight{
+			id: o.ID, account: o.Account, symbol: o.Symbol, side: o.Side,
+			slice: o.History[len(o.History)-1],
+			due:   o.Status != StatusRunning || now.Unix() >= o.NextSliceAt,
+		})
+	}
+	mu.RUnlock()
+
+	for _, f := range flights {
+		settle(ctx, f.id, f.account, f.symbol, f.side, f.slice, f.due)
+	}
+}
+
+// settle reads a placed slice back from the exchange and adds what it filled
+// to its order. An open slice is left in flight unless cancelOpen is set.
+func settle(ctx context.Context, id, account, symbol, side string, s Slice, cancelOpen bool) {
+	exchange := orders.Live(account)
+	info, err := exchange.Info(ctx, symbol, s.OrderID, side)
+	if err != nil {
+		log.Warn().Err(err).Str("algo", id).Str("order", s.OrderID).Msg("Failed to read algo slice, confirming later")
+		return
+	}
+	if info.Status == orders.StatusOpen {
+		if !cancelOpen {
+			return
+		}
+		if err := exchange.Cancel(ctx, symbol, s.OrderID, side); err != nil {
+			log.Warn().Err(err).Str("algo", id).Str("order", s.OrderID).Msg("Failed to cancel the rest of an algo slice")
+			return
+		}
+		if info, err = exchange.Info(ctx, symbol, s.OrderID, side); err != nil || info.Status == orders.StatusOpen {
+			log.Warn().Err(err).Str("algo", id).Str("order", s.OrderID).Msg("Algo slice still open after cancel, confirming later")
+			return
+		}
+	}
+
+	s.Filled, s.THB, s.Price = info.Filled, info.Filled*info.AvgPrice, utils.Round(info.AvgPrice)
+	switch {
+	case info.Filled <= 0:
+		s.Status, s.Note = SliceFailed, "order "+info.Status+" without a fill"
+	case info.Filled < s.Amount*(1-1e-9):
+		s.Status, s.Note = SliceFilled, fmt.Sprintf("filled %.8g of %.8g, the rest was cancelled", info.Filled, s.Amount)
+	default:
+		s.Status = SliceFilled
+	}
+
+	mu.Lock()
+	o, ok := algos[id]
+	if !ok || !o.inFlight() || o.History[len(o.History)-1].OrderID != s.OrderID {
+		mu.Unlock()
+		return
+	}
+	o.History[len(o.History)-1] = s
+	o.Filled += s.Filled
+	o.FilledTHB += s.THB
+
+	closed := o.Closed()
+	if o.Status == StatusRunning {
+		switch {
+		case o.Remaining() <= o.Amount*1e-9:
+			finish(o, StatusCompleted, "")
+			closed = true
+		case o.EndAt > 0 && time.Now().Unix() >= o.EndAt:
+			finish(o, StatusExpired, "end time reached with the order partly filled")
+			closed = true
+		}
+	}
+	if err := writeAlgoFile(); err != nil {
+		log.Error().Err(err).Str("file", algoFile).Msg("Failed to persist algo orders")
+	}
+	snapshot := clone(o)
+	mu.Unlock()
+
+	log.Info().Str("algo", id).Str("order", s.OrderID).Str("status", s.Status).Float64("filled", s.Filled).Float64("price", s.Price).Msg("Algo slice settled")
+	if closed {
+		log.Info().Str("algo", id).Str("status", snapshot.Status).Float64("filled", snapshot.Filled).Msg("Algo order finished")
+		announce(snapshot)
+	}
+}
+
+// twapSlice spreads what is left evenly over the slices left before the end
+// time, jittering the size and the wait by up to Randomize percent.
+func twapSlice(o Order, now time.Time, remaining float64) (float64, int64) {
+	left := o.EndAt - now.Unix()
+	if left <= 0 || o.Interval <= 0 {
+		return remaining, 0
+	}
+	slices := math.Ceil(float64(left) / float64(o.Interval))
+	if slices <= 1 {
+		return remaining, left
+	}
+	size := remaining / slices * jitter(o.Randomize)
+	wait := int64(math.Round(float64(o.Interval) * jitter(o.Randomize)))
+	return size, min(max(wait, 1), left)
+}
+
+func jitter(percent float64) float64 {
+	return 1 + (rand.Float64()*2-1)*percent/100
+}
+
+// inside keeps the book levels a slice may take without breaching the limit.
+func inside(levels [][]float64, side string, limit float64) [][]float64 {
+	if limit <= 0 {
+		return levels
+	}
+	for i, level := range levels {
+		if len(level) < 2 {
+			return levels[:i]
+		}
+		if (side == orders.Buy && level[0] > limit) || (side == orders.Sell && level[0] < limit) {
+			return levels[:i]
+		}
+	}
+	return levels
+}
+
+func claim(id string, s Slice) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	o, ok := algos[id]
+	if !ok || o.Status != StatusRunning {
+		return false
+	}
+	o.History = append(o.History, s)
+	if err := writeAlgoFile(); err != nil {
+		// Without a durable claim a restart could send the slice again.
+		log.Error().Err(err).Str("file", algoFile).Msg("Failed to persist algo slice claim, skipping this slice")
+		o.History = o.History[:len(o.History)-1]
+		return false
+	}
+	return true
+}
+
+// record stores a slice that was sent or given up on and schedules the next
+// one, closing the order once it is filled or its end time has passed.
+func record(id string, s Slice, now time.Time, wait int64, last bool) {
+	mu.Lock()
+	o, ok := algos[id]
+	if !ok {
+		mu.Unlock()
+		return
+	}
+	claimed := false
+	if n := len(o.History); n > 0 && o.History[n-1].At == s.At && o.History[n-1].Status == SlicePending {
+		o.History[n-1], claimed = s, true
+	} else {
+		o.History = append(o.History, s)
+	}
+	if len(o.History) > historyLimit {
+		o.History = o.History[len(o.History)-historyLimit:]
+	}
+
+	closed := false
+	switch {
+	case o.Status != StatusRunning:
+		// Cancelled or paused while the slice was being sent. Cancel left
+		// the report to whoever learns what the slice filled.
+		closed = o.Closed() && claimed && s.Status != SlicePlaced
+	case s.Status == SlicePlaced:
+		// The order closes, if at all, once settle knows what the slice filled.
+		o.NextSliceAt = nextAt(o, now, wait)
+	case o.Remaining() <= o.Amount*1e-9:
+		finish(o, StatusCompleted, "")
+		closed = true
+	case last:
+		finish(o, StatusExpired, "end time reached with the order partly filled")
+		closed = true
+	default:
+		o.NextSliceAt = nextAt(o, now, wait)
+	}
+	if err := writeAlgoFile(); err != nil {
+		log.Error().Err(err).Str("file", algoFile).Msg("Failed to persist algo orders")
+	}
+	snapshot := clone(o)
+	mu.Unlock()
+
+	if closed {
+		log.Info().Str("algo", id).Str("status", snapshot.Status).Float64("filled", snapshot.Filled).Msg("Algo order finished")
+		announce(snapshot)
+	}
+}
+
+func reschedule(id string, now time.Time, wait int64) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	o, ok := algos[id]
+	if !ok || o.Status != StatusRunning {
+		return
+	}
+	o.NextSliceAt = nextAt(o, now, wait)
+}
+
+func nextAt(o *Order, now time.Time, wait int64) int64 {
+	next := now.Unix() + max(wait, int64(interval/time.Second), 1)
+	if o.EndAt > 0 && next > o.EndAt && now.Unix() < o.EndAt {
+		next = o.EndAt
+	}
+	return next
+}
+
+// close finishes an order outside of a slice.
+func stop(id, status, reason string) {
+	mu.Lock()
+	o, ok := algos[id]
+	if !ok || o.Status != StatusRunning {
+		mu.Unlock()
+		return
+	}
+	finish(o, status, reason)
+	if err := writeAlgoFile(); err != nil {
+		log.Error().Err(err).Str("file", algoFile).Msg("Failed to persist algo orders")
+	}
+	snapshot := clone(o)
+	mu.Unlock()
+
+	log.Info().Str("algo", id).Str("status", status).Str("reason", reason).Msg("Algo order finished")
+	announce(snapshot)
+}
+
+func announce(o Order) {
+	r := o.Report()
+	symbol := strings.ToUpper(o.Symbol)
+	message := fmt.Sprintf("%s %s %s %s: filled %.8g of %.8g (%.1f%%)", strings.ToUpper(o.Strategy), o.Side, symbol, o.Status, r.Filled, o.Amount, r.FilledPercent)
+	if r.Filled > 0 {
+		message += fmt.Sprintf(", avg %.8g vs arrival %.8g (%+.1f bps)", r.AvgPrice, r.ArrivalPrice, r.SlippageBps)
+	}
+	if o.Reason != "" {
+		message += ", " + o.Reason
+	}
+
+	notify.Send(notify.Event{
+		Kind:    notify.KindTrade,
+		Title:   fmt.Sprintf("%s %s %s", strings.ToUpper(o.Strategy), o.ID, symbol),
+		Message: message,
+		Symbol:  o.Symbol,
+		Fields:  map[string]any{"algo_id": o.ID, "status": o.Status, "account": o.Account, "avg_price": r.AvgPrice, "slippage_bps": r.SlippageBps},
+	})
+}
@@ -0,0 +1,255 @@
+package audit
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+type Entry struct {
+	Timestamp int64          `json:"timestamp_ms"`
+	SessionID string         `json:"session_id,omitempty"`
+	Client    string         `json:"client,omitempty"`
+	Principal string         `json:"principal,omitempty"`
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Result    string         `json:"result,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	LatencyMS int64          `json:"latency_ms"`
+	Trading   bool           `json:"trading,omitempty"`
+	OrderIDs  []string       `json:"order_ids,omitempty"`
+}
+
+type Query struct {
+	Tool       string
+	SessionID  string
+	ErrorsOnly bool
+	Since      int64
+	Limit      int
+	// Principal and Accounts, when set, keep only that principal's calls on
+	// those accounts.
+	Principal string
+	Accounts  []string
+}
+
+var (
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	path           = "logs/audit.jsonl"
+	maxSize  int64 = 10 * 1024 * 1024
+	maxFiles       = 10
+)
+
+func Init() {
+	if p := os.Getenv("AUDIT_FILE"); p != "" {
+		path = p
+	}
+	if mb, err := strconv.Atoi(os.Getenv("AUDIT_MAX_SIZE_MB")); err == nil && mb > 0 {
+		maxSize = int64(mb) * 1024 * 1024
+	}
+	if n, err := strconv.Atoi(os.Getenv("AUDIT_MAX_FILES")); err == nil && n > 0 {
+		maxFiles = n
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := open(); err != nil {
+		log.Error().Err(err).Str("file", path).Msg("Failed to open audit log")
+		return
+	}
+
+	log.Info().Str("file", path).Msg("Audit log enabled")
+}
+
+func Write(entry *Entry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Error().Err(err).Str("tool", entry.Tool).Msg("Failed to encode audit entry")
+		return
+	}
+	data = append(data, '\n')
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if file == nil {
+		if err := open(); err != nil {
+			log.Error().Err(err).Str("file", path).Msg("Failed to open audit log")
+			return
+		}
+	}
+
+	if size+int64(len(data)) > maxSize && size > 0 {
+		if err := rotate(); err != nil {
+			log.Error().Err(err).Str("file", path).Msg("Failed to rotate audit log")
+		}
+	}
+
+	n, err := file.Write(data)
+	size += int64(n)
+	if err != nil {
+		log.Error().Err(err).Str("tool", entry.Tool).Msg("Failed to write audit entry")
+	}
+}
+
+func Recent(q Query) ([]*Entry, error) {
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+
+	mu.Lock()
+	files, err := logFiles()
+	mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*Entry{}
+	for _, f := range files {
+		matched, err := readEntries(f, q)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(matched, entries...)
+		if len(entries) >= q.Limit {
+			break
+		}
+	}
+
+	if len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+
+	return entries, nil
+}
+
+func open() error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	file = f
+	size = info.Size()
+	return nil
+}
+
+func rotate() error {
+	if err := file.Close(); err != nil {
+		return err
+	}
+	file = nil
+
+	ext := filepath.Ext(path)
+	rotated := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), time.Now().Format("20060102T150405.000"), ext)
+	if err := os.Rename(path, rotated); err != nil {
+		return err
+	}
+
+	if err := open(); err != nil {
+		return err
+	}
+
+	files, err := logFiles()
+	if err != nil {
+		return err
+	}
+
+	for i := maxFiles + 1; i < len(files); i++ {
+		if err := os.Remove(files[i]); err != nil {
+			log.Warn().Err(err).Str("file", files[i]).Msg("Failed to remove old audit log")
+		}
+	}
+
+	return nil
+}
+
+// logFiles returns the active log followed by rotated logs, newest first.
+func logFiles() ([]string, error) {
+	ext := filepath.Ext(path)
+	rotated, err := filepath.Glob(strings.TrimSuffix(path, ext) + "-*" + ext)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(rotated)))
+
+	files := []string{}
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+
+	return append(files, rotated...), nil
+}
+
+func readEntries(name string, q Query) ([]*Entry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []*Entry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		entry := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			continue
+		}
+
+		if q.Tool != "" && entry.Tool != q.Tool {
+			continue
+		}
+		if q.SessionID != "" && entry.SessionID != q.SessionID {
+			continue
+		}
+		if q.ErrorsOnly && entry.Error == "" {
+			continue
+		}
+		if q.Since > 0 && entry.Timestamp < q.Since {
+			continue
+		}
+		if q.Principal != "" && entry.Principal != q.Principal {
+			continue
+		}
+		if account, _ := entry.Arguments["account"].(string); len(q.Accounts) > 0 && account != "" && !slices.Contains(q.Accounts, strings.ToLower(account)) {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"context"
+	"gokub/auth"
+	"gokub/utils"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const maxResultSummary = 500
+
+type OrderIDer interface {
+	OrderIDs() []string
+}
+
+func Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		entry := &Entry{
+			Timestamp: start.UnixMilli(),
+			Tool:      request.Params.Name,
+			LatencyMS: time.Since(start).Milliseconds(),
+		}
+
+		if args, ok := request.Params.Arguments.(map[string]any); ok {
+			entry.Arguments = Redact(args)
+		}
+
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			entry.SessionID = session.SessionID()
+			if withInfo, ok := session.(server.SessionWithClientInfo); ok {
+				info := withInfo.GetClientInfo()
+				entry.Client = info.Name
+				if info.Version != "" {
+					entry.Client += "/" + info.Version
+				}
+			}
+		}
+
+		if p := auth.FromContext(ctx); p != nil {
+			entry.Principal = p.Name
+		}
+
+		if s := server.ServerFromContext(ctx); s != nil {
+			if tool := s.GetTool(request.Params.Name); tool != nil {
+				entry.Trading = utils.IsTradingTool(tool.Tool)
+			}
+		}
+
+		if err != nil {
+			entry.Error = err.Error()
+		}
+
+		if result != nil {
+			entry.Result = summarize(result)
+			if result.IsError && entry.Error == "" {
+				entry.Error = entry.Result
+			}
+			if ider, ok := result.StructuredContent.(OrderIDer); ok {
+				entry.OrderIDs = ider.OrderIDs()
+			}
+		}
+
+		Write(entry)
+
+		return result, err
+	}
+}
+
+func summarize(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			summary := []rune(text.Text)
+			if len(summary) > maxResultSummary {
+				return string(summary[:maxResultSummary]) + "…"
+			}
+			return string(summary)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var secretKeys = []string{"secret", "apikey", "api_key", "token", "password", "passphrase", "signature", "private"}
+
+func Redact(args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		if isSecretKey(k) {
+			out[k] = redacted
+			continue
+		}
+		out[k] = redactValue(v)
+	}
+
+	return out
+}
+
+func redactValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		return Redact(val)
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = redactValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
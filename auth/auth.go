@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+const (
+	MethodToken = "token"
+	MethodOAuth = "oauth"
+)
+
+type Principal struct {
+	Name     string   `json:"name"`
+	Method   string   `json:"method"`
+	Scopes   []string `json:"scopes"`
+	Accounts []string `json:"accounts,omitempty"`
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+func Enabled() bool {
+	return len(staticTokens) > 0 || oauth != nil
+}
+
+// Init loads the static tokens and OAuth settings. accountNames are the
+// configured account profiles a token may be bound to.
+func Init(accountNames []string) error {
+	if err := loadStaticTokens(os.Getenv("AUTH_TOKENS_FILE"), accountNames); err != nil {
+		return fmt.Errorf("load auth tokens: %w", err)
+	}
+
+	if err := initOAuth(); err != nil {
+		return fmt.Errorf("init oauth: %w", err)
+	}
+
+	if Enabled() {
+		log.Info().Int("static_tokens", len(staticTokens)).Bool("oauth", oauth != nil).Msg("HTTP authentication enabled")
+	}
+
+	return nil
+}
+
+func Authenticate(ctx context.Context, token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrUnauthorized
+	}
+
+	if p := lookupStaticToken(token); p != nil {
+		return p, nil
+	}
+
+	if oauth != nil {
+		return oauth.introspect(ctx, token)
+	}
+
+	return nil, ErrUnauthorized
+}
+
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			challenge(w, "")
+			return
+		}
+
+		p, err := Authenticate(r.Context(), strings.TrimSpace(token))
+		if err != nil {
+			log.Warn().Err(err).Str("remote", r.RemoteAddr).Str("path", r.URL.Path).Msg("Rejected HTTP request")
+			challenge(w, "invalid_token")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+func challenge(w http.ResponseWriter, errCode string) {
+	value := `Bearer realm="gokub"`
+	if oauth != nil {
+		value += fmt.Sprintf(`, resource_metadata="%s"`, oauth.metadataURL())
+	}
+	if errCode != "" {
+		value += fmt.Sprintf(`, error="%s"`, errCode)
+	}
+
+	w.Header().Set("WWW-Authenticate", value)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
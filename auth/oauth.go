@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"gokub/metrics"
+	"gokub/utils"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const introspectionCacheTTL = time.Minute
+
+type oauthConfig struct {
+	resource             string
+	authorizationServers []string
+	introspectionURL     string
+	clientID             string
+	clientSecret         string
+	client               *http.Client
+
+	mu    sync.Mutex
+	cache map[[32]byte]cachedPrincipal
+}
+
+type cachedPrincipal struct {
+	principal *Principal
+	expires   time.Time
+}
+
+type introspectionResponse struct {
+	Active   bool   `json:"active"`
+	Scope    string `json:"scope"`
+	Subject  string `json:"sub"`
+	ClientID string `json:"client_id"`
+	Expiry   int64  `json:"exp"`
+	Audience any    `json:"aud"`
+}
+
+var oauth *oauthConfig
+
+func initOAuth() error {
+	introspectionURL := os.Getenv("OAUTH_INTROSPECTION_URL")
+	if introspectionURL == "" {
+		return nil
+	}
+
+	resource := os.Getenv("OAUTH_RESOURCE")
+	if resource == "" {
+		return fmt.Errorf("OAUTH_RESOURCE is required when OAUTH_INTROSPECTION_URL is set")
+	}
+
+	servers := []string{}
+	for _, s := range strings.Split(os.Getenv("OAUTH_AUTHORIZATION_SERVERS"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			servers = append(servers, s)
+		}
+	}
+	if len(servers) == 0 {
+		return fmt.Errorf("OAUTH_AUTHORIZATION_SERVERS is required when OAUTH_INTROSPECTION_URL is set")
+	}
+
+	oauth = &oauthConfig{
+		resource:             strings.TrimSuffix(resource, "/"),
+		authorizationServers: servers,
+		introspectionURL:     introspectionURL,
+		clientID:             os.Getenv("OAUTH_CLIENT_ID"),
+		clientSecret:         os.Getenv("OAUTH_CLIENT_SECRET"),
+		client:               &http.Client{Timeout: 10 * time.Second},
+		cache:                map[[32]byte]cachedPrincipal{},
+	}
+
+	return nil
+}
+
+func (o *oauthConfig) metadataURL() string {
+	u, err := url.Parse(o.resource)
+	if err != nil {
+		return "/.well-known/oauth-protected-resource"
+	}
+	return fmt.Sprintf("%s://%s/.well-known/oauth-protected-resource%s", u.Scheme, u.Host, u.Path)
+}
+
+func (o *oauthConfig) introspect(ctx context.Context, token string) (*Principal, error) {
+	key := sha256.Sum256([]byte(token))
+
+	o.mu.Lock()
+	cached, ok := o.cache[key]
+	o.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		metrics.CacheHit("oauth_introspection")
+		return cached.principal, nil
+	}
+	metrics.CacheMiss("oauth_introspection")
+
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.introspectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(o.clientID), url.QueryEscape(o.clientSecret))
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection status %d", resp.StatusCode)
+	}
+
+	body := introspectionResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("introspection response: %w", err)
+	}
+
+	if !body.Active {
+		return nil, ErrUnauthorized
+	}
+
+	expires := time.Now().Add(introspectionCacheTTL)
+	if body.Expiry > 0 {
+		exp := time.Unix(body.Expiry, 0)
+		if !time.Now().Before(exp) {
+			return nil, ErrUnauthorized
+		}
+		if exp.Before(expires) {
+			expires = exp
+		}
+	}
+
+	if !o.audienceMatches(body.Audience) {
+		log.Warn().Any("aud", body.Audience).Str("resource", o.resource).Msg("OAuth token issued for another resource")
+		return nil, ErrUnauthorized
+	}
+
+	scopes := []string{}
+	for _, scope := range strings.Fields(body.Scope) {
+		if validScope(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	name := body.Subject
+	if name == "" {
+		name = body.ClientID
+	}
+
+	p := &Principal{Name: name, Method: MethodOAuth, Scopes: scopes}
+
+	o.mu.Lock()
+	o.cache[key] = cachedPrincipal{principal: p, expires: expires}
+	for k, v := range o.cache {
+		if time.Now().After(v.expires) {
+			delete(o.cache, k)
+		}
+	}
+	o.mu.Unlock()
+
+	return p, nil
+}
+
+func (o *oauthConfig) audienceMatches(aud any) bool {
+	matches := func(s string) bool {
+		return strings.TrimSuffix(s, "/") == o.resource
+	}
+
+	switch v := aud.(type) {
+	case string:
+		return matches(v)
+	case []any:
+		for _, a := range v {
+			if s, ok := a.(string); ok && matches(s) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func ProtectedResourceHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if oauth == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"resource":                 oauth.resource,
+			"authorization_servers":    oauth.authorizationServers,
+			"scopes_supported":         []string{utils.ScopeMarket, utils.ScopeAccount, utils.ScopeTrade, utils.ScopeAdmin},
+			"bearer_methods_supported": []string{"header"},
+		})
+	})
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"gokub/utils"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+func allowed(ctx context.Context, tool mcp.Tool) bool {
+	p := FromContext(ctx)
+	if p == nil {
+		return true
+	}
+	return p.HasScope(utils.ToolScope(tool))
+}
+
+func ToolFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	filtered := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if allowed(ctx, tool) {
+			filtered = append(filtered, tool)
+		}
+	}
+	return filtered
+}
+
+func ToolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		s := server.ServerFromContext(ctx)
+		if s == nil {
+			return next(ctx, request)
+		}
+
+		if tool := s.GetTool(request.Params.Name); tool != nil && !allowed(ctx, tool.Tool) {
+			p := FromContext(ctx)
+			log.Warn().Str("tool", request.Params.Name).Str("principal", p.Name).Msg("Tool call outside token scopes")
+			return utils.NewError(utils.CodePermissionDenied, "token lacks %s scope for %s", utils.ToolScope(tool.Tool), request.Params.Name).
+				With("required_scope", utils.ToolScope(tool.Tool)).
+				Result()
+		}
+
+		return next(ctx, request)
+	}
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gokub/utils"
+	"os"
+	"slices"
+	"strings"
+)
+
+type tokenConfig struct {
+	Name     string   `json:"name"`
+	Hash     string   `json:"hash"`
+	Scopes   []string `json:"scopes"`
+	Accounts []string `json:"accounts,omitempty"`
+}
+
+type tokensFile struct {
+	Tokens []tokenConfig `json:"tokens"`
+}
+
+type staticToken struct {
+	hash      []byte
+	principal *Principal
+}
+
+var staticTokens []staticToken
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func loadStaticTokens(path string, accountNames []string) error {
+	if path == "" {
+		path = "auth.json"
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	cfg := tokensFile{}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+
+	staticTokens = staticTokens[:0]
+	for _, t := range cfg.Tokens {
+		hexHash, ok := strings.CutPrefix(t.Hash, "sha256:")
+		if !ok {
+			return fmt.Errorf("token %q: hash must be sha256:<hex>", t.Name)
+		}
+
+		hash, err := hex.DecodeString(hexHash)
+		if err != nil || len(hash) != sha256.Size {
+			return fmt.Errorf("token %q: invalid sha256 hash", t.Name)
+		}
+
+		for _, scope := range t.Scopes {
+			if !validScope(scope) {
+				return fmt.Errorf("token %q: unknown scope %q", t.Name, scope)
+			}
+		}
+
+		// Account profiles are lowercase, so the binding is matched the same way.
+		bound := make([]string, 0, len(t.Accounts))
+		for _, name := range t.Accounts {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if !slices.Contains(accountNames, name) {
+				return fmt.Errorf("token %q: account %q is not configured", t.Name, name)
+			}
+			bound = append(bound, name)
+		}
+
+		staticTokens = append(staticTokens, staticToken{
+			hash: hash,
+			principal: &Principal{
+				Name:     t.Name,
+				Method:   MethodToken,
+				Scopes:   t.Scopes,
+				Accounts: bound,
+			},
+		})
+	}
+
+	return nil
+}
+
+func lookupStaticToken(token string) *Principal {
+	sum := sha256.Sum256([]byte(token))
+
+	var found *Principal
+	for _, t := range staticTokens {
+		if subtle.ConstantTimeCompare(sum[:], t.hash) == 1 {
+			found = t.principal
+		}
+	}
+
+	return found
+}
+
+func validScope(scope string) bool {
+	switch scope {
+	case utils.ScopeMarket, utils.ScopeAccount, utils.ScopeTrade, utils.ScopeAdmin:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package conditional
+
+import (
+	"fmt"
+	"gokub/symbols"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Limit is a bracket entry sent to the exchange up front as a limit order and
+// tracked here until it fills.
+const Limit = "limit"
+
+// StatusWaiting marks bracket exits held back until their entry fills.
+const StatusWaiting = "waiting"
+
+// AddBracket stores an entry with its exits, which should share an OCO group.
+// The exits wait until the entry fills. An entry that already has an exchange
+// order id is tracked as open until its fill is read back from the exchange;
+// one with status filled arms the exits at once.
+func AddBracket(entry Order, exits ...Order) ([]Order, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now().Unix()
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	entry.ID, entry.CreatedAt = id, now
+	switch {
+	case entry.Status == StatusFilled:
+		entry.ClosedAt = now
+	case entry.OrderID != "":
+		entry.Status = StatusOpen
+	default:
+		entry.Status = StatusPending
+	}
+
+	added := []Order{entry}
+	for _, o := range exits {
+		if o.ID, err = newID(); err != nil {
+			return nil, err
+		}
+		o.Parent, o.CreatedAt = entry.ID, now
+		o.Status = StatusWaiting
+		if entry.Status == StatusFilled {
+			o.Status = StatusPending
+		}
+		added = append(added, o)
+	}
+
+	for _, o := range added {
+		conds[o.ID] = &o
+	}
+	if err := writeCondFile(); err != nil {
+		for _, o := range added {
+			delete(conds, o.ID)
+		}
+		return nil, err
+	}
+
+	log.Info().Str("conditional", entry.ID).Str("symbol", entry.Symbol).Str("type", entry.Type).Str("status", entry.Status).Float64("amount", entry.Amount).Int("exits", len(exits)).Msg("Bracket order created")
+	return added, nil
+}
+
+// settleChildren arms the exits of an entry for the amount it filled, or
+// cancels them when the entry closed without a fill. A take-profit keeps its
+// planned reward-to-risk from the price the entry filled at. It must be
+// called with mu held.
+func settleChildren(parent *Order) {
+	filled := parent.Filled
+	if parent.Status == StatusFilled && filled == 0 {
+		// Closed before fills were recorded.
+		filled = parent.Amount
+	}
+
+	var exits []*Order
+	stop := 0.0
+	for _, o := range conds {
+		if o.Parent != parent.ID || o.Status != StatusWaiting {
+			continue
+		}
+		exits = append(exits, o)
+		if o.Type == StopMarket || o.Type == StopLimit {
+			stop = o.TriggerPrice
+		}
+	}
+	planned := parent.LimitPrice
+	if planned == 0 {
+		planned = parent.TriggerPrice
+	}
+	pair, known := symbols.Get(parent.Symbol)
+
+	for _, o := range exits {
+		if filled <= 0 {
+			finish(o, StatusCancelled, fmt.Sprintf("entry %s %s", parent.ID, parent.Status))
+			continue
+		}
+		if filled != parent.Amount {
+			o.Amount *= filled / parent.Amount
+			if known {
+				o.Amount = pair.SnapAmount(o.Amount)
+			}
+		}
+		if fill := parent.FillPrice; o.Type == TakeProfit && o.LimitPrice == 0 && stop > 0 && planned > stop && fill > stop {
+			o.TriggerPrice = fill + (o.TriggerPrice-planned)/(planned-stop)*(fill-stop)
+			if known {
+				o.TriggerPrice = pair.SnapPrice(o.TriggerPrice, symbols.RoundDown)
+			}
+		}
+		o.Status = StatusPending
+		log.Info().Str("conditional", o.ID).Str("entry", parent.ID).Str("type", o.Type).Float64("trigger", o.TriggerPrice).Float64("amount", o.Amount).Msg("Bracket exit armed")
+	}
+}
@@ -0,0 +1,413 @@
+package conditional
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gokub/orders"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const (
+	StopMarket   = "stop_market"
+	StopLimit    = "stop_limit"
+	TakeProfit   = "take_profit"
+	TrailingStop = "trailing_stop"
+)
+
+var Types = []string{StopMarket, StopLimit, TakeProfit, TrailingStop}
+
+// An order is pending until its trigger is crossed, then triggering while the
+// real order is sent, then open until the exchange reports it filled or
+// cancelled. Triggering is persisted first so a crash never sends the same
+// order twice.
+const (
+	StatusPending    = "pending"
+	StatusTriggering = "triggering"
+	StatusOpen       = "open"
+	StatusFilled     = "filled"
+	StatusCancelled  = "cancelled"
+	StatusFailed     = "failed"
+	StatusUnknown    = "unknown"
+)
+
+type Order struct {
+	ID           string  `json:"id"`
+	Symbol       string  `json:"symbol"`
+	Side         string  `json:"side"`
+	Type         string  `json:"type"`
+	Amount       float64 `json:"amount"`
+	TriggerPrice float64 `json:"trigger_price"`
+	LimitPrice   float64 `json:"limit_price,omitempty"`
+	TrailPercent float64 `json:"trail_percent,omitempty"`
+	// TrailDistance is a fixed price distance, e.g. an ATR multiple.
+	TrailDistance float64 `json:"trail_distance,omitempty"`
+	Extreme       float64 `json:"extreme,omitempty"`
+	OCOGroup      string  `json:"oco_group,omitempty"`
+	Parent        string  `json:"parent,omitempty"`
+	Account       string  `json:"account"`
+	Note          string  `json:"note,omitempty"`
+	Status        string  `json:"status"`
+	CreatedAt     int64   `json:"created_at"`
+	CreatedBy     string  `json:"created_by,omitempty"`
+	LastPrice     float64 `json:"last_price,omitempty"`
+	TriggeredAt   int64   `json:"triggered_at,omitempty"`
+	TriggerFill   float64 `json:"triggered_at_price,omitempty"`
+	OrderID       string  `json:"order_id,omitempty"`
+	OrderType     string  `json:"order_type,omitempty"`
+	// Filled is the amount the exchange order matched, at FillPrice on
+	// average. An order cancelled on the exchange may have filled in part.
+	Filled    float64 `json:"filled,omitempty"`
+	FillPrice float64 `json:"fill_price,omitempty"`
+	ClosedAt  int64   `json:"closed_at,omitempty"`
+	Reason    string  `json:"reason,omitempty"`
+}
+
+// Active reports whether the order can still trigger.
+func (o Order) Active() bool {
+	return o.Status == StatusPending
+}
+
+// Closed reports whether the order is done: filled, cancelled, failed or
+// unknown.
+func (o Order) Closed() bool {
+	switch o.Status {
+	case StatusPending, StatusWaiting, StatusTriggering, StatusOpen:
+		return false
+	}
+	return true
+}
+
+// Crossed reports whether price has reached the trigger. Sell stops and buy
+// take-profits fire on the way down; sell take-profits and buy stops on the
+// way up.
+func (o Order) Crossed(price float64) bool {
+	falling := o.Side == orders.Sell
+	if o.Type == TakeProfit {
+		falling = !falling
+	}
+	if falling {
+		return price <= o.TriggerPrice
+	}
+	return price >= o.TriggerPrice
+}
+
+// trail moves a trailing stop's trigger behind the best price seen. It only
+// ever tightens.
+func (o *Order) trail(price float64) {
+	if o.Type != TrailingStop {
+		return
+	}
+	if o.Side == orders.Sell {
+		if price <= o.Extreme {
+			return
+		}
+		o.Extreme = price
+	} else {
+		if o.Extreme > 0 && price >= o.Extreme {
+			return
+		}
+		o.Extreme = price
+	}
+	o.TriggerPrice = trailTrigger(o.Side, o.Extreme, o.TrailPercent, o.TrailDistance)
+}
+
+func trailTrigger(side string, extreme, percent, distance float64) float64 {
+	if percent > 0 {
+		distance = extreme * percent / 100
+	}
+	if side == orders.Sell {
+		return extreme - distance
+	}
+	return extreme + distance
+}
+
+var (
+	mu       sync.RWMutex
+	conds    = map[string]*Order{}
+	condFile = "conditional.json"
+	interval = 5 * time.Second
+)
+
+// retained is how many finished orders are kept for list_conditional_orders.
+const retained = 200
+
+// Init loads persisted orders, reconciles the ones that were mid-flight with
+// the exchange and starts watching prices.
+func Init() {
+	if path := os.Getenv("CONDITIONAL_FILE"); path != "" {
+		condFile = path
+	}
+	if d, err := time.ParseDuration(os.Getenv("CONDITIONAL_POLL_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+
+	loaded, err := readCondFile()
+	if err != nil {
+		log.Error().Err(err).Str("file", condFile).Msg("Failed to load conditional orders")
+	}
+
+	mu.Lock()
+	for _, o := range loaded {
+		conds[o.ID] = o
+	}
+	mu.Unlock()
+
+	if len(loaded) > 0 {
+		log.Info().Int("orders", len(loaded)).Str("file", condFile).Msg("Conditional orders loaded")
+	}
+
+	go func() {
+		reconcile(context.Background(), true)
+		watch()
+	}()
+}
+
+func Interval() time.Duration {
+	return interval
+}
+
+// Add stores new orders. Orders passed together may share an OCO group.
+func Add(list ...Order) ([]Order, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now().Unix()
+	added := make([]Order, 0, len(list))
+	for _, o := range list {
+		id, err := newID()
+		if err != nil {
+			return nil, err
+		}
+		o.ID = id
+		o.Status = StatusPending
+		o.CreatedAt = now
+		if o.Type == TrailingStop && o.Extreme > 0 {
+			o.TriggerPrice = trailTrigger(o.Side, o.Extreme, o.TrailPercent, o.TrailDistance)
+		}
+		added = append(added, o)
+	}
+
+	for _, o := range added {
+		conds[o.ID] = &o
+	}
+	if err := writeCondFile(); err != nil {
+		for _, o := range added {
+			delete(conds, o.ID)
+		}
+		return nil, err
+	}
+
+	for _, o := range added {
+		log.Info().Str("conditional", o.ID).Str("symbol", o.Symbol).Str("type", o.Type).Str("side", o.Side).Float64("trigger", o.TriggerPrice).Str("oco", o.OCOGroup).Msg("Conditional order created")
+	}
+	return added, nil
+}
+
+// NewGroupID returns an id that links OCO siblings.
+func NewGroupID() (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	return "oco_" + id[len("cnd_"):], nil
+}
+
+func Get(id string) (Order, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	o, ok := conds[id]
+	if !ok {
+		return Order{}, false
+	}
+	return *o, true
+}
+
+// List returns orders oldest first; active leaves out closed ones.
+func List(symbol string, active bool) []Order {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]Order, 0, len(conds))
+	for _, o := range conds {
+		if (symbol != "" && o.Symbol != symbol) || (active && o.Closed()) {
+			continue
+		}
+		list = append(list, *o)
+	}
+	sortOrders(list)
+	return list
+}
+
+// Cancel stops a pending order, and its OCO siblings with it. A limit order
+// that was already placed is cancelled on the exchange too, and cancelling a
+// bracket entry cancels its exits, or arms them for what the entry filled.
+func Cancel(ctx context.Context, id, reason string) ([]Order, error) {
+	mu.Lock()
+	o, ok := conds[id]
+	if !ok {
+		mu.Unlock()
+		return nil, nil
+	}
+	if o.Status != StatusPending && o.Status != StatusWaiting && o.Status != StatusOpen {
+		mu.Unlock()
+		return nil, fmt.Errorf("conditional order %s is already %s", id, o.Status)
+	}
+
+	// Orders not on the exchange are cancelled here and now, so check cannot
+	// trigger them once the lock is released.
+	var cancelled []Order
+	var placed []*Order
+	for _, other := range conds {
+		sibling := o.OCOGroup != "" && other.OCOGroup == o.OCOGroup && (other.Active() || other.Status == StatusWaiting)
+		if other.ID != id && !sibling {
+			continue
+		}
+		if other.Status == StatusOpen && other.OrderID != "" {
+			placed = append(placed, other)
+			continue
+		}
+		finish(other, StatusCancelled, reason)
+		settleChildren(other)
+		cancelled = append(cancelled, *other)
+	}
+	mu.Unlock()
+
+	var errs []error
+	for _, t := range placed {
+		exchange := orders.Live(t.Account)
+		if err := exchange.Cancel(ctx, t.Symbol, t.OrderID, t.Side); err != nil {
+			errs = append(errs, fmt.Errorf("cancel %s on exchange: %w", t.OrderID, err))
+			continue
+		}
+		info, err := exchange.Info(ctx, t.Symbol, t.OrderID, t.Side)
+		if err != nil {
+			log.Warn().Err(err).Str("conditional", t.ID).Str("order", t.OrderID).Msg("Failed to read fills of cancelled order")
+		}
+
+		mu.Lock()
+		// reconcile may have closed it while the exchange was called.
+		if t.Status == StatusOpen {
+			if info != nil {
+				t.Filled, t.FillPrice = info.Filled, info.AvgPrice
+			}
+			finish(t, StatusCancelled, reason)
+			settleChildren(t)
+			cancelled = append(cancelled, *t)
+		}
+		mu.Unlock()
+	}
+
+	mu.Lock()
+	if err := writeCondFile(); err != nil {
+		errs = append(errs, err)
+	}
+	mu.Unlock()
+
+	sortOrders(cancelled)
+	return cancelled, errors.Join(errs...)
+}
+
+// finish must be called with mu held.
+func finish(o *Order, status, reason string) {
+	o.Status = status
+	o.ClosedAt = time.Now().Unix()
+	if reason != "" {
+		o.Reason = reason
+	}
+}
+
+func sortOrders(list []Order) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt != list[j].CreatedAt {
+			return list[i].CreatedAt < list[j].CreatedAt
+		}
+		return list[i].ID < list[j].ID
+	})
+}
+
+// prune drops the oldest finished orders beyond the retention limit. It must
+// be called with mu held.
+func prune() {
+	var done []*Order
+	for _, o := range conds {
+		if o.Closed() {
+			done = append(done, o)
+		}
+	}
+	if len(done) <= retained {
+		return
+	}
+	sort.Slice(done, func(i, j int) bool {
+		return done[i].ClosedAt < done[j].ClosedAt
+	})
+	for _, o := range done[:len(done)-retained] {
+		delete(conds, o.ID)
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate conditional order id: %w", err)
+	}
+	return "cnd_" + hex.EncodeToString(b), nil
+}
+
+func readCondFile() ([]*Order, error) {
+	data, err := os.ReadFile(condFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var list []*Order
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode conditional orders: %w", err)
+	}
+	return list, nil
+}
+
+// writeCondFile must be called with mu held. The file is replaced atomically.
+func writeCondFile() error {
+	prune()
+
+	if dir := filepath.Dir(condFile); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	list := make([]*Order, 0, len(conds))
+	for _, o := range conds {
+		list = append(list, o)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt < list[j].CreatedAt
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := condFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, condFile)
+}
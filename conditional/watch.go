@@ -0,0 +1,278 @@
+package conditional
+
+import (
+	"context"
+	"fmt"
+	"gokub/killswitch"
+	"gokub/notify"
+	"gokub/orders"
+	"gokub/symbols"
+	"gokub/tracing"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	"github.com/rs/zerolog/log"
+)
+
+func watch() {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval*4)
+		check(ctx)
+		reconcile(ctx, false)
+		cancel()
+	}
+}
+
+// check moves trailing stops and fires every pending order whose trigger the
+// last price crossed. Triggering an order cancels its OCO siblings.
+func check(ctx context.Context) {
+	mu.RLock()
+	active := 0
+	for _, o := range conds {
+		if o.Active() {
+			active++
+		}
+	}
+	mu.RUnlock()
+
+	// While halted nothing fires; orders stay pending until trading resumes.
+	if active == 0 || killswitch.IsHalted() {
+		return
+	}
+
+	tickers, err := tracing.Exchange(ctx, "market.GetTicker", "", func() ([]market.Ticker, error) {
+		return market.GetTicker("")
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to fetch prices for conditional orders")
+		return
+	}
+	prices := make(map[string]float64, len(tickers))
+	for _, t := range tickers {
+		prices[symbols.Normalize(t.Symbol)] = t.Last
+	}
+
+	now := time.Now().Unix()
+	var fired []*Order
+	changed := false
+
+	mu.Lock()
+	for _, o := range conds {
+		price, ok := prices[o.Symbol]
+		if !o.Active() || !ok || price <= 0 {
+			continue
+		}
+		o.LastPrice = price
+
+		trigger := o.TriggerPrice
+		o.trail(price)
+		changed = changed || o.TriggerPrice != trigger
+
+		if !o.Crossed(price) {
+			continue
+		}
+		o.Status, o.TriggeredAt, o.TriggerFill = StatusTriggering, now, price
+		fired = append(fired, o)
+		changed = true
+
+		if o.OCOGroup == "" {
+			continue
+		}
+		for _, sibling := range conds {
+			if sibling.ID != o.ID && sibling.OCOGroup == o.OCOGroup && sibling.Active() {
+				finish(sibling, StatusCancelled, "OCO sibling "+o.ID+" triggered")
+			}
+		}
+	}
+
+	if changed {
+		if err := writeCondFile(); err != nil {
+			// Without a durable triggering state a restart could send the
+			// order twice, so hold off until the file can be written.
+			log.Error().Err(err).Str("file", condFile).Msg("Failed to persist conditional orders, not firing triggers")
+			for _, o := range fired {
+				o.Status, o.TriggeredAt, o.TriggerFill = StatusPending, 0, 0
+			}
+			fired = nil
+		}
+	}
+	mu.Unlock()
+
+	for _, o := range fired {
+		place(ctx, o)
+	}
+}
+
+// place sends the real order for a triggered conditional order: a limit order
+// for stop-limits and take-profits with a limit price, market otherwise. The
+// order stays open until reconcile reads its fills from the exchange.
+func place(ctx context.Context, o *Order) {
+	mu.RLock()
+	req := orders.Request{Symbol: o.Symbol, Side: o.Side, Type: orders.Market, Rate: o.TriggerFill, Amount: o.Amount}
+	if o.LimitPrice > 0 {
+		req.Type, req.Rate = orders.Limit, o.LimitPrice
+	}
+	id, account, kind := o.ID, o.Account, o.Type
+	mu.RUnlock()
+
+	placed, err := orders.Live(account).Place(ctx, req)
+
+	mu.Lock()
+	if err != nil {
+		finish(o, StatusFailed, err.Error())
+	} else {
+		o.Status, o.OrderID, o.OrderType = StatusOpen, placed.ID, req.Type
+	}
+	if o.Closed() {
+		settleChildren(o)
+	}
+	if werr := writeCondFile(); werr != nil {
+		log.Error().Err(werr).Str("file", condFile).Msg("Failed to persist conditional orders")
+	}
+	snapshot := *o
+	mu.Unlock()
+
+	if err != nil {
+		log.Error().Err(err).Str("conditional", id).Str("symbol", req.Symbol).Str("side", req.Side).Msg("Conditional order trigger failed")
+	} else {
+		log.Info().Str("conditional", id).Str("symbol", req.Symbol).Str("type", kind).Str("side", req.Side).Float64("price", snapshot.TriggerFill).Str("order", placed.ID).Msg("Conditional order triggered")
+	}
+	announce(snapshot, err)
+}
+
+// reconcile checks placed orders against the exchange. An order that left the
+// book is looked up for how it ended: filled arms its bracket exits, and a
+// cancelled one closes with whatever part it filled. On startup it also
+// resolves orders caught mid-trigger by looking for a matching open order.
+// While trading is halted it waits, since the kill switch is cancelling
+// orders; their outcome is read once trading resumes.
+func reconcile(ctx context.Context, startup bool) {
+	if killswitch.IsHalted() {
+		return
+	}
+
+	type key struct{ account, symbol string }
+	type tracked struct {
+		order   *Order
+		status  string
+		orderID string
+		side    string
+	}
+	groups := map[key][]tracked{}
+
+	mu.RLock()
+	for _, o := range conds {
+		if o.Status == StatusOpen || (startup && o.Status == StatusTriggering) {
+			k := key{o.Account, o.Symbol}
+			groups[k] = append(groups[k], tracked{o, o.Status, o.OrderID, o.Side})
+		}
+	}
+	mu.RUnlock()
+
+	for k, list := range groups {
+		exchange := orders.Live(k.account)
+		open, err := exchange.Open(ctx, k.symbol)
+		if err != nil {
+			log.Warn().Err(err).Str("account", k.account).Str("symbol", k.symbol).Msg("Failed to reconcile conditional orders")
+			continue
+		}
+		ids := make(map[string]orders.Order, len(open))
+		for _, e := range open {
+			ids[e.ID] = e
+		}
+
+		infos := map[string]*orders.Info{}
+		for _, t := range list {
+			if _, ok := ids[t.orderID]; t.status != StatusOpen || ok {
+				continue
+			}
+			info, err := exchange.Info(ctx, k.symbol, t.orderID, t.side)
+			if err != nil {
+				log.Warn().Err(err).Str("conditional", t.order.ID).Str("order", t.orderID).Msg("Failed to read conditional order status")
+				continue
+			}
+			infos[t.orderID] = info
+		}
+
+		var closed []Order
+		mu.Lock()
+		for _, t := range list {
+			o := t.order
+			// Cancelled while the exchange was being read.
+			if o.Status != t.status || o.OrderID != t.orderID {
+				continue
+			}
+			switch o.Status {
+			case StatusOpen:
+				info, ok := infos[o.OrderID]
+				if !ok || info.Status == orders.StatusOpen {
+					continue
+				}
+				o.Filled, o.FillPrice = info.Filled, info.AvgPrice
+				if info.Status == orders.StatusFilled {
+					finish(o, StatusFilled, "")
+				} else {
+					reason := "cancelled on the exchange"
+					if info.Filled > 0 {
+						reason += fmt.Sprintf(" after filling %.8g of %.8g", info.Filled, o.Amount)
+					}
+					finish(o, StatusCancelled, reason)
+				}
+				settleChildren(o)
+				closed = append(closed, *o)
+			case StatusTriggering:
+				if match, ok := matchOpen(o, open); ok {
+					o.Status, o.OrderID, o.OrderType = StatusOpen, match.ID, orders.Limit
+					o.Reason = "recovered after restart: matched open order " + match.ID
+					delete(ids, match.ID)
+				} else {
+					finish(o, StatusUnknown, "server stopped while the order was being sent and no matching open order was found; check the account's order history")
+					settleChildren(o)
+				}
+			}
+		}
+		if err := writeCondFile(); err != nil {
+			log.Error().Err(err).Str("file", condFile).Msg("Failed to persist conditional orders")
+		}
+		mu.Unlock()
+
+		for _, o := range closed {
+			log.Info().Str("conditional", o.ID).Str("symbol", o.Symbol).Str("order", o.OrderID).Str("status", o.Status).Float64("filled", o.Filled).Float64("price", o.FillPrice).Msg("Conditional order closed on the exchange")
+		}
+	}
+}
+
+func matchOpen(o *Order, open []orders.Order) (orders.Order, bool) {
+	if o.LimitPrice <= 0 {
+		return orders.Order{}, false
+	}
+	for _, e := range open {
+		if e.Side == o.Side && math.Abs(e.Rate-o.LimitPrice) <= o.LimitPrice*1e-9 && math.Abs(e.Amount-o.Amount) <= o.Amount*1e-6 {
+			return e, true
+		}
+	}
+	return orders.Order{}, false
+}
+
+func announce(o Order, err error) {
+	symbol := strings.ToUpper(o.Symbol)
+	message := fmt.Sprintf("%s %s %.8g %s triggered at %.8g", strings.ReplaceAll(o.Type, "_", " "), o.Side, o.Amount, symbol, o.TriggerFill)
+	if err != nil {
+		message += ", order failed: " + err.Error()
+	} else if o.OrderType == orders.Limit {
+		message += fmt.Sprintf(", limit %.8g placed", o.LimitPrice)
+	}
+
+	notify.Send(notify.Event{
+		Kind:    notify.KindTrade,
+		Title:   fmt.Sprintf("%s %s", symbol, strings.ReplaceAll(o.Type, "_", " ")),
+		Message: message,
+		Symbol:  o.Symbol,
+		Fields:  map[string]any{"conditional_id": o.ID, "order_id": o.OrderID, "status": o.Status, "account": o.Account},
+	})
+}
@@ -0,0 +1,149 @@
+package dca
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Location is where schedules are read. Thailand keeps no daylight saving,
+// so a fixed +07:00 zone stands in when the tz database is missing.
+var Location = bangkok()
+
+func bangkok() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Bangkok"); err == nil {
+		return loc
+	}
+	return time.FixedZone("ICT", 7*60*60)
+}
+
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// Cron is a standard five-field schedule: minute, hour, day of month, month
+// and day of week. Fields take *, lists, ranges and steps (*/15, 1-5, 9,21).
+// As in cron, when both day fields are restricted either may match.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+func ParseCron(spec string) (*Cron, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := macros[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day month weekday), got %d", len(fields))
+	}
+
+	c := &Cron{}
+	var err error
+	if c.minute, err = parseField(fields[0], 0, 59, "minute"); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseField(fields[1], 0, 23, "hour"); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseField(fields[2], 1, 31, "day of month"); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseField(fields[3], 1, 12, "month"); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseField(fields[4], 0, 7, "day of week"); err != nil {
+		return nil, err
+	}
+	// 7 is Sunday too.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return c, nil
+}
+
+func parseField(field string, lo, hi int, name string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: invalid step %q", name, stepStr)
+			}
+			step = n
+		}
+
+		start, end := lo, hi
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var errA, errB error
+			start, errA = strconv.Atoi(a)
+			end, errB = strconv.Atoi(b)
+			if errA != nil || errB != nil || start > end {
+				return 0, fmt.Errorf("%s: invalid range %q", name, rng)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("%s: invalid value %q", name, rng)
+			}
+			start = n
+			if !hasStep {
+				end = n
+			}
+		}
+		if start < lo || end > hi {
+			return 0, fmt.Errorf("%s: %q is outside %d-%d", name, part, lo, hi)
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first slot strictly after t, in Location. It returns the
+// zero time when nothing matches within five years (e.g. 31 February).
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.In(Location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, Location)
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, Location)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, Location)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
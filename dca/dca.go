@@ -0,0 +1,630 @@
+package dca
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gokub/killswitch"
+	"gokub/notify"
+	"gokub/orders"
+	"gokub/symbols"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const (
+	StatusActive = "active"
+	StatusPaused = "paused"
+)
+
+const (
+	ExecPending     = "pending"
+	ExecPlaced      = "placed"
+	ExecFilled      = "filled"
+	ExecFailed      = "failed"
+	ExecMissed      = "missed"
+	ExecHalted      = "halted"
+	ExecInterrupted = "interrupted"
+)
+
+const (
+	DipRSI      = "rsi"
+	DipDrawdown = "drawdown"
+)
+
+// Dip scales a buy by Multiplier when the market is down: daily RSI(14) at
+// or below Threshold, or price at least Threshold percent under the 30-day
+// high.
+type Dip struct {
+	Indicator  string  `json:"indicator"`
+	Threshold  float64 `json:"threshold"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// DipResult is one reading of a plan's dip indicator.
+type DipResult struct {
+	Triggered bool
+	Value     float64
+	Message   string
+}
+
+type DipCheck func(ctx context.Context, p Plan) (DipResult, error)
+
+// Execution is one schedule slot. It is written as pending before the order
+// goes out, so a crash never leads to the same slot being bought twice. A
+// sent order is placed until the exchange confirms what it filled. Once it
+// has, Price, Coin, FeeTHB and SpentTHB are that fill.
+type Execution struct {
+	Slot       int64   `json:"slot"`
+	Status     string  `json:"status"`
+	ExecutedAt int64   `json:"executed_at,omitempty"`
+	AmountTHB  float64 `json:"amount_thb,omitempty"`
+	Multiplier float64 `json:"multiplier,omitempty"`
+	DipValue   float64 `json:"dip_value,omitempty"`
+	Price      float64 `json:"price,omitempty"`
+	Coin       float64 `json:"coin,omitempty"`
+	FeeTHB     float64 `json:"fee_thb,omitempty"`
+	SpentTHB   float64 `json:"spent_thb,omitempty"`
+	OrderID    string  `json:"order_id,omitempty"`
+	Skipped    int     `json:"skipped_slots,omitempty"`
+	Note       string  `json:"note,omitempty"`
+}
+
+type Plan struct {
+	ID         string      `json:"id"`
+	Symbol     string      `json:"symbol"`
+	AmountTHB  float64     `json:"amount_thb"`
+	Schedule   string      `json:"schedule"`
+	Dip        *Dip        `json:"dip,omitempty"`
+	Account    string      `json:"account"`
+	Status     string      `json:"status"`
+	CreatedAt  int64       `json:"created_at"`
+	CreatedBy  string      `json:"created_by,omitempty"`
+	NextRunAt  int64       `json:"next_run_at"`
+	LastRunAt  int64       `json:"last_run_at,omitempty"`
+	Buys       int         `json:"buys"`
+	SpentTHB   float64     `json:"spent_thb"`
+	BoughtCoin float64     `json:"bought_coin"`
+	History    []Execution `json:"history"`
+}
+
+// AvgPrice is the average cost of the coin bought so far.
+func (p Plan) AvgPrice() float64 {
+	if p.BoughtCoin <= 0 {
+		return 0
+	}
+	return p.SpentTHB / p.BoughtCoin
+}
+
+const historyLimit = 200
+
+var (
+	mu       sync.RWMutex
+	plans    = map[string]*Plan{}
+	checkDip DipCheck
+	dcaFile  = "dca.json"
+	interval = 30 * time.Second
+	grace    = time.Hour
+)
+
+// Init loads persisted plans and starts the scheduler. Slots missed while the
+// server was down are bought once if the latest is within DCA_MISSED_GRACE,
+// otherwise recorded as missed.
+func Init(dip DipCheck) {
+	if path := os.Getenv("DCA_FILE"); path != "" {
+		dcaFile = path
+	}
+	if d, err := time.ParseDuration(os.Getenv("DCA_POLL_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("DCA_MISSED_GRACE")); err == nil && d >= 0 {
+		grace = d
+	}
+
+	loaded, err := readDCAFile()
+	if err != nil {
+		log.Error().Err(err).Str("file", dcaFile).Msg("Failed to load DCA plans")
+	}
+
+	mu.Lock()
+	checkDip = dip
+	for _, p := range loaded {
+		for i := range p.History {
+			if p.History[i].Status == ExecPending {
+				p.History[i].Status = ExecInterrupted
+				p.History[i].Note = "server stopped while the buy was in flight, check the order history; the slot is not retried"
+			}
+		}
+		plans[p.ID] = p
+	}
+	if len(loaded) > 0 {
+		if err := writeDCAFile(); err != nil {
+			log.Error().Err(err).Str("file", dcaFile).Msg("Failed to persist DCA plans")
+		}
+	}
+	mu.Unlock()
+
+	if len(loaded) > 0 {
+		log.Info().Int("plans", len(loaded)).Str("file", dcaFile).Msg("DCA plans loaded")
+	}
+
+	go run()
+}
+
+func Interval() time.Duration {
+	return interval
+}
+
+func Create(p Plan) (Plan, error) {
+	cron, err := ParseCron(p.Schedule)
+	if err != nil {
+		return Plan{}, err
+	}
+	next := cron.Next(time.Now())
+	if next.IsZero() {
+		return Plan{}, fmt.Errorf("schedule %q never runs", p.Schedule)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Plan{}, err
+	}
+	p.ID = id
+	p.Status = StatusActive
+	p.CreatedAt = time.Now().Unix()
+	p.NextRunAt = next.Unix()
+	p.History = []Execution{}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	plans[p.ID] = &p
+	if err := writeDCAFile(); err != nil {
+		delete(plans, p.ID)
+		return Plan{}, err
+	}
+
+	log.Info().Str("dca", p.ID).Str("symbol", p.Symbol).Float64("amount_thb", p.AmountTHB).Str("schedule", p.Schedule).Str("account", p.Account).Msg("DCA plan created")
+	return p, nil
+}
+
+func Get(id string) (Plan, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	p, ok := plans[id]
+	if !ok {
+		return Plan{}, false
+	}
+	return clone(p), true
+}
+
+// List returns plans oldest first.
+func List() []Plan {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]Plan, 0, len(plans))
+	for _, p := range plans {
+		list = append(list, clone(p))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt != list[j].CreatedAt {
+			return list[i].CreatedAt < list[j].CreatedAt
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// SetPaused pauses or resumes a plan. Resuming picks up at the next slot;
+// slots that passed while paused are not bought.
+func SetPaused(id string, paused bool) (Plan, bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	p, ok := plans[id]
+	if !ok {
+		return Plan{}, false, nil
+	}
+	prevStatus, prevNext := p.Status, p.NextRunAt
+
+	if paused {
+		p.Status = StatusPaused
+	} else {
+		cron, err := ParseCron(p.Schedule)
+		if err != nil {
+			return Plan{}, true, err
+		}
+		p.Status = StatusActive
+		p.NextRunAt = cron.Next(time.Now()).Unix()
+	}
+	if err := writeDCAFile(); err != nil {
+		p.Status, p.NextRunAt = prevStatus, prevNext
+		return Plan{}, true, err
+	}
+
+	log.Info().Str("dca", id).Str("status", p.Status).Msg("DCA plan updated")
+	return clone(p), true, nil
+}
+
+func Delete(id string) (Plan, bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	p, ok := plans[id]
+	if !ok {
+		return Plan{}, false, nil
+	}
+	delete(plans, id)
+	if err := writeDCAFile(); err != nil {
+		plans[id] = p
+		return Plan{}, true, err
+	}
+
+	log.Info().Str("dca", id).Msg("DCA plan deleted")
+	return clone(p), true, nil
+}
+
+// NextRuns lists the next n slots of a schedule.
+func NextRuns(schedule string, n int) ([]time.Time, error) {
+	cron, err := ParseCron(schedule)
+	if err != nil {
+		return nil, err
+	}
+	runs := []time.Time{}
+	t := time.Now()
+	for range n {
+		t = cron.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs, nil
+}
+
+func run() {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		confirmPlaced()
+		for _, id := range due(time.Now()) {
+			execute(id)
+		}
+	}
+}
+
+// due claims the current slot of every active plan whose next run has
+// passed. The claim is persisted before any order is sent.
+func due(now time.Time) []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var ids []string
+	changed := false
+	for _, p := range plans {
+		if p.Status != StatusActive || p.NextRunAt == 0 || now.Unix() < p.NextRunAt {
+			continue
+		}
+		cron, err := ParseCron(p.Schedule)
+		if err != nil {
+			log.Error().Err(err).Str("dca", p.ID).Msg("Invalid DCA schedule, pausing plan")
+			p.Status = StatusPaused
+			changed = true
+			continue
+		}
+
+		// Walk forward to the latest slot that has passed; the ones before
+		// it were missed while the server was down.
+		slot, skipped := time.Unix(p.NextRunAt, 0), 0
+		for {
+			next := cron.Next(slot)
+			if next.IsZero() || next.After(now) {
+				break
+			}
+			slot = next
+			skipped++
+		}
+
+		exec := Execution{Slot: slot.Unix(), Status: ExecPending, Skipped: skipped}
+		if now.Sub(slot) > grace {
+			exec.Status = ExecMissed
+			exec.Note = fmt.Sprintf("slot passed %s ago, beyond the %s grace period", now.Sub(slot).Round(time.Minute), grace)
+		} else {
+			ids = append(ids, p.ID)
+		}
+		if skipped > 0 {
+			log.Warn().Str("dca", p.ID).Int("skipped", skipped).Msg("DCA slots missed while the server was down")
+		}
+
+		p.History = append(p.History, exec)
+		if len(p.History) > historyLimit {
+			p.History = p.History[len(p.History)-historyLimit:]
+		}
+		p.NextRunAt = cron.Next(now).Unix()
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	if err := writeDCAFile(); err != nil {
+		// Without a durable claim a restart could buy the slot again.
+		log.Error().Err(err).Str("file", dcaFile).Msg("Failed to persist DCA slot claims, skipping this round")
+		for _, id := range ids {
+			p := plans[id]
+			last := &p.History[len(p.History)-1]
+			last.Status, last.Note = ExecFailed, "could not persist the slot claim: "+err.Error()
+		}
+		return nil
+	}
+	return ids
+}
+
+func execute(id string) {
+	mu.RLock()
+	p, ok := plans[id]
+	if !ok {
+		mu.RUnlock()
+		return
+	}
+	plan := clone(p)
+	dip := checkDip
+	mu.RUnlock()
+
+	exec := plan.History[len(plan.History)-1]
+	exec.ExecutedAt = time.Now().Unix()
+	exec.AmountTHB, exec.Multiplier = plan.AmountTHB, 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	finish := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		p, ok := plans[id]
+		if !ok {
+			return
+		}
+		for i := len(p.History) - 1; i >= 0; i-- {
+			if p.History[i].Slot == exec.Slot {
+				p.History[i] = exec
+				break
+			}
+		}
+		p.LastRunAt = exec.ExecutedAt
+		if exec.Status == ExecFilled {
+			p.Buys++
+			p.SpentTHB += exec.SpentTHB
+			p.BoughtCoin += exec.Coin
+		}
+		if err := writeDCAFile(); err != nil {
+			log.Error().Err(err).Str("file", dcaFile).Msg("Failed to persist DCA plans")
+		}
+	}
+	defer finish()
+
+	if killswitch.IsHalted() {
+		exec.Status, exec.Note = ExecHalted, "trading halted: "+killswitch.Status().Reason
+		log.Warn().Str("dca", id).Msg("DCA buy skipped, trading halted")
+		return
+	}
+
+	if plan.Dip != nil && dip != nil {
+		res, err := dip(ctx, plan)
+		switch {
+		case err != nil:
+			exec.Note = "dip check failed, bought the base amount: " + err.Error()
+			log.Warn().Err(err).Str("dca", id).Msg("DCA dip check failed")
+		case res.Triggered:
+			exec.Multiplier, exec.DipValue = plan.Dip.Multiplier, res.Value
+			exec.AmountTHB = plan.AmountTHB * plan.Dip.Multiplier
+			exec.Note = res.Message
+		default:
+			exec.DipValue = res.Value
+		}
+	}
+
+	exchange := orders.Live(plan.Account)
+	price, err := exchange.LastPrice(ctx, plan.Symbol)
+	if err != nil {
+		exec.Status, exec.Note = ExecFailed, err.Error()
+		log.Error().Err(err).Str("dca", id).Msg("DCA buy failed")
+		return
+	}
+	exec.Price = price
+
+	// orders.Request is in coin; the live exchange turns it back into the
+	// THB amount for the market bid.
+	coin := exec.AmountTHB / price
+	if pair, ok := symbols.Get(plan.Symbol); ok {
+		if terr := pair.CheckOrder(orders.Buy, price, coin); terr != nil {
+			exec.Status, exec.Note = ExecFailed, terr.Message
+			log.Error().Str("dca", id).Msg("DCA buy rejected: " + terr.Message)
+			return
+		}
+	}
+
+	o, err := exchange.Place(ctx, orders.Request{Symbol: plan.Symbol, Side: orders.Buy, Type: orders.Market, Rate: price, Amount: coin})
+	if err != nil {
+		exec.Status, exec.Note = ExecFailed, err.Error()
+		log.Error().Err(err).Str("dca", id).Msg("DCA buy failed")
+		return
+	}
+	exec.Status, exec.OrderID = ExecPlaced, o.ID
+
+	log.Info().Str("dca", id).Str("symbol", plan.Symbol).Float64("amount_thb", exec.AmountTHB).Float64("multiplier", exec.Multiplier).Str("order", o.ID).Msg("DCA buy placed")
+
+	if confirm(ctx, exchange, plan.Symbol, &exec) && exec.Status == ExecFilled {
+		notifyBuy(plan, exec)
+	}
+}
+
+// confirm reads a placed buy back from the exchange and records what it
+// filled. It reports false while the order is still open or cannot be read,
+// leaving the slot placed for the next round.
+func confirm(ctx context.Context, exchange orders.Exchange, symbol string, exec *Execution) bool {
+	info, err := exchange.Info(ctx, symbol, exec.OrderID, orders.Buy)
+	if err != nil {
+		log.Warn().Err(err).Str("order", exec.OrderID).Msg("Failed to read DCA order, confirming later")
+		return false
+	}
+	if info.Status == orders.StatusOpen {
+		return false
+	}
+	if info.Filled <= 0 {
+		exec.Status, exec.Note = ExecFailed, "order "+info.Status+" without a fill"
+		return true
+	}
+	exec.Status = ExecFilled
+	exec.Coin, exec.Price, exec.FeeTHB = info.Filled, info.AvgPrice, info.Fee
+	exec.SpentTHB = info.Filled*info.AvgPrice + info.Fee
+	return true
+}
+
+// confirmPlaced settles buys whose fill was not confirmed when they were
+// sent, including those placed before a restart.
+func confirmPlaced() {
+	type placed struct {
+		plan Plan
+		exec Execution
+	}
+	mu.RLock()
+	var pending []placed
+	for _, p := range plans {
+		for _, e := range p.History {
+			if e.Status == ExecPlaced {
+				pending = append(pending, placed{plan: clone(p), exec: e})
+			}
+		}
+	}
+	mu.RUnlock()
+
+	for _, pl := range pending {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		done := confirm(ctx, orders.Live(pl.plan.Account), pl.plan.Symbol, &pl.exec)
+		cancel()
+		if !done {
+			continue
+		}
+
+		mu.Lock()
+		recorded := false
+		if p, ok := plans[pl.plan.ID]; ok {
+			for i := range p.History {
+				if p.History[i].Slot != pl.exec.Slot || p.History[i].Status != ExecPlaced {
+					continue
+				}
+				p.History[i] = pl.exec
+				if pl.exec.Status == ExecFilled {
+					p.Buys++
+					p.SpentTHB += pl.exec.SpentTHB
+					p.BoughtCoin += pl.exec.Coin
+				}
+				recorded = true
+				break
+			}
+			if recorded {
+				if err := writeDCAFile(); err != nil {
+					log.Error().Err(err).Str("file", dcaFile).Msg("Failed to persist DCA plans")
+				}
+			}
+		}
+		mu.Unlock()
+
+		if recorded && pl.exec.Status == ExecFilled {
+			notifyBuy(pl.plan, pl.exec)
+		}
+	}
+}
+
+func notifyBuy(plan Plan, exec Execution) {
+	log.Info().Str("dca", plan.ID).Str("symbol", plan.Symbol).Float64("coin", exec.Coin).Float64("price", exec.Price).Str("order", exec.OrderID).Msg("DCA buy filled")
+
+	message := fmt.Sprintf("bought %.8g %s at %.8g for %.2f THB", exec.Coin, strings.ToUpper(plan.Symbol), exec.Price, exec.SpentTHB)
+	if exec.Multiplier > 1 {
+		message += fmt.Sprintf(" (x%.2f dip buy: %s)", exec.Multiplier, exec.Note)
+	}
+	notify.Send(notify.Event{
+		Kind:    notify.KindTrade,
+		Title:   fmt.Sprintf("DCA %s %s", plan.ID, strings.ToUpper(plan.Symbol)),
+		Message: message,
+		Symbol:  plan.Symbol,
+		Fields:  map[string]any{"dca_id": plan.ID, "account": plan.Account, "order_id": exec.OrderID, "multiplier": exec.Multiplier},
+	})
+}
+
+func clone(p *Plan) Plan {
+	c := *p
+	c.History = append([]Execution{}, p.History...)
+	if p.Dip != nil {
+		dip := *p.Dip
+		c.Dip = &dip
+	}
+	return c
+}
+
+func newID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate dca id: %w", err)
+	}
+	return "dca_" + hex.EncodeToString(b), nil
+}
+
+func readDCAFile() ([]*Plan, error) {
+	data, err := os.ReadFile(dcaFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var list []*Plan
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode dca plans: %w", err)
+	}
+	return list, nil
+}
+
+// writeDCAFile must be called with mu held. The file is replaced atomically.
+func writeDCAFile() error {
+	if dir := filepath.Dir(dcaFile); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	list := make([]*Plan, 0, len(plans))
+	for _, p := range plans {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt < list[j].CreatedAt
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := dcaFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dcaFile)
+}
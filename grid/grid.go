@@ -0,0 +1,686 @@
+package grid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gokub/killswitch"
+	"gokub/notify"
+	"gokub/orders"
+	"gokub/symbols"
+	"gokub/utils"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const (
+	Arithmetic = "arithmetic"
+	Geometric  = "geometric"
+
+	StatusRunning = "running"
+	StatusStopped = "stopped"
+	StatusHalted  = "halted"
+)
+
+type Config struct {
+	Symbol     string  `json:"symbol"`
+	Lower      float64 `json:"lower_price"`
+	Upper      float64 `json:"upper_price"`
+	Grids      int     `json:"grids"`
+	TotalTHB   float64 `json:"total_thb"`
+	Spacing    string  `json:"spacing"`
+	FeePercent float64 `json:"fee_percent"`
+	Account    string  `json:"account,omitempty"`
+	Simulated  bool    `json:"simulated"`
+}
+
+// Cell is one rung of the ladder: it buys Amount at BuyPrice and sells it
+// back at SellPrice, over and over. Side is the order currently working.
+// Filled and FilledTHB count what has matched on that side so far, which can
+// span several orders when one is cancelled part way; CostTHB is what the
+// coin a sell-side cell holds cost, fees included.
+type Cell struct {
+	Index      int     `json:"index"`
+	BuyPrice   float64 `json:"buy_price"`
+	SellPrice  float64 `json:"sell_price"`
+	Amount     float64 `json:"amount"`
+	Side       string  `json:"side"`
+	OrderID    string  `json:"order_id,omitempty"`
+	Filled     float64 `json:"filled,omitempty"`
+	FilledTHB  float64 `json:"filled_thb,omitempty"`
+	CostTHB    float64 `json:"cost_thb,omitempty"`
+	RoundTrips int     `json:"round_trips"`
+	ProfitTHB  float64 `json:"profit_thb"`
+}
+
+type Bot struct {
+	ID string `json:"id"`
+	Config
+	Status        string  `json:"status"`
+	StopReason    string  `json:"stop_reason,omitempty"`
+	CreatedAt     int64   `json:"created_at"`
+	StoppedAt     int64   `json:"stopped_at,omitempty"`
+	StartPrice    float64 `json:"start_price"`
+	InitialCoin   float64 `json:"initial_coin"`
+	Cells         []*Cell `json:"cells"`
+	GridProfitTHB float64 `json:"grid_profit_thb"`
+	FeesTHB       float64 `json:"fees_thb"`
+	RoundTrips    int     `json:"round_trips"`
+	LastPrice     float64 `json:"last_price"`
+	LastCheckedAt int64   `json:"last_checked_at,omitempty"`
+	LastError     string  `json:"last_error,omitempty"`
+
+	// work serializes Maintain and Stop, which talk to the exchange; mu
+	// guards the fields for readers and is never held across a call.
+	// Fields change with both held.
+	work     sync.Mutex
+	mu       sync.Mutex
+	exchange orders.Exchange
+	sim      *orders.Simulated
+}
+
+var (
+	mu       sync.RWMutex
+	bots     = map[string]*Bot{}
+	gridFile = "grids.json"
+	interval = 15 * time.Second
+
+	// livePrice feeds simulated bots the market price.
+	livePrice = func(ctx context.Context, symbol string) (float64, error) {
+		return orders.Live("").LastPrice(ctx, symbol)
+	}
+)
+
+// Init restores persisted bots and starts maintaining them. Live bots resume
+// against their account; simulations cannot be restored and are stopped.
+func Init() {
+	if path := os.Getenv("GRID_FILE"); path != "" {
+		gridFile = path
+	}
+	if d, err := time.ParseDuration(os.Getenv("GRID_POLL_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+
+	loaded, err := readGridFile()
+	if err != nil {
+		log.Error().Err(err).Str("file", gridFile).Msg("Failed to load grid bots")
+	}
+
+	mu.Lock()
+	for _, b := range loaded {
+		if b.Status == StatusRunning && b.Simulated {
+			b.Status, b.StopReason, b.StoppedAt = StatusStopped, "simulation does not survive a restart", time.Now().Unix()
+		}
+		if !b.Simulated {
+			b.exchange = orders.Live(b.Account)
+		}
+		bots[b.ID] = b
+	}
+	mu.Unlock()
+
+	if len(loaded) > 0 {
+		log.Info().Int("bots", len(loaded)).Str("file", gridFile).Msg("Grid bots loaded")
+	}
+
+	go run()
+}
+
+func Interval() time.Duration {
+	return interval
+}
+
+// Levels returns grids+1 prices from lower to upper, evenly spaced in price
+// (arithmetic) or in percent (geometric).
+func Levels(lower, upper float64, grids int, spacing string) []float64 {
+	levels := make([]float64, grids+1)
+	ratio := math.Pow(upper/lower, 1/float64(grids))
+	for i := range levels {
+		if spacing == Geometric {
+			levels[i] = lower * math.Pow(ratio, float64(i))
+		} else {
+			levels[i] = lower + (upper-lower)*float64(i)/float64(grids)
+		}
+	}
+	levels[grids] = upper
+	return levels
+}
+
+// Plan lays out the cells for cfg around price. Cells entirely above the
+// price start on the sell side and need coin bought up front; the returned
+// coin amount is that initial buy.
+func Plan(cfg Config, pair *symbols.Pair, price float64) ([]*Cell, float64, *utils.ToolError) {
+	if cfg.Lower <= 0 || cfg.Upper <= cfg.Lower {
+		return nil, 0, utils.InvalidField("upper_price", "must be greater than lower_price")
+	}
+
+	levels := Levels(cfg.Lower, cfg.Upper, cfg.Grids, cfg.Spacing)
+	for i := range levels {
+		levels[i] = pair.SnapPrice(levels[i], symbols.RoundNearest)
+		if i > 0 && levels[i] <= levels[i-1] {
+			return nil, 0, utils.NewError(utils.CodeInvalidArgument, "%d grids are too dense for the %g tick size of %s", cfg.Grids, pair.PriceStep(), strings.ToUpper(pair.Symbol)).
+				With("tick_size", pair.PriceStep())
+		}
+	}
+
+	perGrid := cfg.TotalTHB / float64(cfg.Grids)
+	cells := make([]*Cell, cfg.Grids)
+	initialCoin := 0.0
+	for i := range cells {
+		c := &Cell{
+			Index:     i,
+			BuyPrice:  levels[i],
+			SellPrice: levels[i+1],
+			Amount:    pair.SnapAmount(perGrid / levels[i]),
+			Side:      orders.Buy,
+		}
+		if err := pair.CheckOrder(orders.Buy, c.BuyPrice, c.Amount); err != nil {
+			return nil, 0, err.With("cell", i)
+		}
+		if c.BuyPrice >= price {
+			c.Side = orders.Sell
+			initialCoin += c.Amount
+		}
+		cells[i] = c
+	}
+
+	return cells, pair.SnapAmount(initialCoin), nil
+}
+
+// Create plans a grid, buys the coin the sell side needs and places the
+// ladder. When a simulation is requested without a start price, it starts
+// at the live price.
+func Create(ctx context.Context, cfg Config, pair *symbols.Pair, simPrice float64) (*Bot, error) {
+	b := &Bot{Config: cfg, Status: StatusRunning, CreatedAt: time.Now().Unix()}
+
+	if cfg.Simulated {
+		b.sim = orders.NewSimulated()
+		b.exchange = b.sim
+		if simPrice <= 0 {
+			price, err := livePrice(ctx, cfg.Symbol)
+			if err != nil {
+				return nil, err
+			}
+			simPrice = price
+		}
+		b.sim.SetPrice(cfg.Symbol, simPrice)
+	} else {
+		b.exchange = orders.Live(cfg.Account)
+	}
+
+	price, err := b.exchange.LastPrice(ctx, cfg.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	if price < cfg.Lower || price > cfg.Upper {
+		return nil, utils.NewError(utils.CodeInvalidArgument, "price %.8g is outside the grid range %.8g-%.8g", price, cfg.Lower, cfg.Upper).
+			With("last_price", price)
+	}
+
+	cells, initialCoin, planErr := Plan(cfg, pair, price)
+	if planErr != nil {
+		return nil, planErr
+	}
+	b.Cells, b.StartPrice, b.LastPrice, b.InitialCoin = cells, price, price, initialCoin
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	b.ID = id
+
+	var bought *orders.Info
+	if initialCoin > 0 {
+		o, err := b.exchange.Place(ctx, orders.Request{Symbol: cfg.Symbol, Side: orders.Buy, Type: orders.Market, Rate: price, Amount: initialCoin})
+		if err != nil {
+			return nil, fmt.Errorf("initial buy of %.8g: %w", initialCoin, err)
+		}
+		bought, err = b.exchange.Info(ctx, cfg.Symbol, o.ID, orders.Buy)
+		if err != nil {
+			return nil, boughtError(fmt.Errorf("read initial buy: %w", err), o.ID, -1)
+		}
+		// The sell side gets the coin that arrived after the fee and any
+		// slippage, which can be less than the cells were planned with.
+		received := pair.SnapAmount(bought.Filled * (1 - cfg.FeePercent/100))
+		if received < initialCoin {
+			for _, c := range b.Cells {
+				if c.Side != orders.Sell {
+					continue
+				}
+				c.Amount = pair.SnapAmount(c.Amount * received / initialCoin)
+				if err := pair.CheckOrder(orders.Sell, c.SellPrice, c.Amount); err != nil {
+					return nil, boughtError(err.With("cell", c.Index), o.ID, received)
+				}
+			}
+		}
+		b.InitialCoin = received
+		b.FeesTHB = utils.Round(bought.Filled*bought.AvgPrice*cfg.FeePercent/100, 2)
+	}
+
+	for _, c := range b.Cells {
+		if err := b.place(ctx, c); err != nil {
+			b.cancelAll(ctx)
+			err = fmt.Errorf("place grid cell %d: %w", c.Index, err)
+			if bought != nil {
+				return nil, boughtError(err, bought.ID, b.InitialCoin)
+			}
+			return nil, err
+		}
+	}
+
+	mu.Lock()
+	bots[b.ID] = b
+	saveErr := writeGridFile()
+	mu.Unlock()
+	if saveErr != nil {
+		log.Error().Err(saveErr).Str("file", gridFile).Msg("Failed to persist grid bots")
+	}
+
+	log.Info().Str("grid", b.ID).Str("symbol", cfg.Symbol).Int("grids", cfg.Grids).Bool("simulated", cfg.Simulated).Msg("Grid bot started")
+	return b.Snapshot(), nil
+}
+
+// boughtError reports a failure after the initial buy went through. Its coin
+// stays on the account, so the error names the order and how much it bought;
+// coin is negative when the fill could not be read.
+func boughtError(err error, orderID string, coin float64) error {
+	e := *utils.ClassifyError(err)
+	e.Details = maps.Clone(e.Details)
+	if coin < 0 {
+		e.Message += fmt.Sprintf("; initial buy %s went through and its coin stays on the account", orderID)
+		return e.With("initial_order_id", orderID)
+	}
+	e.Message += fmt.Sprintf("; initial buy %s bought %.8g coin, which stays on the account", orderID, coin)
+	return e.With("initial_order_id", orderID).With("initial_coin", coin)
+}
+
+func Get(id string) (*Bot, bool) {
+	mu.RLock()
+	b, ok := bots[id]
+	mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return b.Snapshot(), true
+}
+
+func List() []*Bot {
+	mu.RLock()
+	list := make([]*Bot, 0, len(bots))
+	for _, b := range bots {
+		list = append(list, b)
+	}
+	mu.RUnlock()
+
+	snapshots := make([]*Bot, len(list))
+	for i, b := range list {
+		snapshots[i] = b.Snapshot()
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt < snapshots[j].CreatedAt
+	})
+	return snapshots
+}
+
+// Stop halts a bot and, unless keepOrders is set, cancels its resting orders.
+func Stop(ctx context.Context, id, reason string, keepOrders bool) (*Bot, error) {
+	mu.RLock()
+	b, ok := bots[id]
+	mu.RUnlock()
+	if !ok {
+		return nil, utils.NewError(utils.CodeNotFound, "grid %s not found", id).With("id", id)
+	}
+
+	b.work.Lock()
+	if b.Status == StatusRunning {
+		if !keepOrders {
+			b.cancelAll(ctx)
+		}
+		b.mu.Lock()
+		b.Status, b.StopReason, b.StoppedAt = StatusStopped, reason, time.Now().Unix()
+		b.mu.Unlock()
+	}
+	b.work.Unlock()
+
+	save()
+	log.Info().Str("grid", id).Str("reason", reason).Bool("keep_orders", keepOrders).Msg("Grid bot stopped")
+	return b.Snapshot(), nil
+}
+
+// Snapshot is a copy safe to read and serialize while the bot keeps running.
+func (b *Bot) Snapshot() *Bot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := &Bot{
+		ID:            b.ID,
+		Config:        b.Config,
+		Status:        b.Status,
+		StopReason:    b.StopReason,
+		CreatedAt:     b.CreatedAt,
+		StoppedAt:     b.StoppedAt,
+		StartPrice:    b.StartPrice,
+		InitialCoin:   b.InitialCoin,
+		Cells:         make([]*Cell, len(b.Cells)),
+		GridProfitTHB: b.GridProfitTHB,
+		FeesTHB:       b.FeesTHB,
+		RoundTrips:    b.RoundTrips,
+		LastPrice:     b.LastPrice,
+		LastCheckedAt: b.LastCheckedAt,
+		LastError:     b.LastError,
+	}
+	for i, cell := range b.Cells {
+		cp := *cell
+		c.Cells[i] = &cp
+	}
+	return c
+}
+
+// Holdings is the coin held for sell-side cells and the THB committed to
+// buy-side cells. A cell part way through its order counts on both sides.
+func (b *Bot) Holdings() (coin, thb float64) {
+	for _, c := range b.Cells {
+		if c.Side == orders.Sell {
+			coin += c.Amount - c.Filled
+		} else {
+			coin += c.Filled
+			thb += (c.Amount - c.Filled) * c.BuyPrice
+		}
+	}
+	return utils.Round(coin), utils.Round(thb, 2)
+}
+
+func (b *Bot) OrderIDs() []string {
+	ids := []string{}
+	for _, c := range b.Cells {
+		if c.OrderID != "" {
+			ids = append(ids, c.OrderID)
+		}
+	}
+	return ids
+}
+
+// Simulation exposes the in-memory book of a simulated bot so its price can
+// be driven by hand.
+func Simulation(id string) (*orders.Simulated, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	b, ok := bots[id]
+	if !ok || b.sim == nil {
+		return nil, false
+	}
+	return b.sim, true
+}
+
+// place puts up the rest of the cell's working side. It must be called with
+// work held, or before the bot is shared.
+func (b *Bot) place(ctx context.Context, c *Cell) error {
+	price := c.BuyPrice
+	if c.Side == orders.Sell {
+		price = c.SellPrice
+	}
+	o, err := b.exchange.Place(ctx, orders.Request{Symbol: b.Symbol, Side: c.Side, Type: orders.Limit, Rate: price, Amount: c.Amount - c.Filled})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		c.OrderID = ""
+		return err
+	}
+	c.OrderID = o.ID
+	return nil
+}
+
+// cancelAll must be called with work held, or before the bot is shared.
+func (b *Bot) cancelAll(ctx context.Context) {
+	for _, c := range b.Cells {
+		if c.OrderID == "" {
+			continue
+		}
+		if err := b.exchange.Cancel(ctx, b.Symbol, c.OrderID, c.Side); err != nil {
+			log.Warn().Err(err).Str("grid", b.ID).Str("id", c.OrderID).Msg("Failed to cancel grid order")
+			continue
+		}
+		b.mu.Lock()
+		c.OrderID = ""
+		b.mu.Unlock()
+	}
+}
+
+func run() {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		mu.RLock()
+		running := make([]*Bot, 0, len(bots))
+		for _, b := range bots {
+			running = append(running, b)
+		}
+		mu.RUnlock()
+
+		changed := false
+		for _, b := range running {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if b.Maintain(ctx) {
+				changed = true
+			}
+			cancel()
+		}
+		if changed {
+			save()
+		}
+	}
+}
+
+// Maintain checks which orders have left the book and places the next order
+// for each. An order is only counted as filled as far as the exchange says
+// it matched: a filled buy becomes a sell one level up, a filled sell books
+// the cell's profit and becomes a buy one level down, and the unfilled rest
+// of a cancelled order is placed again. It reports whether the bot changed.
+func (b *Bot) Maintain(ctx context.Context) bool {
+	b.work.Lock()
+	defer b.work.Unlock()
+
+	if b.Status != StatusRunning {
+		return false
+	}
+
+	// The kill switch cancels every live order; the bot stops rather than
+	// placing them again.
+	if !b.Simulated && killswitch.IsHalted() {
+		b.mu.Lock()
+		b.Status, b.StopReason, b.StoppedAt = StatusHalted, "trading halted, orders cancelled by kill switch", time.Now().Unix()
+		b.mu.Unlock()
+		log.Warn().Str("grid", b.ID).Msg("Grid bot stopped by kill switch")
+		return true
+	}
+
+	if b.Simulated {
+		if price, err := livePrice(ctx, b.Symbol); err == nil {
+			b.sim.SetPrice(b.Symbol, price)
+		}
+	}
+
+	price, priceErr := b.exchange.LastPrice(ctx, b.Symbol)
+	open, err := b.exchange.Open(ctx, b.Symbol)
+
+	b.mu.Lock()
+	b.LastCheckedAt = time.Now().Unix()
+	if priceErr == nil {
+		b.LastPrice = price
+	}
+	b.LastError = ""
+	if err != nil {
+		b.LastError = err.Error()
+	}
+	b.mu.Unlock()
+	if err != nil {
+		log.Warn().Err(err).Str("grid", b.ID).Msg("Failed to get open orders for grid")
+		return true
+	}
+
+	resting := make(map[string]bool, len(open))
+	for _, o := range open {
+		resting[o.ID] = true
+	}
+
+	for _, c := range b.Cells {
+		if c.OrderID != "" {
+			if resting[c.OrderID] {
+				continue
+			}
+			info, err := b.exchange.Info(ctx, b.Symbol, c.OrderID, c.Side)
+			if err != nil {
+				b.fail(c, "Failed to read grid order, retrying next poll", err)
+				continue
+			}
+			// Matched after the open orders were read; next poll sees it.
+			if info.Status == orders.StatusOpen {
+				continue
+			}
+			b.settle(c, info)
+		}
+		if err := b.place(ctx, c); err != nil {
+			b.fail(c, "Failed to place grid order, retrying next poll", err)
+		}
+	}
+	return true
+}
+
+func (b *Bot) fail(c *Cell, message string, err error) {
+	b.mu.Lock()
+	b.LastError = fmt.Sprintf("cell %d: %v", c.Index, err)
+	b.mu.Unlock()
+	log.Warn().Err(err).Str("grid", b.ID).Int("cell", c.Index).Msg(message)
+}
+
+// settle books what an order that left the book matched and flips the cell
+// once its side has filled in full.
+func (b *Bot) settle(c *Cell, info *orders.Info) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c.OrderID = ""
+	if info.Filled > 0 {
+		value := info.Filled * info.AvgPrice
+		c.Filled += info.Filled
+		c.FilledTHB += value
+		b.FeesTHB = utils.Round(b.FeesTHB+value*b.FeePercent/100, 2)
+	}
+	if c.Filled < c.Amount*(1-1e-9) {
+		log.Info().Str("grid", b.ID).Int("cell", c.Index).Str("id", info.ID).Str("status", info.Status).Float64("filled", c.Filled).Float64("amount", c.Amount).Msg("Grid order left the book unfilled, placing the rest")
+		return
+	}
+	b.filled(c)
+}
+
+// filled must be called with mu held.
+func (b *Bot) filled(c *Cell) {
+	price := c.FilledTHB / c.Filled
+	fee := c.FilledTHB * b.FeePercent / 100
+
+	message := fmt.Sprintf("%s %.8g @ %.8g filled (cell %d)", strings.ToUpper(c.Side), c.Filled, price, c.Index)
+	if c.Side == orders.Buy {
+		c.CostTHB = c.FilledTHB + fee
+		c.Side = orders.Sell
+	} else {
+		cost := c.CostTHB
+		if cost == 0 {
+			// Coin from the initial buy, bought for this cell at its buy price.
+			cost = c.Amount * c.BuyPrice * (1 + b.FeePercent/100)
+		}
+		profit := c.FilledTHB - fee - cost
+		c.ProfitTHB = utils.Round(c.ProfitTHB+profit, 2)
+		c.RoundTrips++
+		b.GridProfitTHB = utils.Round(b.GridProfitTHB+profit, 2)
+		b.RoundTrips++
+		c.CostTHB = 0
+		c.Side = orders.Buy
+		message += fmt.Sprintf(", round trip profit %.2f THB, grid total %.2f THB", profit, b.GridProfitTHB)
+	}
+	c.Filled, c.FilledTHB = 0, 0
+
+	log.Info().Str("grid", b.ID).Str("symbol", b.Symbol).Int("cell", c.Index).Msg(message)
+	notify.Send(notify.Event{
+		Kind:    notify.KindTrade,
+		Title:   fmt.Sprintf("grid %s %s", b.ID, strings.ToUpper(b.Symbol)),
+		Message: message,
+		Symbol:  b.Symbol,
+		Fields:  map[string]any{"grid_id": b.ID, "cell": c.Index, "simulated": b.Simulated, "grid_profit_thb": b.GridProfitTHB},
+	})
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate grid id: %w", err)
+	}
+	return "grid_" + hex.EncodeToString(buf), nil
+}
+
+func save() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := writeGridFile(); err != nil {
+		log.Error().Err(err).Str("file", gridFile).Msg("Failed to persist grid bots")
+	}
+}
+
+func readGridFile() ([]*Bot, error) {
+	data, err := os.ReadFile(gridFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var list []*Bot
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode grid bots: %w", err)
+	}
+	return list, nil
+}
+
+// writeGridFile must be called with mu held.
+func writeGridFile() error {
+	if dir := filepath.Dir(gridFile); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	list := make([]*Bot, 0, len(bots))
+	for _, b := range bots {
+		list = append(list, b.Snapshot())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt < list[j].CreatedAt
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := gridFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, gridFile)
+}
@@ -0,0 +1,204 @@
+package grid
+
+import (
+	"context"
+	"errors"
+	"gokub/orders"
+	"gokub/symbols"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+const testSymbol = "btc_thb"
+
+// newTestBot starts a simulated 100-140 grid of four cells at 120: cells 0
+// and 1 rest buys at 100 and 110, cells 2 and 3 rest sells at 130 and 140.
+func newTestBot(t *testing.T) (*Bot, *orders.Simulated) {
+	t.Helper()
+
+	oldFile, oldPrice := gridFile, livePrice
+	t.Cleanup(func() {
+		gridFile, livePrice = oldFile, oldPrice
+		mu.Lock()
+		bots = map[string]*Bot{}
+		mu.Unlock()
+	})
+	gridFile = filepath.Join(t.TempDir(), "grids.json")
+	livePrice = func(context.Context, string) (float64, error) {
+		return 0, errors.New("no live price in tests")
+	}
+
+	cfg := Config{Symbol: testSymbol, Lower: 100, Upper: 140, Grids: 4, TotalTHB: 4000, Spacing: Arithmetic, FeePercent: 0.25, Simulated: true}
+	created, err := Create(t.Context(), cfg, &symbols.Pair{Symbol: testSymbol}, 120)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	mu.RLock()
+	b := bots[created.ID]
+	mu.RUnlock()
+	return b, b.sim
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestCreatePlacesLadder(t *testing.T) {
+	b, sim := newTestBot(t)
+
+	open, _ := sim.Open(t.Context(), testSymbol)
+	if len(open) != 4 {
+		t.Fatalf("got %d resting orders, want 4", len(open))
+	}
+	want := []string{orders.Buy, orders.Buy, orders.Sell, orders.Sell}
+	for i, c := range b.Cells {
+		if c.Side != want[i] || c.OrderID == "" {
+			t.Errorf("cell %d: side %s order %q, want a resting %s", i, c.Side, c.OrderID, want[i])
+		}
+	}
+	if !near(b.InitialCoin, b.Cells[2].Amount+b.Cells[3].Amount) {
+		t.Errorf("initial coin %.8g does not cover the sell-side cells", b.InitialCoin)
+	}
+}
+
+func TestCreateSizesSellsToCoinReceived(t *testing.T) {
+	b, sim := newTestBot(t)
+
+	planned, initial, err := Plan(b.Config, &symbols.Pair{Symbol: testSymbol}, 120)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if want := initial * (1 - 0.0025); !near(b.InitialCoin, want) {
+		t.Errorf("initial coin %.8g, want %.8g after the fee", b.InitialCoin, want)
+	}
+
+	open, _ := sim.Open(t.Context(), testSymbol)
+	for _, c := range b.Cells[2:] {
+		if c.Amount >= planned[c.Index].Amount {
+			t.Errorf("cell %d sells %.8g, want less than the planned %.8g", c.Index, c.Amount, planned[c.Index].Amount)
+		}
+		for _, o := range open {
+			if o.ID == c.OrderID && o.Amount != c.Amount {
+				t.Errorf("cell %d order is for %.8g, want %.8g", c.Index, o.Amount, c.Amount)
+			}
+		}
+	}
+}
+
+func TestMaintainRoundTrip(t *testing.T) {
+	b, sim := newTestBot(t)
+	c := b.Cells[1]
+
+	sim.SetPrice(testSymbol, 109)
+	b.Maintain(t.Context())
+	if c.Side != orders.Sell || c.OrderID == "" {
+		t.Fatalf("filled buy did not become a resting sell: %+v", *c)
+	}
+	if want := c.Amount * 110 * 1.0025; !near(c.CostTHB, want) {
+		t.Errorf("cost %.8g, want %.8g", c.CostTHB, want)
+	}
+
+	sim.SetPrice(testSymbol, 121)
+	b.Maintain(t.Context())
+	if c.Side != orders.Buy || c.RoundTrips != 1 || b.RoundTrips != 1 {
+		t.Fatalf("filled sell did not complete the round trip: %+v", *c)
+	}
+	profit := c.Amount*(120-110) - c.Amount*110*0.0025 - c.Amount*120*0.0025
+	if math.Abs(b.GridProfitTHB-profit) > 0.01 {
+		t.Errorf("grid profit %.2f, want %.2f", b.GridProfitTHB, profit)
+	}
+}
+
+func TestMaintainKeepsPartialFillResting(t *testing.T) {
+	b, sim := newTestBot(t)
+	c := b.Cells[1]
+	id := c.OrderID
+
+	if _, err := sim.FillPart(id, 4); err != nil {
+		t.Fatal(err)
+	}
+	b.Maintain(t.Context())
+
+	if c.OrderID != id || c.Side != orders.Buy || c.Filled != 0 {
+		t.Fatalf("partly filled resting order was settled: %+v", *c)
+	}
+}
+
+func TestMaintainReplacesRestOfCancelledOrder(t *testing.T) {
+	b, sim := newTestBot(t)
+	c := b.Cells[1]
+	id := c.OrderID
+
+	if _, err := sim.FillPart(id, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.Cancel(t.Context(), testSymbol, id, orders.Buy); err != nil {
+		t.Fatal(err)
+	}
+	b.Maintain(t.Context())
+
+	if c.Side != orders.Buy || c.OrderID == "" || c.OrderID == id {
+		t.Fatalf("cancelled buy was not placed again: %+v", *c)
+	}
+	if !near(c.Filled, 4) || !near(c.FilledTHB, 440) {
+		t.Errorf("filled %.8g for %.8g THB, want 4 for 440 THB", c.Filled, c.FilledTHB)
+	}
+	info, err := sim.Info(t.Context(), testSymbol, c.OrderID, orders.Buy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(info.Amount, c.Amount-4) {
+		t.Errorf("replacement is for %.8g, want the unfilled %.8g", info.Amount, c.Amount-4)
+	}
+	if coin, _ := b.Holdings(); !near(coin, b.Cells[2].Amount+b.Cells[3].Amount+4) {
+		t.Errorf("holdings %.8g do not include the partial fill", coin)
+	}
+
+	sim.SetPrice(testSymbol, 110)
+	b.Maintain(t.Context())
+	if c.Side != orders.Sell || c.Filled != 0 {
+		t.Fatalf("cell did not flip once its buy filled in full: %+v", *c)
+	}
+	if want := c.Amount * 110 * 1.0025; !near(c.CostTHB, want) {
+		t.Errorf("cost %.8g, want %.8g", c.CostTHB, want)
+	}
+}
+
+func TestMaintainDoesNotCountCancelledOrderAsFilled(t *testing.T) {
+	b, sim := newTestBot(t)
+	c := b.Cells[0]
+	id := c.OrderID
+	fees := b.FeesTHB
+
+	if err := sim.Cancel(t.Context(), testSymbol, id, orders.Buy); err != nil {
+		t.Fatal(err)
+	}
+	b.Maintain(t.Context())
+
+	if c.Side != orders.Buy || c.OrderID == id || c.OrderID == "" || c.Filled != 0 {
+		t.Fatalf("cancelled buy was not placed again on the same side: %+v", *c)
+	}
+	if b.FeesTHB != fees {
+		t.Errorf("fees changed from %.2f to %.2f without a fill", fees, b.FeesTHB)
+	}
+}
+
+func TestStopCancelsOrders(t *testing.T) {
+	b, sim := newTestBot(t)
+
+	stopped, err := Stop(t.Context(), b.ID, "test", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stopped.Status != StatusStopped || len(stopped.OrderIDs()) != 0 {
+		t.Fatalf("stop left status %s and orders %v", stopped.Status, stopped.OrderIDs())
+	}
+	if open, _ := sim.Open(t.Context(), testSymbol); len(open) != 0 {
+		t.Errorf("%d orders still resting", len(open))
+	}
+	if b.Maintain(t.Context()) {
+		t.Error("stopped bot changed on maintain")
+	}
+}
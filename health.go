@@ -0,0 +1,100 @@
+package main
+
+import (
+	"gokub/accounts"
+	"gokub/metrics"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const readinessTTL = 30 * time.Second
+
+type check struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type readiness struct {
+	Ready       bool             `json:"ready"`
+	Exchange    check            `json:"exchange"`
+	Credentials map[string]check `json:"credentials"`
+	CheckedAt   int64            `json:"checked_at"`
+}
+
+var (
+	readyMu   sync.Mutex
+	lastReady *readiness
+)
+
+func healthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+	})
+}
+
+// readyzHandler is unauthenticated, so it only reports the verdict. Which
+// check failed, and why, goes to the log.
+func readyzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if checkReadiness().Ready {
+			writeJSON(w, http.StatusOK, map[string]any{"status": "ready"})
+			return
+		}
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "not_ready"})
+	})
+}
+
+func checkReadiness() *readiness {
+	readyMu.Lock()
+	defer readyMu.Unlock()
+
+	if lastReady != nil && time.Since(time.Unix(lastReady.CheckedAt, 0)) < readinessTTL {
+		metrics.CacheHit("readiness")
+		return lastReady
+	}
+	metrics.CacheMiss("readiness")
+
+	result := &readiness{
+		Ready:       true,
+		Credentials: map[string]check{},
+		CheckedAt:   time.Now().Unix(),
+	}
+
+	if _, err := market.GetTicker("btc_thb"); err != nil {
+		result.Ready = false
+		result.Exchange = check{Error: err.Error()}
+		log.Warn().Err(err).Msg("Readiness check: exchange unreachable")
+	} else {
+		result.Exchange = check{OK: true}
+	}
+
+	for _, name := range accounts.Names() {
+		err := accounts.Use(name, func() error {
+			_, err := market.GetBalances()
+			return err
+		})
+		if err != nil {
+			result.Ready = false
+			result.Credentials[name] = check{Error: err.Error()}
+			log.Warn().Err(err).Str("account", name).Msg("Readiness check: credentials rejected")
+			continue
+		}
+		result.Credentials[name] = check{OK: true}
+	}
+
+	lastReady = result
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"gokub/accounts"
+	"gokub/keystore"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func runKeysCommand(args []string) int {
+	usage := func() int {
+		fmt.Fprintln(os.Stderr, "usage: gokub keys add [-passphrase] <name> | list | remove <name>")
+		return 2
+	}
+
+	if len(args) == 0 {
+		return usage()
+	}
+
+	path := keystore.Path()
+
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("keys add", flag.ContinueOnError)
+		passphraseOnly := fs.Bool("passphrase", false, "Protect a new keystore with a passphrase instead of the OS keyring")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 1 {
+			return usage()
+		}
+
+		name := strings.ToLower(strings.TrimSpace(fs.Arg(0)))
+		store, err := openOrCreateKeystore(path, !*passphraseOnly)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "keys add: %v\n", err)
+			return 1
+		}
+
+		apiKey, err := readSecret("Bitkub API key: ")
+		if err != nil || apiKey == "" {
+			fmt.Fprintln(os.Stderr, "keys add: API key is required")
+			return 1
+		}
+		secret, err := readSecret("Bitkub API secret: ")
+		if err != nil || secret == "" {
+			fmt.Fprintln(os.Stderr, "keys add: API secret is required")
+			return 1
+		}
+
+		store.Accounts[name] = keystore.Credential{APIKey: apiKey, Secret: secret}
+		if err := store.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "keys add: %v\n", err)
+			return 1
+		}
+
+		fmt.Printf("Account %q saved to %s (%s)\n", name, path, store.KDF())
+	case "list":
+		store, err := openKeystore(path, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "keys list: %v\n", err)
+			return 1
+		}
+
+		fmt.Printf("Keystore: %s (%s)\n", path, store.KDF())
+		for _, name := range store.Names() {
+			fmt.Printf("  %s\tapi_key=%s\n", name, maskKey(store.Accounts[name].APIKey))
+		}
+	case "remove":
+		if len(args) != 2 {
+			return usage()
+		}
+
+		name := strings.ToLower(strings.TrimSpace(args[1]))
+		store, err := openKeystore(path, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "keys remove: %v\n", err)
+			return 1
+		}
+
+		if _, ok := store.Accounts[name]; !ok {
+			fmt.Fprintf(os.Stderr, "keys remove: account %q not found\n", name)
+			return 1
+		}
+
+		delete(store.Accounts, name)
+		if err := store.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "keys remove: %v\n", err)
+			return 1
+		}
+
+		fmt.Printf("Account %q removed\n", name)
+	default:
+		return usage()
+	}
+
+	return 0
+}
+
+func loadKeystoreAccounts(interactive bool) error {
+	path := keystore.Path()
+	if !keystore.Exists(path) {
+		return nil
+	}
+
+	store, err := openKeystore(path, interactive)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range store.Names() {
+		cred := store.Accounts[name]
+		accounts.Add(name, cred.APIKey, cred.Secret)
+	}
+
+	log.Info().Str("file", path).Int("accounts", len(store.Accounts)).Msg("Keystore unlocked")
+	return nil
+}
+
+func openKeystore(path string, interactive bool) (*keystore.Store, error) {
+	needsPassphrase, err := keystore.NeedsPassphrase(path)
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase := ""
+	if needsPassphrase {
+		passphrase = os.Getenv("GOKUB_KEYSTORE_PASSPHRASE")
+		if passphrase == "" {
+			if !interactive || !term.IsTerminal(int(os.Stdin.Fd())) {
+				return nil, fmt.Errorf("keystore is passphrase protected, set GOKUB_KEYSTORE_PASSPHRASE")
+			}
+			if passphrase, err = readSecret("Keystore passphrase: "); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return keystore.Open(path, passphrase)
+}
+
+func openOrCreateKeystore(path string, useKeyring bool) (*keystore.Store, error) {
+	store, err := openKeystore(path, true)
+	if !errors.Is(err, keystore.ErrNotExist) {
+		return store, err
+	}
+
+	passphrase := os.Getenv("GOKUB_KEYSTORE_PASSPHRASE")
+	if useKeyring {
+		if store, err := keystore.Create(path, "", true); err == nil {
+			return store, nil
+		}
+		fmt.Fprintln(os.Stderr, "OS keyring unavailable, falling back to a passphrase protected file")
+	}
+
+	if passphrase == "" {
+		if passphrase, err = readSecret("New keystore passphrase: "); err != nil {
+			return nil, err
+		}
+		confirm, err := readSecret("Confirm passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if confirm != passphrase {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return keystore.Create(path, passphrase, false)
+}
+
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		value, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(value)), err
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return key[:4] + strings.Repeat("*", 8)
+}
@@ -0,0 +1,274 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/zalando/go-keyring"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const (
+	KDFKeyring    = "keyring"
+	KDFPassphrase = "pbkdf2-sha256"
+
+	keyringService = "gokub-mcp"
+	iterations     = 600000
+)
+
+var ErrNotExist = errors.New("keystore does not exist")
+
+type Credential struct {
+	APIKey string `json:"api_key"`
+	Secret string `json:"secret"`
+}
+
+type Store struct {
+	path     string
+	file     storeFile
+	key      []byte
+	Accounts map[string]Credential
+}
+
+type storeFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       string `json:"salt,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	KeyringID  string `json:"keyring_id,omitempty"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+func Path() string {
+	if p := os.Getenv("KEYSTORE_FILE"); p != "" {
+		return p
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "keystore.json"
+	}
+	return filepath.Join(dir, "gokub", "keystore.json")
+}
+
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Create initializes an empty keystore. The master key is kept in the OS
+// keyring when one is available, otherwise it is derived from the passphrase.
+func Create(path, passphrase string, useKeyring bool) (*Store, error) {
+	s := &Store{
+		path:     path,
+		Accounts: map[string]Credential{},
+		file:     storeFile{Version: 1},
+	}
+
+	if useKeyring {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+
+		id := keyringID(path)
+		if err := keyring.Set(keyringService, id, base64.StdEncoding.EncodeToString(key)); err == nil {
+			s.key = key
+			s.file.KDF = KDFKeyring
+			s.file.KeyringID = id
+			return s, nil
+		}
+	}
+
+	if passphrase == "" {
+		return nil, fmt.Errorf("OS keyring unavailable, a passphrase is required")
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	s.key = key
+	s.file.KDF = KDFPassphrase
+	s.file.Salt = base64.StdEncoding.EncodeToString(salt)
+	s.file.Iterations = iterations
+	return s, nil
+}
+
+func NeedsPassphrase(path string) (bool, error) {
+	f, err := readFile(path)
+	if err != nil {
+		return false, err
+	}
+	return f.KDF == KDFPassphrase, nil
+}
+
+func Open(path, passphrase string) (*Store, error) {
+	f, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{path: path, file: *f}
+
+	switch f.KDF {
+	case KDFKeyring:
+		encoded, err := keyring.Get(keyringService, f.KeyringID)
+		if err != nil {
+			return nil, fmt.Errorf("read master key from OS keyring: %w", err)
+		}
+		if s.key, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, fmt.Errorf("decode master key: %w", err)
+		}
+	case KDFPassphrase:
+		if passphrase == "" {
+			return nil, fmt.Errorf("keystore passphrase required")
+		}
+		salt, err := base64.StdEncoding.DecodeString(f.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("decode salt: %w", err)
+		}
+		if s.key, err = pbkdf2.Key(sha256.New, passphrase, salt, f.Iterations, 32); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported keystore kdf %q", f.KDF)
+	}
+
+	if err := s.decrypt(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.Accounts))
+	for name := range s.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Store) KDF() string {
+	return s.file.KDF
+}
+
+func (s *Store) Save() error {
+	plaintext, err := json.Marshal(s.Accounts)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	s.file.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	s.file.Ciphertext = base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, s.aad()))
+
+	data, err := json.MarshalIndent(s.file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *Store) decrypt() error {
+	s.Accounts = map[string]Credential{}
+	if s.file.Ciphertext == "" {
+		return nil
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(s.file.Nonce)
+	if err != nil {
+		return fmt.Errorf("decode nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(s.file.Ciphertext)
+	if err != nil {
+		return fmt.Errorf("decode ciphertext: %w", err)
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, s.aad())
+	if err != nil {
+		return fmt.Errorf("unlock keystore: wrong passphrase or corrupted file")
+	}
+
+	return json.Unmarshal(plaintext, &s.Accounts)
+}
+
+func (s *Store) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *Store) aad() []byte {
+	return []byte(fmt.Sprintf("gokub-keystore:v%d:%s", s.file.Version, s.file.KDF))
+}
+
+func readFile(path string) (*storeFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotExist
+		}
+		return nil, err
+	}
+
+	f := &storeFile{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parse keystore: %w", err)
+	}
+	return f, nil
+}
+
+func keyringID(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return "keystore-" + base64.RawURLEncoding.EncodeToString(sum[:9])
+}
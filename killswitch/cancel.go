@@ -0,0 +1,89 @@
+package killswitch
+
+import (
+	"fmt"
+	"gokub/accounts"
+	"gokub/symbols"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	"github.com/rs/zerolog/log"
+)
+
+type CancelledOrder struct {
+	Account string `json:"account"`
+	Symbol  string `json:"symbol"`
+	ID      string `json:"id"`
+	Side    string `json:"side"`
+}
+
+type CancelReport struct {
+	SymbolsChecked int               `json:"symbols_checked"`
+	Cancelled      []*CancelledOrder `json:"cancelled"`
+	Failed         []string          `json:"failed"`
+}
+
+func CancelAllOrders() *CancelReport {
+	report := &CancelReport{
+		Cancelled: []*CancelledOrder{},
+		Failed:    []string{},
+	}
+
+	tickers, err := market.GetTicker("")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list symbols for order cancellation")
+		report.Failed = append(report.Failed, fmt.Sprintf("list symbols: %v", err))
+		return report
+	}
+
+	pairs := make([]string, 0, len(tickers))
+	for _, ticker := range tickers {
+		pairs = append(pairs, symbols.Normalize(ticker.Symbol))
+	}
+	report.SymbolsChecked = len(pairs)
+
+	for _, account := range accounts.Names() {
+		cancelAccountOrders(report, account, pairs)
+	}
+
+	return report
+}
+
+// cancelAccountOrders switches to the account for each call rather than for
+// the whole sweep, so other tools are not locked out while it runs.
+func cancelAccountOrders(report *CancelReport, account string, symbols []string) {
+	for _, symbol := range symbols {
+		var orders []market.Order
+		err := accounts.Use(account, func() (err error) {
+			orders, err = market.GetOpenOrders(symbol)
+			return err
+		})
+		if err != nil {
+			log.Warn().Err(err).Str("account", account).Str("symbol", symbol).Msg("Failed to get open orders for cancellation")
+			report.Failed = append(report.Failed, fmt.Sprintf("%s %s: %v", account, symbol, err))
+			continue
+		}
+
+		for _, order := range orders {
+			err := accounts.Use(account, func() error {
+				return market.CancelOrder(market.CancelOrderRequest{
+					Symbol: symbol,
+					ID:     order.ID,
+					Side:   order.Side,
+				})
+			})
+			if err != nil {
+				log.Error().Err(err).Str("account", account).Str("symbol", symbol).Str("id", order.ID).Msg("Failed to cancel order")
+				report.Failed = append(report.Failed, fmt.Sprintf("%s %s %s: %v", account, symbol, order.ID, err))
+				continue
+			}
+
+			log.Warn().Str("account", account).Str("symbol", symbol).Str("id", order.ID).Str("side", order.Side).Msg("Order cancelled by kill switch")
+			report.Cancelled = append(report.Cancelled, &CancelledOrder{
+				Account: account,
+				Symbol:  symbol,
+				ID:      order.ID,
+				Side:    order.Side,
+			})
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package killswitch
+
+import (
+	"errors"
+	"gokub/utils"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+type State struct {
+	Halted   bool   `json:"halted"`
+	Reason   string `json:"reason,omitempty"`
+	Source   string `json:"source,omitempty"`
+	HaltedAt int64  `json:"halted_at,omitempty"`
+}
+
+var (
+	mu       sync.RWMutex
+	state    State
+	haltFile = "gokub.halt"
+)
+
+func Init() {
+	if path := os.Getenv("HALT_FILE"); path != "" {
+		haltFile = path
+	}
+
+	mu.Lock()
+	state = readHaltFile()
+	mu.Unlock()
+
+	if state.Halted {
+		log.Warn().Str("file", haltFile).Str("reason", state.Reason).Msg("Trading is halted, resume required before trading tools can run")
+	}
+
+	go watch(2 * time.Second)
+}
+
+func IsHalted() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return state.Halted
+}
+
+func Status() State {
+	mu.RLock()
+	defer mu.RUnlock()
+	return state
+}
+
+// Check returns a TRADING_HALTED error while trading is halted. Everything
+// that sends orders to the exchange calls it, so no code path can trade
+// through a halt.
+func Check() error {
+	status := Status()
+	if !status.Halted {
+		return nil
+	}
+	return utils.NewError(utils.CodeTradingHalted, "trading halted: %s", status.Reason).
+		With("halted_at", status.HaltedAt)
+}
+
+func Halt(reason, source string) (*CancelReport, error) {
+	mu.Lock()
+	state = State{
+		Halted:   true,
+		Reason:   reason,
+		Source:   source,
+		HaltedAt: time.Now().Unix(),
+	}
+	err := writeHaltFile(state)
+	mu.Unlock()
+
+	log.Warn().Str("reason", reason).Str("source", source).Msg("Trading halted")
+	if err != nil {
+		log.Error().Err(err).Str("file", haltFile).Msg("Failed to persist halt state")
+	}
+
+	return CancelAllOrders(), err
+}
+
+func Resume(source string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := os.Remove(haltFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	state = State{}
+
+	log.Warn().Str("source", source).Msg("Trading resumed")
+	return nil
+}
+
+func watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		mu.Lock()
+		current := readHaltFile()
+		changed := current.Halted != state.Halted
+		if changed {
+			state = current
+		}
+		mu.Unlock()
+
+		if !changed {
+			continue
+		}
+
+		if current.Halted {
+			log.Warn().Str("file", haltFile).Msg("Halt signal file detected, cancelling open orders")
+			CancelAllOrders()
+		} else {
+			log.Warn().Str("file", haltFile).Msg("Halt signal file removed, trading resumed")
+		}
+	}
+}
+
+func readHaltFile() State {
+	data, err := os.ReadFile(haltFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Error().Err(err).Str("file", haltFile).Msg("Failed to read halt file, assuming halted")
+			return State{Halted: true, Reason: "unreadable halt file", Source: "file"}
+		}
+		return State{}
+	}
+
+	s := State{}
+	if err := json.Unmarshal(data, &s); err != nil || !s.Halted {
+		s = State{Reason: "signal file", Source: "file"}
+		if info, err := os.Stat(haltFile); err == nil {
+			s.HaltedAt = info.ModTime().Unix()
+		}
+	}
+	s.Halted = true
+
+	return s
+}
+
+func writeHaltFile(s State) error {
+	if dir := filepath.Dir(haltFile); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(haltFile, data, 0o644)
+}
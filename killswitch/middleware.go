@@ -0,0 +1,87 @@
+package killswitch
+
+import (
+	"context"
+	"crypto/subtle"
+	"gokub/utils"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+func Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		halted := Check()
+		if halted == nil {
+			return next(ctx, request)
+		}
+
+		// Without the server the tool cannot be told apart from a trading
+		// tool, so it is blocked.
+		s := server.ServerFromContext(ctx)
+		var tool *server.ServerTool
+		if s != nil {
+			tool = s.GetTool(request.Params.Name)
+		}
+		if s == nil || (tool != nil && utils.IsTradingTool(tool.Tool) && !utils.Simulates(tool.Tool)) {
+			log.Warn().Str("tool", request.Params.Name).Str("reason", Status().Reason).Msg("Blocked trading tool while halted")
+			return utils.ClassifyError(halted).Result()
+		}
+
+		return next(ctx, request)
+	}
+}
+
+func AdminHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /admin/halt", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, Status())
+	})
+
+	mux.HandleFunc("POST /admin/halt", func(w http.ResponseWriter, r *http.Request) {
+		reason := r.URL.Query().Get("reason")
+		if reason == "" {
+			reason = "admin endpoint"
+		}
+
+		report, err := Halt(reason, "http")
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error(), "cancel": report})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"state": Status(), "cancel": report})
+	})
+
+	mux.HandleFunc("POST /admin/resume", func(w http.ResponseWriter, r *http.Request) {
+		if err := Resume("http"); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, Status())
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(utils.MustJSON(v)))
+}
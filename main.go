@@ -1,14 +1,32 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"gokub/accounts"
+	"gokub/alerts"
+	"gokub/algo"
+	"gokub/audit"
+	"gokub/auth"
+	"gokub/conditional"
+	"gokub/dca"
+	"gokub/grid"
+	"gokub/killswitch"
+	"gokub/metrics"
+	"gokub/notify"
 	"gokub/prompts"
+	"gokub/rebalance"
 	"gokub/resources"
+	"gokub/strategy"
+	"gokub/symbols"
 	"gokub/tools"
+	"gokub/tracing"
 	"gokub/utils"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/dvgamerr-app/go-bitkub/bitkub"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/rs/zerolog/log"
 	"github.com/tmilewski/goenv"
@@ -18,19 +36,6 @@ func init() {
 	goenv.Load()
 
 	utils.InitLogger()
-
-	apiKey := os.Getenv("BTK_APIKEY")
-	secretKey := os.Getenv("BTK_SECRET")
-
-	if apiKey == "" || secretKey == "" {
-		log.Warn().Msg("BTK_APIKEY and BTK_SECRET not set in environment")
-		log.Info().Msg("Please set them to use Bitkub API features")
-	} else {
-		if err := bitkub.Initlizer(apiKey, secretKey); err != nil {
-			log.Fatal().Err(err).Msg("Failed to initialize Bitkub client")
-		}
-		log.Info().Msg("Bitkub client initialized successfully")
-	}
 }
 
 func logServerInfo(s *server.MCPServer, mode string) {
@@ -52,14 +57,77 @@ var (
 )
 
 func main() {
-	serveHTTP := flag.Bool("serv", false, "Run server in HTTP mode instead of stdio")
-	flag.BoolVar(serveHTTP, "s", false, "Run server in HTTP mode instead of stdio (shorthand)")
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		os.Exit(runKeysCommand(os.Args[2:]))
+	}
+
+	transport := flag.String("transport", "stdio", "Transport: stdio, sse, http (streamable HTTP at /mcp) or sse,http to serve both")
+	serveHTTP := flag.Bool("serv", false, "Run server in SSE mode instead of stdio (same as -transport=sse)")
+	flag.BoolVar(serveHTTP, "s", false, "Run server in SSE mode instead of stdio (shorthand)")
+	hashToken := flag.Bool("hash-token", false, "Read a bearer token from stdin and print its hash for AUTH_TOKENS_FILE")
 	flag.Parse()
 
+	if *hashToken {
+		printTokenHash()
+		return
+	}
+
+	mode := *transport
+	if *serveHTTP {
+		mode = "sse"
+	}
+	transports := parseTransports(mode)
+
+	if err := loadKeystoreAccounts(!transports["stdio"]); err != nil {
+		log.Fatal().Err(err).Msg("Failed to unlock keystore")
+	}
+
+	if err := accounts.Init(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize Bitkub client")
+	}
+
+	killswitch.Init()
+	audit.Init()
+
+	shutdownTracing, err := tracing.Init()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize tracing")
+	}
+	defer shutdownTracing(context.Background())
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		shutdownTracing(context.Background())
+		os.Exit(0)
+	}()
+	http.DefaultTransport = tracing.InstrumentTransport(http.DefaultTransport)
+
+	symbols.Init()
+	// Before tools are registered so evaluate_strategy lists the loaded strategies.
+	strategy.Init()
+
+	if err := notify.Init(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize notifications")
+	}
+
+	if err := auth.Init(accounts.Names()); err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize authentication")
+	}
+
 	s := server.NewMCPServer(
 		name,
 		version,
 		server.WithResourceCapabilities(true, true),
+		server.WithLogging(),
+		server.WithHooks(sessionHooks()),
+		server.WithToolFilter(auth.ToolFilter),
+		server.WithToolHandlerMiddleware(tracing.Middleware),
+		server.WithToolHandlerMiddleware(metrics.Middleware),
+		server.WithToolHandlerMiddleware(audit.Middleware),
+		server.WithToolHandlerMiddleware(notify.Middleware),
+		server.WithToolHandlerMiddleware(auth.ToolMiddleware),
+		server.WithToolHandlerMiddleware(killswitch.Middleware),
 	)
 
 	s.AddTool(tools.NewWalletBalanceTool(), tools.WalletBalanceHandler)
@@ -71,6 +139,9 @@ func main() {
 	s.AddTool(tools.NewCalculatePositionSizeTool(), tools.CalculatePositionSizeHandler)
 	s.AddTool(tools.NewCalculateSpreadTool(), tools.CalculateSpreadHandler)
 	s.AddTool(tools.NewCalculateLiquidityDepthTool(), tools.CalculateLiquidityDepthHandler)
+	s.AddTool(tools.NewEstimateFillTool(), tools.EstimateFillHandler)
+	s.AddTool(tools.NewAnalyzeOrderBookTool(), tools.AnalyzeOrderBookHandler)
+	s.AddTool(tools.NewRecentTradesTool(), tools.RecentTradesHandler)
 	s.AddTool(tools.NewGetMarketScreenerTool(), tools.GetMarketScreenerHandler)
 	s.AddTool(tools.NewHistoricalCandlesTool(), tools.HistoricalCandlesHandler)
 	s.AddTool(tools.NewCalculateEMATool(), tools.CalculateEMAHandler)
@@ -80,39 +151,66 @@ func main() {
 	s.AddTool(tools.NewCalculateRelativeStrengthRankTool(), tools.CalculateRelativeStrengthRankHandler)
 	s.AddTool(tools.NewDetectBreakoutSignalTool(), tools.DetectBreakoutSignalHandler)
 	s.AddTool(tools.NewDetectPullbackSignalTool(), tools.DetectPullbackSignalHandler)
+	s.AddTool(tools.NewEvaluateStrategyTool(), tools.EvaluateStrategyHandler)
 	s.AddTool(tools.NewCheckMarketRegimeTool(), tools.CheckMarketRegimeHandler)
+	s.AddTool(tools.NewCreateAlertTool(), tools.CreateAlertHandler)
+	s.AddTool(tools.NewListAlertsTool(), tools.ListAlertsHandler)
+	s.AddTool(tools.NewDeleteAlertTool(), tools.DeleteAlertHandler)
+	s.AddTool(tools.NewGridCreateTool(), tools.GridCreateHandler)
+	s.AddTool(tools.NewGridStatusTool(), tools.GridStatusHandler)
+	s.AddTool(tools.NewGridStopTool(), tools.GridStopHandler)
+	s.AddTool(tools.NewRebalancePreviewTool(), tools.RebalancePreviewHandler)
+	s.AddTool(tools.NewRebalanceExecuteTool(), tools.RebalanceExecuteHandler)
+	s.AddTool(tools.NewDCACreateTool(), tools.DCACreateHandler)
+	s.AddTool(tools.NewDCAListTool(), tools.DCAListHandler)
+	s.AddTool(tools.NewDCAPauseTool(), tools.DCAPauseHandler)
+	s.AddTool(tools.NewDCADeleteTool(), tools.DCADeleteHandler)
+	s.AddTool(tools.NewPlaceConditionalOrderTool(), tools.PlaceConditionalOrderHandler)
+	s.AddTool(tools.NewPlaceOCOOrderTool(), tools.PlaceOCOOrderHandler)
+	s.AddTool(tools.NewListConditionalOrdersTool(), tools.ListConditionalOrdersHandler)
+	s.AddTool(tools.NewCancelConditionalOrderTool(), tools.CancelConditionalOrderHandler)
+	s.AddTool(tools.NewPlaceBracketOrderTool(), tools.PlaceBracketOrderHandler)
+	s.AddTool(tools.NewAlgoCreateTool(), tools.AlgoCreateHandler)
+	s.AddTool(tools.NewAlgoStatusTool(), tools.AlgoStatusHandler)
+	s.AddTool(tools.NewAlgoPauseTool(), tools.AlgoPauseHandler)
+	s.AddTool(tools.NewAlgoCancelTool(), tools.AlgoCancelHandler)
+	s.AddTool(tools.NewTradingHaltTool(), tools.TradingHaltHandler)
+	s.AddTool(tools.NewAuditLogTool(), tools.AuditLogHandler)
 
 	s.AddPrompt(prompts.NewTradingStrategyPrompt(), prompts.TradingStrategyHandler)
 	s.AddPrompt(prompts.NewMarketAnalysisPrompt(), prompts.MarketAnalysisHandler)
 
 	s.AddResource(resources.NewSymbolsResource().Resource, resources.NewSymbolsResource().Handler)
+	s.AddResource(resources.NewAlertsResource().Resource, resources.NewAlertsResource().Handler)
 	s.AddResourceTemplate(resources.NewTickerResource().Template, resources.NewTickerResource().Handler)
 
-	if *serveHTTP {
-		logServerInfo(s, "HTTP")
-
-		port := os.Getenv("PORT")
-		if port == "" {
-			port = "3000"
+	alerts.NotifySessions(s)
+	alerts.NotifySinks()
+	alerts.Init(tools.EvaluateAlert)
+	grid.Init()
+	rebalance.Init()
+	dca.Init(tools.EvaluateDip)
+	conditional.Init()
+	algo.Init(tools.AlgoVolume)
+
+	if transports["stdio"] {
+		if len(transports) > 1 {
+			log.Fatal().Str("transport", mode).Msg("stdio transport cannot be combined with HTTP transports")
 		}
 
-		log.Info().Str("port", port).Msgf("Server listening on http://localhost:%s", port)
-		log.Info().Msg("Endpoint SSE: /sse, Message: /msg")
-
-		sseServer := server.NewSSEServer(s,
-			server.WithSSEEndpoint("/sse"),
-			server.WithMessageEndpoint("/msg"),
-		)
-
-		addr := ":" + port
-		if err := sseServer.Start(addr); err != nil {
-			log.Fatal().Err(err).Msg("Server error")
-		}
-	} else {
 		logServerInfo(s, "stdio")
 
 		if err := server.ServeStdio(s); err != nil {
+			shutdownTracing(context.Background())
 			log.Fatal().Err(err).Msg("Server error")
 		}
+		return
+	}
+
+	logServerInfo(s, "HTTP")
+
+	if err := serveHTTPTransports(s, transports); err != nil {
+		shutdownTracing(context.Background())
+		log.Fatal().Err(err).Msg("Server error")
 	}
 }
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	toolCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gokub_tool_calls_total",
+		Help: "Tool calls by tool name and outcome.",
+	}, []string{"tool", "status"})
+
+	toolErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gokub_tool_errors_total",
+		Help: "Tool calls that returned an error.",
+	}, []string{"tool"})
+
+	toolDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gokub_tool_duration_seconds",
+		Help:    "Tool call latency.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"tool"})
+
+	exchangeRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gokub_exchange_requests_total",
+		Help: "HTTP requests to the Bitkub API by endpoint and status code.",
+	}, []string{"endpoint", "status"})
+
+	exchangeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gokub_exchange_request_duration_seconds",
+		Help:    "Bitkub API request latency.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gokub_cache_requests_total",
+		Help: "Cache lookups by cache name and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	activeSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gokub_active_sessions",
+		Help: "Connected MCP client sessions.",
+	})
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func CacheHit(cache string) {
+	cacheRequests.WithLabelValues(cache, "hit").Inc()
+}
+
+func CacheMiss(cache string) {
+	cacheRequests.WithLabelValues(cache, "miss").Inc()
+}
+
+func SessionStarted() {
+	activeSessions.Inc()
+}
+
+func SessionEnded() {
+	activeSessions.Dec()
+}
+
+func Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		tool := request.Params.Name
+		toolDuration.WithLabelValues(tool).Observe(time.Since(start).Seconds())
+
+		status := "ok"
+		if err != nil || (result != nil && result.IsError) {
+			status = "error"
+			toolErrors.WithLabelValues(tool).Inc()
+		}
+		toolCalls.WithLabelValues(tool, status).Inc()
+
+		return result, err
+	}
+}
+
+type exchangeTransport struct {
+	next http.RoundTripper
+}
+
+// InstrumentTransport wraps the transport go-bitkub sends requests through so
+// every call to the Bitkub API is counted by endpoint and status.
+func InstrumentTransport(next http.RoundTripper) http.RoundTripper {
+	return &exchangeTransport{next: next}
+}
+
+func (t *exchangeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Hostname(), "bitkub.com") {
+		return t.next.RoundTrip(req)
+	}
+
+	endpoint := req.URL.Path
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	exchangeDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	exchangeRequests.WithLabelValues(endpoint, status).Inc()
+
+	return resp, err
+}
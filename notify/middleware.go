@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"context"
+	"gokub/audit"
+	"gokub/utils"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Middleware sends a trade confirmation for every successful trading tool
+// call that placed or cancelled orders.
+func Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err != nil || result == nil || result.IsError || !Enabled() {
+			return result, err
+		}
+
+		s := server.ServerFromContext(ctx)
+		if s == nil {
+			return result, err
+		}
+		tool := s.GetTool(request.Params.Name)
+		if tool == nil || !utils.IsTradingTool(tool.Tool) {
+			return result, err
+		}
+
+		ider, ok := result.StructuredContent.(audit.OrderIDer)
+		if !ok || len(ider.OrderIDs()) == 0 {
+			return result, err
+		}
+
+		text := ""
+		for _, content := range result.Content {
+			if t, ok := content.(mcp.TextContent); ok {
+				text = t.Text
+				break
+			}
+		}
+		symbol := ""
+		if args, ok := request.Params.Arguments.(map[string]any); ok {
+			symbol, _ = args["symbol"].(string)
+		}
+
+		Send(Event{
+			Kind:    KindTrade,
+			Title:   strings.TrimSpace(request.Params.Name + " " + strings.ToUpper(symbol)),
+			Message: text,
+			Symbol:  symbol,
+			Fields:  map[string]any{"tool": request.Params.Name, "order_ids": ider.OrderIDs()},
+		})
+
+		return result, err
+	}
+}
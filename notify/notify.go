@@ -0,0 +1,241 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const (
+	KindAlert = "alert"
+	KindTrade = "trade"
+)
+
+type Event struct {
+	Kind    string         `json:"kind"`
+	Title   string         `json:"title"`
+	Message string         `json:"message"`
+	Symbol  string         `json:"symbol,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
+	Time    time.Time      `json:"time"`
+}
+
+// Message is an event rendered through its kind's template.
+type Message struct {
+	Event Event
+	Text  string
+}
+
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// permanentError marks a delivery failure that retrying cannot fix, such as
+// a 4xx response to a malformed payload or a revoked token.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return permanentError{err: err}
+}
+
+var defaultTemplates = map[string]string{
+	KindAlert: "🔔 {{.Title}}\n{{.Message}}",
+	KindTrade: "💱 {{.Title}}\n{{.Message}}",
+}
+
+var (
+	sinks       []*worker
+	templates   = map[string]*template.Template{}
+	events      = map[string]bool{KindAlert: true, KindTrade: true}
+	maxAttempts = 5
+	baseBackoff = time.Second
+	maxBackoff  = time.Minute
+
+	deadMu         sync.Mutex
+	deadLetterFile = "logs/notify-dead-letter.jsonl"
+)
+
+type worker struct {
+	sink  Sink
+	queue chan Message
+}
+
+// Init configures sinks from the environment. With no sink configured Send is
+// a no-op.
+func Init() error {
+	if path := os.Getenv("NOTIFY_DEAD_LETTER_FILE"); path != "" {
+		deadLetterFile = path
+	}
+	if n, err := strconv.Atoi(os.Getenv("NOTIFY_MAX_ATTEMPTS")); err == nil && n > 0 {
+		maxAttempts = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("NOTIFY_RETRY_BACKOFF")); err == nil && d > 0 {
+		baseBackoff = d
+	}
+	if list := os.Getenv("NOTIFY_EVENTS"); list != "" {
+		events = map[string]bool{}
+		for _, kind := range strings.Split(list, ",") {
+			events[strings.ToLower(strings.TrimSpace(kind))] = true
+		}
+	}
+
+	for kind, text := range defaultTemplates {
+		if custom := os.Getenv("NOTIFY_TEMPLATE_" + strings.ToUpper(kind)); custom != "" {
+			text = strings.ReplaceAll(custom, `\n`, "\n")
+		}
+		tmpl, err := template.New(kind).Parse(text)
+		if err != nil {
+			return fmt.Errorf("parse %s notification template: %w", kind, err)
+		}
+		templates[kind] = tmpl
+	}
+
+	configured, err := sinksFromEnv()
+	if err != nil {
+		return err
+	}
+	for _, sink := range configured {
+		w := &worker{sink: sink, queue: make(chan Message, 100)}
+		sinks = append(sinks, w)
+		go w.run()
+		log.Info().Str("sink", sink.Name()).Msg("Notification sink enabled")
+	}
+
+	return nil
+}
+
+func Enabled() bool {
+	return len(sinks) > 0
+}
+
+// Send renders the event and queues it on every sink. It never blocks: when
+// a sink's queue is full the message goes straight to the dead-letter file.
+func Send(e Event) {
+	if len(sinks) == 0 || !events[e.Kind] {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	msg := Message{Event: e, Text: render(e)}
+	for _, w := range sinks {
+		select {
+		case w.queue <- msg:
+		default:
+			deadLetter(w.sink.Name(), msg, 0, errors.New("queue full"))
+		}
+	}
+}
+
+func render(e Event) string {
+	tmpl, ok := templates[e.Kind]
+	if !ok {
+		return e.Title + "\n" + e.Message
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, e); err != nil {
+		log.Warn().Err(err).Str("kind", e.Kind).Msg("Failed to render notification template")
+		return e.Title + "\n" + e.Message
+	}
+	return buf.String()
+}
+
+func (w *worker) run() {
+	for msg := range w.queue {
+		w.deliver(msg)
+	}
+}
+
+func (w *worker) deliver(msg Message) {
+	backoff := baseBackoff
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		err = w.sink.Send(ctx, msg)
+		cancel()
+		if err == nil {
+			log.Debug().Str("sink", w.sink.Name()).Str("kind", msg.Event.Kind).Int("attempt", attempt).Msg("Notification delivered")
+			return
+		}
+
+		var perm permanentError
+		if errors.As(err, &perm) {
+			deadLetter(w.sink.Name(), msg, attempt, err)
+			return
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		log.Warn().Err(err).Str("sink", w.sink.Name()).Int("attempt", attempt).Dur("retry_in", backoff).Msg("Notification delivery failed, retrying")
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxBackoff)
+	}
+	deadLetter(w.sink.Name(), msg, maxAttempts, err)
+}
+
+type deadLetterEntry struct {
+	Timestamp int64  `json:"timestamp"`
+	Sink      string `json:"sink"`
+	Attempts  int    `json:"attempts"`
+	Error     string `json:"error"`
+	Event     Event  `json:"event"`
+	Text      string `json:"text"`
+}
+
+func deadLetter(sink string, msg Message, attempts int, cause error) {
+	log.Error().Err(cause).Str("sink", sink).Str("kind", msg.Event.Kind).Int("attempts", attempts).Msg("Notification dropped to dead-letter file")
+
+	data, err := json.Marshal(deadLetterEntry{
+		Timestamp: time.Now().UnixMilli(),
+		Sink:      sink,
+		Attempts:  attempts,
+		Error:     cause.Error(),
+		Event:     msg.Event,
+		Text:      msg.Text,
+	})
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	deadMu.Lock()
+	defer deadMu.Unlock()
+
+	if dir := filepath.Dir(deadLetterFile); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			log.Error().Err(err).Str("file", deadLetterFile).Msg("Failed to create dead-letter directory")
+			return
+		}
+	}
+	f, err := os.OpenFile(deadLetterFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		log.Error().Err(err).Str("file", deadLetterFile).Msg("Failed to open dead-letter file")
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		log.Error().Err(err).Str("file", deadLetterFile).Msg("Failed to write dead-letter entry")
+	}
+}
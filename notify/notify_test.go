@@ -0,0 +1,205 @@
+package notify
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a webhook endpoint that answers with the given statuses in
+// turn, repeating the last one, and keeps every request it saw.
+type recorder struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []recorded
+}
+
+type recorded struct {
+	at     time.Time
+	header http.Header
+	body   []byte
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, recorded{at: time.Now(), header: req.Header.Clone(), body: body})
+	status := r.statuses[min(len(r.requests), len(r.statuses))-1]
+	w.WriteHeader(status)
+}
+
+func (r *recorder) seen() []recorded {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]recorded(nil), r.requests...)
+}
+
+func newRecorder(t *testing.T, statuses ...int) (*recorder, string) {
+	rec := &recorder{statuses: statuses}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+	return rec, srv.URL
+}
+
+// setup points the dead-letter file into a temp dir and shortens the retry
+// schedule, restoring both when the test ends.
+func setup(t *testing.T, attempts int, backoff time.Duration) {
+	oldFile, oldAttempts, oldBackoff := deadLetterFile, maxAttempts, baseBackoff
+	t.Cleanup(func() { deadLetterFile, maxAttempts, baseBackoff = oldFile, oldAttempts, oldBackoff })
+
+	deadLetterFile = filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	maxAttempts, baseBackoff = attempts, backoff
+}
+
+func deadLetters(t *testing.T) []deadLetterEntry {
+	t.Helper()
+	f, err := os.Open(deadLetterFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("open dead-letter file: %v", err)
+	}
+	defer f.Close()
+
+	var entries []deadLetterEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e deadLetterEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("decode dead-letter entry: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func testMessage() Message {
+	e := Event{Kind: KindAlert, Title: "BTC_THB above 2,000,000", Message: "last 2,010,000", Symbol: "btc_thb", Time: time.Unix(1700000000, 0)}
+	return Message{Event: e, Text: render(e)}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	rec, url := newRecorder(t, http.StatusOK)
+	sink := &webhookSink{url: url, secret: "s3cret"}
+
+	if err := sink.Send(t.Context(), testMessage()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	req := rec.seen()[0]
+	ts := req.header.Get("X-Gokub-Timestamp")
+	if ts == "" {
+		t.Fatal("no X-Gokub-Timestamp header")
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(ts + "."))
+	mac.Write(req.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := req.header.Get("X-Gokub-Signature"); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if payload["symbol"] != "btc_thb" || payload["kind"] != KindAlert {
+		t.Errorf("unexpected payload %v", payload)
+	}
+}
+
+func TestWebhookUnsignedWithoutSecret(t *testing.T) {
+	rec, url := newRecorder(t, http.StatusOK)
+	sink := &webhookSink{url: url}
+
+	if err := sink.Send(t.Context(), testMessage()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if sig := rec.seen()[0].header.Get("X-Gokub-Signature"); sig != "" {
+		t.Errorf("unexpected signature %q", sig)
+	}
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	setup(t, 5, 20*time.Millisecond)
+	rec, url := newRecorder(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	w := &worker{sink: &webhookSink{url: url}}
+
+	w.deliver(testMessage())
+
+	reqs := rec.seen()
+	if len(reqs) != 3 {
+		t.Fatalf("got %d requests, want 3", len(reqs))
+	}
+	for i, want := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond} {
+		if gap := reqs[i+1].at.Sub(reqs[i].at); gap < want {
+			t.Errorf("retry %d came after %v, want at least %v", i+1, gap, want)
+		}
+	}
+	if entries := deadLetters(t); len(entries) != 0 {
+		t.Errorf("delivered message was dead-lettered: %+v", entries)
+	}
+}
+
+func TestDeliverDeadLettersAfterMaxAttempts(t *testing.T) {
+	setup(t, 3, time.Millisecond)
+	rec, url := newRecorder(t, http.StatusInternalServerError)
+	w := &worker{sink: &webhookSink{url: url}}
+
+	w.deliver(testMessage())
+
+	if n := len(rec.seen()); n != 3 {
+		t.Fatalf("got %d requests, want 3", n)
+	}
+	entries := deadLetters(t)
+	if len(entries) != 1 {
+		t.Fatalf("got %d dead-letter entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Sink != "webhook" || e.Attempts != 3 || e.Event.Symbol != "btc_thb" || e.Text != testMessage().Text {
+		t.Errorf("unexpected dead-letter entry %+v", e)
+	}
+}
+
+func TestDeliverDoesNotRetryPermanentFailure(t *testing.T) {
+	setup(t, 5, time.Millisecond)
+	rec, url := newRecorder(t, http.StatusBadRequest)
+	w := &worker{sink: &webhookSink{url: url}}
+
+	w.deliver(testMessage())
+
+	if n := len(rec.seen()); n != 1 {
+		t.Fatalf("got %d requests, want 1", n)
+	}
+	entries := deadLetters(t)
+	if len(entries) != 1 || entries[0].Attempts != 1 {
+		t.Fatalf("unexpected dead-letter entries %+v", entries)
+	}
+}
+
+func TestSendDeadLettersWhenQueueFull(t *testing.T) {
+	setup(t, 1, time.Millisecond)
+	oldSinks := sinks
+	t.Cleanup(func() { sinks = oldSinks })
+	sinks = []*worker{{sink: &webhookSink{url: "http://127.0.0.1:0"}, queue: make(chan Message)}}
+
+	Send(testMessage().Event)
+
+	entries := deadLetters(t)
+	if len(entries) != 1 || entries[0].Error != "queue full" || entries[0].Attempts != 0 {
+		t.Fatalf("unexpected dead-letter entries %+v", entries)
+	}
+}
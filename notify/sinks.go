@@ -0,0 +1,247 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const linePushURL = "https://api.line.me/v2/bot/message/push"
+
+func sinksFromEnv() ([]Sink, error) {
+	var sinks []Sink
+
+	if u := os.Getenv("NOTIFY_WEBHOOK_URL"); u != "" {
+		sinks = append(sinks, &webhookSink{url: u, secret: os.Getenv("NOTIFY_WEBHOOK_SECRET")})
+	}
+	if u := os.Getenv("NOTIFY_DISCORD_WEBHOOK_URL"); u != "" {
+		sinks = append(sinks, &discordSink{url: u})
+	}
+	if token := os.Getenv("NOTIFY_LINE_TOKEN"); token != "" {
+		line := &lineSink{token: token, to: os.Getenv("NOTIFY_LINE_TO"), url: os.Getenv("NOTIFY_LINE_URL")}
+		if line.to == "" {
+			return nil, fmt.Errorf("NOTIFY_LINE_TO must name the user, group or room to push to")
+		}
+		if line.url == "" {
+			line.url = linePushURL
+		}
+		sinks = append(sinks, line)
+	}
+	if addr := os.Getenv("NOTIFY_SMTP_ADDR"); addr != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("NOTIFY_SMTP_ADDR must be host:port: %w", err)
+		}
+		mail := &smtpSink{
+			addr:     addr,
+			host:     host,
+			username: os.Getenv("NOTIFY_SMTP_USERNAME"),
+			password: os.Getenv("NOTIFY_SMTP_PASSWORD"),
+			from:     os.Getenv("NOTIFY_SMTP_FROM"),
+		}
+		for _, to := range strings.Split(os.Getenv("NOTIFY_SMTP_TO"), ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				mail.to = append(mail.to, to)
+			}
+		}
+		if mail.from == "" || len(mail.to) == 0 {
+			return nil, fmt.Errorf("NOTIFY_SMTP_FROM and NOTIFY_SMTP_TO are required with NOTIFY_SMTP_ADDR")
+		}
+		sinks = append(sinks, mail)
+	}
+
+	return sinks, nil
+}
+
+// post sends one HTTP request and classifies the response: 2xx is delivered,
+// 429 and 5xx are retried, any other status is permanent.
+func post(ctx context.Context, target, contentType string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return permanent(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "gokub-notify")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	err = fmt.Errorf("%s", resp.Status)
+	if detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512)); len(bytes.TrimSpace(detail)) > 0 {
+		err = fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return permanent(err)
+}
+
+// webhookSink posts the event as JSON. With a secret, the body is signed as
+// X-Gokub-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body)).
+type webhookSink struct {
+	url    string
+	secret string
+}
+
+func (s *webhookSink) Name() string { return "webhook" }
+
+func (s *webhookSink) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]any{
+		"kind":    msg.Event.Kind,
+		"title":   msg.Event.Title,
+		"message": msg.Event.Message,
+		"symbol":  msg.Event.Symbol,
+		"fields":  msg.Event.Fields,
+		"text":    msg.Text,
+		"time":    msg.Event.Time.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return permanent(err)
+	}
+
+	headers := map[string]string{}
+	if s.secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		headers["X-Gokub-Timestamp"] = ts
+		headers["X-Gokub-Signature"] = "sha256=" + sign(s.secret, ts, body)
+	}
+
+	return post(ctx, s.url, "application/json", body, headers)
+}
+
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type discordSink struct {
+	url string
+}
+
+func (s *discordSink) Name() string { return "discord" }
+
+func (s *discordSink) Send(ctx context.Context, msg Message) error {
+	color := 0x3498db
+	if msg.Event.Kind == KindTrade {
+		color = 0x2ecc71
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"username": "gokub",
+		"embeds": []map[string]any{{
+			"title":       truncate(msg.Event.Title, 256),
+			"description": truncate(msg.Text, 4096),
+			"color":       color,
+			"timestamp":   msg.Event.Time.UTC().Format(time.RFC3339),
+		}},
+	})
+	if err != nil {
+		return permanent(err)
+	}
+
+	return post(ctx, s.url, "application/json", body, nil)
+}
+
+// lineSink pushes to a user, group or room through the LINE Messaging API
+// with a channel access token.
+type lineSink struct {
+	url   string
+	token string
+	to    string
+}
+
+func (s *lineSink) Name() string { return "line" }
+
+func (s *lineSink) Send(ctx context.Context, msg Message) error {
+	headers := map[string]string{"Authorization": "Bearer " + s.token}
+
+	body, err := json.Marshal(map[string]any{
+		"to":       s.to,
+		"messages": []map[string]any{{"type": "text", "text": truncate(msg.Text, 5000)}},
+	})
+	if err != nil {
+		return permanent(err)
+	}
+	return post(ctx, s.url, "application/json", body, headers)
+}
+
+type smtpSink struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func (s *smtpSink) Name() string { return "smtp" }
+
+func (s *smtpSink) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&b, "Subject: [gokub] %s\r\n", strings.ReplaceAll(msg.Event.Title, "\n", " "))
+	fmt.Fprintf(&b, "Date: %s\r\n", msg.Event.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Text, "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	// net/smtp has no context support; run it aside so a hung server
+	// does not hold the worker past the delivery timeout.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, auth, s.from, s.to, []byte(b.String()))
+	}()
+
+	select {
+	case err := <-done:
+		// 5xx replies (unknown mailbox, auth rejected) will not succeed on retry.
+		var reply *textproto.Error
+		if errors.As(err, &reply) && reply.Code >= 500 {
+			return permanent(err)
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
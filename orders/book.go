@@ -0,0 +1,64 @@
+package orders
+
+import "math"
+
+// BookFill is the result of walking one side of the order book.
+type BookFill struct {
+	Coin   float64
+	THB    float64
+	Levels int
+	Worst  float64
+	Filled bool
+}
+
+func (f BookFill) VWAP() float64 {
+	if f.Coin == 0 {
+		return 0
+	}
+	return f.THB / f.Coin
+}
+
+// WalkBook takes liquidity level by level until amount is filled. The amount
+// is in THB when inTHB is set, otherwise in coin.
+func WalkBook(levels [][]float64, amount float64, inTHB bool) BookFill {
+	var fill BookFill
+	remaining := amount
+
+	for _, level := range levels {
+		if len(level) < 2 || remaining <= 0 {
+			break
+		}
+		price, size := level[0], level[1]
+		if price <= 0 || size <= 0 {
+			continue
+		}
+
+		var take float64
+		if inTHB {
+			take = math.Min(size, remaining/price)
+			remaining -= take * price
+		} else {
+			take = math.Min(size, remaining)
+			remaining -= take
+		}
+
+		fill.Coin += take
+		fill.THB += take * price
+		fill.Levels++
+		fill.Worst = price
+	}
+
+	fill.Filled = remaining <= amount*1e-9
+	return fill
+}
+
+// SlippageBps is how far price is from mid against the taker, in basis points.
+func SlippageBps(side string, price, mid float64) float64 {
+	if mid == 0 || price == 0 {
+		return 0
+	}
+	if side == Sell {
+		return (mid - price) / mid * 10000
+	}
+	return (price - mid) / mid * 10000
+}
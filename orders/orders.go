@@ -0,0 +1,167 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"gokub/accounts"
+	"gokub/killswitch"
+	"gokub/tracing"
+	"strconv"
+	"strings"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+)
+
+const (
+	Buy  = "buy"
+	Sell = "sell"
+
+	Limit  = "limit"
+	Market = "market"
+
+	StatusOpen      = "open"
+	StatusFilled    = "filled"
+	StatusCancelled = "cancelled"
+)
+
+// Request is an order in coin terms. For market buys Rate is the reference
+// price used to size the THB amount Bitkub expects.
+type Request struct {
+	Symbol string
+	Side   string
+	Type   string
+	Rate   float64
+	Amount float64
+}
+
+type Order struct {
+	ID        string  `json:"id"`
+	Symbol    string  `json:"symbol"`
+	Side      string  `json:"side"`
+	Type      string  `json:"type"`
+	Rate      float64 `json:"rate"`
+	Amount    float64 `json:"amount"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// Info is an order's state on the exchange. Filled is the coin amount matched
+// so far and AvgPrice the average rate it matched at; a cancelled order may
+// have filled in part.
+type Info struct {
+	Order
+	Status   string  `json:"status"`
+	Filled   float64 `json:"filled"`
+	AvgPrice float64 `json:"avg_price,omitempty"`
+	// Fee is the THB fee charged on the fills, when the exchange reports it.
+	Fee float64 `json:"fee,omitempty"`
+}
+
+// Exchange is what bots trade against: the live Bitkub account or an
+// in-memory simulation.
+type Exchange interface {
+	Place(ctx context.Context, req Request) (*Order, error)
+	Cancel(ctx context.Context, symbol, id, side string) error
+	Open(ctx context.Context, symbol string) ([]Order, error)
+	Info(ctx context.Context, symbol, id, side string) (*Info, error)
+	LastPrice(ctx context.Context, symbol string) (float64, error)
+}
+
+type live struct {
+	account string
+}
+
+// Live trades on the named account. Every call switches the shared go-bitkub
+// client to that account, see accounts.Use.
+func Live(account string) Exchange {
+	return &live{account: account}
+}
+
+func (l *live) Place(ctx context.Context, req Request) (*Order, error) {
+	if err := killswitch.Check(); err != nil {
+		return nil, err
+	}
+
+	orderType := req.Type
+	if orderType == "" {
+		orderType = Limit
+	}
+
+	var resp *market.PlaceOrderResponse
+	err := accounts.Use(l.account, func() (err error) {
+		if req.Side == Buy {
+			// Bitkub sizes bids in THB to spend.
+			resp, err = tracing.Exchange(ctx, "market.PlaceBid", req.Symbol, func() (*market.PlaceOrderResponse, error) {
+				return market.PlaceBid(market.PlaceOrderRequest{Symbol: req.Symbol, Amount: req.Amount * req.Rate, Rate: req.Rate, Type: orderType})
+			})
+			return err
+		}
+		resp, err = tracing.Exchange(ctx, "market.PlaceAsk", req.Symbol, func() (*market.PlaceOrderResponse, error) {
+			return market.PlaceAsk(market.PlaceOrderRequest{Symbol: req.Symbol, Amount: req.Amount, Rate: req.Rate, Type: orderType})
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Order{
+		ID:        resp.ID,
+		Symbol:    req.Symbol,
+		Side:      req.Side,
+		Type:      orderType,
+		Rate:      req.Rate,
+		Amount:    req.Amount,
+		Timestamp: resp.Timestamp,
+	}, nil
+}
+
+func (l *live) Cancel(ctx context.Context, symbol, id, side string) error {
+	return accounts.Use(l.account, func() error {
+		_, err := tracing.Exchange(ctx, "market.CancelOrder", symbol, func() (struct{}, error) {
+			return struct{}{}, market.CancelOrder(market.CancelOrderRequest{Symbol: symbol, ID: id, Side: side})
+		})
+		return err
+	})
+}
+
+func (l *live) Open(ctx context.Context, symbol string) ([]Order, error) {
+	var open []market.Order
+	err := accounts.Use(l.account, func() (err error) {
+		open, err = tracing.Exchange(ctx, "market.GetOpenOrders", symbol, func() ([]market.Order, error) {
+			return market.GetOpenOrders(symbol)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Order, 0, len(open))
+	for _, o := range open {
+		rate, _ := strconv.ParseFloat(o.Rate, 64)
+		amount, _ := strconv.ParseFloat(o.Amount, 64)
+		list = append(list, Order{
+			ID:        o.ID,
+			Symbol:    symbol,
+			Side:      strings.ToLower(o.Side),
+			Type:      o.Type,
+			Rate:      rate,
+			Amount:    amount,
+			Timestamp: o.Timestamp,
+		})
+	}
+	return list, nil
+}
+
+func (l *live) LastPrice(ctx context.Context, symbol string) (float64, error) {
+	tickers, err := tracing.Exchange(ctx, "market.GetTicker", symbol, func() ([]market.Ticker, error) {
+		return market.GetTicker(symbol)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(tickers) == 0 || tickers[0].Last <= 0 {
+		return 0, fmt.Errorf("no ticker data for %s", symbol)
+	}
+	return tickers[0].Last, nil
+}
@@ -0,0 +1,191 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fill is an order the simulation executed, in whole or in part. Amount is
+// what matched in this fill.
+type Fill struct {
+	Order
+	Price    float64 `json:"price"`
+	FilledAt int64   `json:"filled_at"`
+}
+
+// Simulated is an in-memory exchange. Limit orders rest until SetPrice moves
+// the price through them and fill at their own rate; market and marketable
+// orders fill at once. It needs no network, so bot logic can be exercised
+// offline.
+type Simulated struct {
+	mu     sync.Mutex
+	prices map[string]float64
+	orders map[string]*Info
+	fills  []Fill
+	seq    int
+}
+
+func NewSimulated() *Simulated {
+	return &Simulated{
+		prices: map[string]float64{},
+		orders: map[string]*Info{},
+	}
+}
+
+// SetPrice moves a symbol's price and fills every resting order it crosses.
+func (s *Simulated) SetPrice(symbol string, price float64) []Fill {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prices[symbol] = price
+
+	var filled []Fill
+	for _, o := range s.orders {
+		if o.Status != StatusOpen || o.Symbol != symbol || !crosses(o.Side, o.Rate, price) {
+			continue
+		}
+		filled = append(filled, s.fill(o, o.Amount-o.Filled, o.Rate))
+	}
+	sort.Slice(filled, func(i, j int) bool {
+		return filled[i].ID < filled[j].ID
+	})
+	return filled
+}
+
+// FillPart matches amount of a resting order at its rate, leaving the rest
+// on the book.
+func (s *Simulated) FillPart(id string, amount float64) (Fill, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[id]
+	if !ok || o.Status != StatusOpen {
+		return Fill{}, fmt.Errorf("order %s not found", id)
+	}
+	if amount <= 0 || amount > o.Amount-o.Filled {
+		return Fill{}, fmt.Errorf("order %s has %.8g left to fill", id, o.Amount-o.Filled)
+	}
+	return s.fill(o, amount, o.Rate), nil
+}
+
+func (s *Simulated) Fills() []Fill {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Fill(nil), s.fills...)
+}
+
+func (s *Simulated) Place(ctx context.Context, req Request) (*Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	price, ok := s.prices[req.Symbol]
+	if !ok {
+		return nil, fmt.Errorf("no simulated price for %s", req.Symbol)
+	}
+
+	s.seq++
+	o := &Info{
+		Order: Order{
+			ID:        fmt.Sprintf("sim-%06d", s.seq),
+			Symbol:    req.Symbol,
+			Side:      req.Side,
+			Type:      req.Type,
+			Rate:      req.Rate,
+			Amount:    req.Amount,
+			Timestamp: time.Now().Unix(),
+		},
+		Status: StatusOpen,
+	}
+	if o.Type == "" {
+		o.Type = Limit
+	}
+	s.orders[o.ID] = o
+
+	switch {
+	case o.Type == Market:
+		s.fill(o, o.Amount, price)
+	case crosses(o.Side, o.Rate, price):
+		s.fill(o, o.Amount, o.Rate)
+	}
+	placed := o.Order
+	return &placed, nil
+}
+
+func (s *Simulated) Cancel(ctx context.Context, symbol, id, side string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[id]
+	if !ok || o.Status != StatusOpen {
+		return fmt.Errorf("order %s not found", id)
+	}
+	o.Status = StatusCancelled
+	return nil
+}
+
+func (s *Simulated) Open(ctx context.Context, symbol string) ([]Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []Order{}
+	for _, o := range s.orders {
+		if o.Status == StatusOpen && o.Symbol == symbol {
+			list = append(list, o.Order)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+func (s *Simulated) Info(ctx context.Context, symbol, id, side string) (*Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return nil, fmt.Errorf("order %s not found", id)
+	}
+	info := *o
+	return &info, nil
+}
+
+func (s *Simulated) LastPrice(ctx context.Context, symbol string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	price, ok := s.prices[symbol]
+	if !ok {
+		return 0, fmt.Errorf("no simulated price for %s", symbol)
+	}
+	return price, nil
+}
+
+// fill must be called with mu held.
+func (s *Simulated) fill(o *Info, amount, price float64) Fill {
+	o.AvgPrice = (o.AvgPrice*o.Filled + price*amount) / (o.Filled + amount)
+	if amount >= o.Amount-o.Filled {
+		o.Filled, o.Status = o.Amount, StatusFilled
+	} else {
+		o.Filled += amount
+	}
+
+	f := Fill{Order: o.Order, Price: price, FilledAt: time.Now().Unix()}
+	f.Amount = amount
+	s.fills = append(s.fills, f)
+	return f
+}
+
+func crosses(side string, rate, price float64) bool {
+	if side == Buy {
+		return price <= rate
+	}
+	return price >= rate
+}
@@ -3,6 +3,7 @@ package prompts
 import (
 	"context"
 	"fmt"
+	"gokub/symbols"
 	"gokub/utils"
 	"strings"
 
@@ -20,7 +21,7 @@ func TradingStrategyHandler(ctx context.Context, request mcp.GetPromptRequest) (
 
 	symbol := "btc_thb"
 	if val, ok := args["symbol"].(string); ok {
-		symbol = strings.ToLower(val)
+		symbol = symbols.Normalize(val)
 	}
 
 	riskTolerance := "medium"
@@ -110,17 +111,17 @@ func MarketAnalysisHandler(ctx context.Context, request mcp.GetPromptRequest) (*
 		analysisType = val
 	}
 
-	symbols := strings.Split(symbolsStr, ",")
+	symbolList := strings.Split(symbolsStr, ",")
 	log.Debug().
-		Strs("symbols", symbols).
+		Strs("symbols", symbolList).
 		Str("analysis_type", analysisType).
 		Msg("Generating market analysis prompt")
 
 	var marketData strings.Builder
 	marketData.WriteString("Current Market Overview:\n\n")
 
-	for _, symbol := range symbols {
-		symbol = strings.TrimSpace(strings.ToLower(symbol))
+	for _, symbol := range symbolList {
+		symbol = symbols.Normalize(symbol)
 		tickers, err := market.GetTicker(symbol)
 		if err != nil || len(tickers) == 0 {
 			continue
@@ -152,7 +153,7 @@ Provide your analysis in a structured, professional format.`,
 		analysisType, marketData.String())
 
 	log.Info().
-		Strs("symbols", symbols).
+		Strs("symbols", symbolList).
 		Msg("Generated market analysis prompt")
 
 	return &mcp.GetPromptResult{
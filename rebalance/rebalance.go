@@ -0,0 +1,355 @@
+package rebalance
+
+import (
+	"context"
+	"fmt"
+	"gokub/accounts"
+	"gokub/killswitch"
+	"gokub/orders"
+	"gokub/symbols"
+	"gokub/tracing"
+	"gokub/utils"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	"github.com/rs/zerolog/log"
+)
+
+const quote = "THB"
+
+type Options struct {
+	Targets        map[string]float64 `json:"targets"`
+	DriftPercent   float64            `json:"drift_percent"`
+	MinTradeTHB    float64            `json:"min_trade_thb"`
+	FeePercent     float64            `json:"fee_percent"`
+	MaxSlippageBps float64            `json:"max_slippage_bps"`
+}
+
+type Holding struct {
+	Asset     string  `json:"asset"`
+	Amount    float64 `json:"amount"`
+	Available float64 `json:"available"`
+	Price     float64 `json:"price"`
+	ValueTHB  float64 `json:"value_thb"`
+	Weight    float64 `json:"weight"`
+	Target    float64 `json:"target"`
+	Drift     float64 `json:"drift"`
+}
+
+type Trade struct {
+	Symbol      string  `json:"symbol"`
+	Side        string  `json:"side"`
+	Amount      float64 `json:"amount"`
+	Price       float64 `json:"price"`
+	ValueTHB    float64 `json:"value_thb"`
+	EstPrice    float64 `json:"est_price"`
+	SlippageBps float64 `json:"slippage_bps"`
+	FeeTHB      float64 `json:"fee_thb"`
+	Thin        bool    `json:"thin,omitempty"`
+	Status      string  `json:"status"`
+	OrderID     string  `json:"order_id,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+type Skip struct {
+	Asset  string `json:"asset"`
+	Reason string `json:"reason"`
+}
+
+type Plan struct {
+	Account     string     `json:"account"`
+	TotalTHB    float64    `json:"total_thb"`
+	Holdings    []*Holding `json:"holdings"`
+	Trades      []*Trade   `json:"trades"`
+	Skipped     []*Skip    `json:"skipped"`
+	Untracked   []string   `json:"untracked"`
+	FeesTHB     float64    `json:"fees_thb"`
+	GeneratedAt int64      `json:"generated_at"`
+}
+
+const (
+	StatusPlanned = "planned"
+	StatusPlaced  = "placed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// ParseTargets reads "btc=50,eth=30,thb=20" into asset weights.
+func ParseTargets(s string) (map[string]float64, error) {
+	targets := map[string]float64{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		asset, weight, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("target %q must be asset=weight", part)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(weight), "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %w", part, err)
+		}
+		targets[strings.TrimSpace(asset)] = w
+	}
+	return targets, CheckTargets(targets)
+}
+
+// CheckTargets requires weights between 0 and 100 that add up to 100.
+func CheckTargets(targets map[string]float64) error {
+	if len(targets) == 0 {
+		return fmt.Errorf("at least one target weight is required")
+	}
+	sum := 0.0
+	for asset, w := range targets {
+		if w < 0 || w > 100 {
+			return fmt.Errorf("weight for %s must be between 0 and 100", strings.ToUpper(asset))
+		}
+		sum += w
+	}
+	if math.Abs(sum-100) > 0.01 {
+		return fmt.Errorf("weights add up to %.2f%%, they must add up to 100%%", sum)
+	}
+	return nil
+}
+
+// Preview prices the account's holdings and works out the market orders that
+// bring every asset drifting more than DriftPercent back to its target.
+// Sells come first so their THB can fund the buys.
+func Preview(ctx context.Context, account string, opts Options) (*Plan, error) {
+	targets := map[string]float64{}
+	for asset, w := range opts.Targets {
+		targets[strings.ToUpper(strings.TrimSpace(asset))] = w
+	}
+
+	var balances map[string]market.Balance
+	err := accounts.Use(account, func() (err error) {
+		balances, err = tracing.Exchange(ctx, "market.GetBalances", "", market.GetBalances)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tickers, err := tracing.Exchange(ctx, "market.GetTicker", "", func() ([]market.Ticker, error) {
+		return market.GetTicker("")
+	})
+	if err != nil {
+		return nil, err
+	}
+	prices := map[string]float64{}
+	for _, t := range tickers {
+		prices[symbols.Normalize(t.Symbol)] = t.Last
+	}
+
+	plan := &Plan{
+		Account:     account,
+		Holdings:    []*Holding{},
+		Trades:      []*Trade{},
+		Skipped:     []*Skip{},
+		Untracked:   []string{},
+		GeneratedAt: time.Now().Unix(),
+	}
+
+	held := map[string]market.Balance{}
+	for currency, b := range balances {
+		held[strings.ToUpper(currency)] = b
+	}
+	for currency, b := range held {
+		if _, ok := targets[currency]; !ok && b.Available+b.Reserved > 0 {
+			plan.Untracked = append(plan.Untracked, currency)
+		}
+	}
+	sort.Strings(plan.Untracked)
+
+	for asset, target := range targets {
+		b := held[asset]
+		h := &Holding{Asset: asset, Amount: b.Available + b.Reserved, Available: b.Available, Price: 1, Target: target}
+		if asset != quote {
+			price, ok := prices[symbols.Normalize(asset)]
+			if !ok || price <= 0 {
+				return nil, utils.NewError(utils.CodeNotFoundSymbol, "no %s market price for %s", quote, asset).With("asset", asset)
+			}
+			h.Price = price
+		}
+		h.ValueTHB = h.Amount * h.Price
+		plan.TotalTHB += h.ValueTHB
+		plan.Holdings = append(plan.Holdings, h)
+	}
+	if plan.TotalTHB <= 0 {
+		return nil, utils.NewError(utils.CodeInsufficientBalance, "the target assets hold no value to rebalance")
+	}
+
+	sort.Slice(plan.Holdings, func(i, j int) bool {
+		return plan.Holdings[i].ValueTHB > plan.Holdings[j].ValueTHB
+	})
+
+	var sells, buys []*Trade
+	for _, h := range plan.Holdings {
+		h.Weight = utils.Round(h.ValueTHB/plan.TotalTHB*100, 2)
+		h.Drift = utils.Round(h.Weight-h.Target, 2)
+		h.ValueTHB = utils.Round(h.ValueTHB, 2)
+		if h.Asset == quote {
+			continue
+		}
+
+		if math.Abs(h.Drift) < opts.DriftPercent {
+			plan.Skipped = append(plan.Skipped, &Skip{Asset: h.Asset, Reason: fmt.Sprintf("drift %+.2f%% is within %.2f%%", h.Drift, opts.DriftPercent)})
+			continue
+		}
+
+		trade, reason := planTrade(ctx, h, plan.TotalTHB, opts)
+		if trade == nil {
+			plan.Skipped = append(plan.Skipped, &Skip{Asset: h.Asset, Reason: reason})
+			continue
+		}
+		if trade.Side == orders.Sell {
+			sells = append(sells, trade)
+		} else {
+			buys = append(buys, trade)
+		}
+	}
+
+	// Buys are funded by free THB plus what the sells bring in after fees.
+	cash := held[quote].Available
+	for _, t := range sells {
+		cash += t.ValueTHB - t.FeeTHB
+	}
+	sort.Slice(buys, func(i, j int) bool {
+		return buys[i].ValueTHB > buys[j].ValueTHB
+	})
+	funded := buys[:0]
+	for _, t := range buys {
+		if t.ValueTHB+t.FeeTHB > cash {
+			plan.Skipped = append(plan.Skipped, &Skip{Asset: baseAsset(t.Symbol), Reason: fmt.Sprintf("needs %.2f THB but only %.2f THB is free after sells", t.ValueTHB+t.FeeTHB, cash)})
+			continue
+		}
+		cash -= t.ValueTHB + t.FeeTHB
+		funded = append(funded, t)
+	}
+
+	plan.Trades = append(sells, funded...)
+	if plan.Trades == nil {
+		plan.Trades = []*Trade{}
+	}
+	for _, t := range plan.Trades {
+		plan.FeesTHB += t.FeeTHB
+	}
+	plan.TotalTHB = utils.Round(plan.TotalTHB, 2)
+	plan.FeesTHB = utils.Round(plan.FeesTHB, 2)
+
+	return plan, nil
+}
+
+func planTrade(ctx context.Context, h *Holding, total float64, opts Options) (*Trade, string) {
+	symbol := symbols.Normalize(h.Asset)
+	pair, symErr := symbols.Resolve(symbol)
+	if symErr != nil {
+		return nil, symErr.Message
+	}
+
+	diff := total*h.Target/100 - h.Amount*h.Price
+	side, amount := orders.Buy, pair.SnapAmount(diff/h.Price)
+	if diff < 0 {
+		side, amount = orders.Sell, pair.SnapAmount(math.Min(-diff/h.Price, h.Available))
+	}
+
+	value := amount * h.Price
+	if minTrade := math.Max(opts.MinTradeTHB, pair.MinOrderValue()); value < minTrade {
+		return nil, fmt.Sprintf("%s of %.2f THB is below the %.2f THB minimum trade", side, value, minTrade)
+	}
+	if err := pair.CheckOrder(side, h.Price, amount); err != nil {
+		return nil, err.Message
+	}
+
+	trade := &Trade{
+		Symbol:   symbol,
+		Side:     side,
+		Amount:   amount,
+		Price:    h.Price,
+		ValueTHB: utils.Round(value, 2),
+		EstPrice: h.Price,
+		FeeTHB:   utils.Round(value*opts.FeePercent/100, 2),
+		Status:   StatusPlanned,
+	}
+
+	depth, err := tracing.Exchange(ctx, "market.GetDepth", symbol, func() (*market.Depth, error) {
+		return market.GetDepth(symbol, 100)
+	})
+	if err != nil || len(depth.Bids) == 0 || len(depth.Asks) == 0 {
+		log.Warn().Err(err).Str("symbol", symbol).Msg("No order book for rebalance slippage estimate, using last price")
+		return trade, ""
+	}
+
+	mid := (depth.Bids[0][0] + depth.Asks[0][0]) / 2
+	fill := orders.WalkBook(depth.Asks, value, true)
+	if side == orders.Sell {
+		fill = orders.WalkBook(depth.Bids, amount, false)
+	}
+	if fill.Coin > 0 {
+		trade.EstPrice = utils.Round(fill.VWAP())
+		trade.SlippageBps = utils.Round(orders.SlippageBps(side, fill.VWAP(), mid), 2)
+		trade.FeeTHB = utils.Round(fill.THB*opts.FeePercent/100, 2)
+	}
+	if !fill.Filled {
+		trade.Thin, trade.Error = true, "order book too thin to fill the whole trade"
+	}
+	return trade, ""
+}
+
+// Execute sends the plan's trades as market orders, sells first. Trades the
+// order book cannot fill or whose estimated slippage exceeds MaxSlippageBps
+// are skipped, and so is every trade left once trading is halted.
+func Execute(ctx context.Context, plan *Plan, opts Options) error {
+	if killswitch.IsHalted() {
+		return utils.NewError(utils.CodeTradingHalted, "trading halted: %s", killswitch.Status().Reason)
+	}
+
+	exchange := orders.Live(plan.Account)
+	for _, t := range plan.Trades {
+		if killswitch.IsHalted() {
+			t.Status, t.Error = StatusSkipped, "trading halted: "+killswitch.Status().Reason
+			continue
+		}
+		if t.Thin {
+			t.Status = StatusSkipped
+			continue
+		}
+		if opts.MaxSlippageBps > 0 && t.SlippageBps > opts.MaxSlippageBps {
+			t.Status = StatusSkipped
+			t.Error = fmt.Sprintf("estimated slippage %.2f bps exceeds %.0f bps", t.SlippageBps, opts.MaxSlippageBps)
+			continue
+		}
+
+		o, err := exchange.Place(ctx, orders.Request{Symbol: t.Symbol, Side: t.Side, Type: orders.Market, Rate: t.Price, Amount: t.Amount})
+		if err != nil {
+			t.Status, t.Error = StatusFailed, err.Error()
+			// A failed sell can leave later buys unfunded; the exchange
+			// rejects those on its own, so keep going.
+			log.Error().Err(err).Str("account", plan.Account).Str("symbol", t.Symbol).Str("side", t.Side).Msg("Rebalance order failed")
+			continue
+		}
+		t.Status, t.OrderID = StatusPlaced, o.ID
+		log.Info().Str("account", plan.Account).Str("symbol", t.Symbol).Str("side", t.Side).Float64("amount", t.Amount).Str("id", o.ID).Msg("Rebalance order placed")
+	}
+	return nil
+}
+
+func (p *Plan) OrderIDs() []string {
+	ids := []string{}
+	for _, t := range p.Trades {
+		if t.OrderID != "" {
+			ids = append(ids, t.OrderID)
+		}
+	}
+	return ids
+}
+
+func baseAsset(symbol string) string {
+	base, _, _ := strings.Cut(symbol, "_")
+	return strings.ToUpper(base)
+}
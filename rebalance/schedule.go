@@ -0,0 +1,130 @@
+package rebalance
+
+import (
+	"context"
+	"fmt"
+	"gokub/accounts"
+	"gokub/notify"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	mu       sync.RWMutex
+	schedule *Schedule
+	lastRun  *Plan
+)
+
+// Schedule is the unattended rebalance configured through REBALANCE_*.
+type Schedule struct {
+	Account  string        `json:"account"`
+	Interval time.Duration `json:"-"`
+	Options  Options       `json:"options"`
+}
+
+// Init starts the scheduled rebalance when REBALANCE_TARGETS is set. A bad
+// configuration is logged and leaves the schedule off.
+func Init() {
+	raw := os.Getenv("REBALANCE_TARGETS")
+	if raw == "" {
+		return
+	}
+
+	targets, err := ParseTargets(raw)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid REBALANCE_TARGETS, scheduled rebalance disabled")
+		return
+	}
+
+	s := &Schedule{
+		Account:  strings.ToLower(os.Getenv("REBALANCE_ACCOUNT")),
+		Interval: 24 * time.Hour,
+		Options: Options{
+			Targets:        targets,
+			DriftPercent:   5,
+			FeePercent:     0.25,
+			MaxSlippageBps: 100,
+		},
+	}
+	if s.Account == "" {
+		s.Account = accounts.Default()
+	}
+	if d, err := time.ParseDuration(os.Getenv("REBALANCE_INTERVAL")); err == nil && d > 0 {
+		s.Interval = d
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("REBALANCE_DRIFT_PERCENT"), 64); err == nil && v >= 0 {
+		s.Options.DriftPercent = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("REBALANCE_MIN_TRADE_THB"), 64); err == nil && v >= 0 {
+		s.Options.MinTradeTHB = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("REBALANCE_MAX_SLIPPAGE_BPS"), 64); err == nil && v >= 0 {
+		s.Options.MaxSlippageBps = v
+	}
+
+	mu.Lock()
+	schedule = s
+	mu.Unlock()
+
+	log.Info().Str("account", s.Account).Str("targets", raw).Dur("interval", s.Interval).Float64("drift_percent", s.Options.DriftPercent).Msg("Scheduled rebalance enabled")
+
+	go run(s)
+}
+
+// Scheduled returns the configured schedule and its last run, if any.
+func Scheduled() (*Schedule, *Plan) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return schedule, lastRun
+}
+
+func run(s *Schedule) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		plan, err := RunScheduled(context.Background(), s)
+		if err != nil {
+			log.Error().Err(err).Str("account", s.Account).Msg("Scheduled rebalance failed")
+			continue
+		}
+		log.Info().Str("account", s.Account).Int("trades", len(plan.Trades)).Int("orders", len(plan.OrderIDs())).Msg("Scheduled rebalance finished")
+	}
+}
+
+// RunScheduled previews and executes one rebalance with the schedule's
+// settings and reports the placed orders through notify.
+func RunScheduled(ctx context.Context, s *Schedule) (*Plan, error) {
+	plan, err := Preview(ctx, s.Account, s.Options)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Trades) > 0 {
+		if err := Execute(ctx, plan, s.Options); err != nil {
+			return nil, err
+		}
+	}
+
+	mu.Lock()
+	lastRun = plan
+	mu.Unlock()
+
+	for _, t := range plan.Trades {
+		if t.Status != StatusPlaced {
+			continue
+		}
+		notify.Send(notify.Event{
+			Kind:    notify.KindTrade,
+			Title:   fmt.Sprintf("rebalance %s %s", strings.ToUpper(t.Side), strings.ToUpper(t.Symbol)),
+			Message: fmt.Sprintf("%s %.8g %s for about %.2f THB to bring it back to target", t.Side, t.Amount, strings.ToUpper(baseAsset(t.Symbol)), t.ValueTHB),
+			Symbol:  t.Symbol,
+			Fields:  map[string]any{"account": plan.Account, "order_id": t.OrderID, "scheduled": true},
+		})
+	}
+	return plan, nil
+}
@@ -0,0 +1,45 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gokub/alerts"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+func NewAlertsResource() server.ServerResource {
+	return server.ServerResource{
+		Resource: mcp.NewResource(
+			alerts.ResourceURI,
+			"Price Alerts",
+			mcp.WithResourceDescription("Active and fired price alerts with the most recent triggers. Subscribe to be notified when an alert fires"),
+			mcp.WithMIMEType("application/json"),
+		),
+		Handler: AlertsResourceHandler,
+	}
+}
+
+func AlertsResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	log.Debug().Str("uri", request.Params.URI).Msg("read_resource")
+
+	jsonData, err := json.Marshal(map[string]any{
+		"alerts":          alerts.List(alerts.Owner(ctx), ""),
+		"recent_triggers": alerts.Recent(alerts.Owner(ctx)),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("json marshal failed")
+		return nil, fmt.Errorf("failed to marshal alerts: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(jsonData),
+		},
+	}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"gokub/symbols"
 
 	"github.com/dvgamerr-app/go-bitkub/market"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -26,10 +27,13 @@ func NewSymbolsResource() server.ServerResource {
 func SymbolsResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
 	log.Debug().Str("uri", request.Params.URI).Msg("read_resource")
 
-	result, err := market.GetSymbols()
-	if err != nil {
-		log.Error().Err(err).Msg("GetSymbols failed")
-		return nil, fmt.Errorf("failed to get symbols: %w", err)
+	result := symbols.List()
+	if len(result) == 0 {
+		if err := symbols.Refresh(); err != nil {
+			log.Error().Err(err).Msg("GetSymbols failed")
+			return nil, fmt.Errorf("failed to get symbols: %w", err)
+		}
+		result = symbols.List()
 	}
 
 	jsonData, err := json.Marshal(result)
@@ -69,6 +73,8 @@ func TickerResourceHandler(ctx context.Context, request mcp.ReadResourceRequest)
 		return nil, fmt.Errorf("invalid URI format: %w", err)
 	}
 
+	symbol = symbols.Normalize(symbol)
+
 	result, err := market.GetTicker(symbol)
 	if err != nil {
 		log.Error().Err(err).Str("symbol", symbol).Msg("GetTicker failed")
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"gokub/alerts"
+	"gokub/auth"
+	"gokub/killswitch"
+	"gokub/metrics"
+	"gokub/tracing"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+func parseTransports(mode string) map[string]bool {
+	transports := map[string]bool{}
+	for _, t := range strings.Split(strings.ToLower(mode), ",") {
+		t = strings.TrimSpace(t)
+		switch t {
+		case "stdio", "sse", "http":
+			transports[t] = true
+		case "":
+		default:
+			log.Fatal().Str("transport", t).Msg("Unknown transport, use stdio, sse or http")
+		}
+	}
+
+	if len(transports) == 0 {
+		transports["stdio"] = true
+	}
+
+	return transports
+}
+
+func sessionHooks() *server.Hooks {
+	hooks := &server.Hooks{}
+
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+		metrics.SessionStarted()
+		log.Info().Str("session", session.SessionID()).Msg("Client session started")
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		metrics.SessionEnded()
+		log.Info().Str("session", session.SessionID()).Msg("Client session ended")
+	})
+	alerts.TrackSessions(hooks)
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, message *mcp.InitializeRequest, result *mcp.InitializeResult) {
+		log.Info().
+			Str("client", message.Params.ClientInfo.Name).
+			Str("client_version", message.Params.ClientInfo.Version).
+			Str("protocol", message.Params.ProtocolVersion).
+			Msg("Client initialized")
+	})
+
+	return hooks
+}
+
+func serveHTTPTransports(s *server.MCPServer, transports map[string]bool) error {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "3000"
+	}
+
+	if !auth.Enabled() {
+		if os.Getenv("AUTH_DISABLED") != "true" {
+			return fmt.Errorf("HTTP transport requires AUTH_TOKENS_FILE or OAUTH_INTROSPECTION_URL (set AUTH_DISABLED=true to run without authentication)")
+		}
+		log.Warn().Msg("HTTP authentication disabled, anyone who can reach this port can use the Bitkub account")
+	}
+
+	http.DefaultTransport = metrics.InstrumentTransport(http.DefaultTransport)
+
+	protect := func(h http.Handler) http.Handler {
+		if !auth.Enabled() {
+			return tracing.HTTPMiddleware(h)
+		}
+		return tracing.HTTPMiddleware(auth.Middleware(h))
+	}
+
+	mux := http.NewServeMux()
+	endpoints := []string{}
+
+	if transports["sse"] {
+		sseServer := server.NewSSEServer(s,
+			server.WithSSEEndpoint("/sse"),
+			server.WithMessageEndpoint("/msg"),
+		)
+
+		mux.Handle("/sse", protect(sseServer))
+		mux.Handle("/msg", protect(sseServer))
+		endpoints = append(endpoints, "SSE: /sse, Message: /msg")
+	}
+
+	if transports["http"] {
+		httpServer := server.NewStreamableHTTPServer(s,
+			server.WithEndpointPath("/mcp"),
+			server.WithHeartbeatInterval(30*time.Second),
+		)
+
+		mux.Handle("/mcp", protect(httpServer))
+		endpoints = append(endpoints, "Streamable HTTP: /mcp")
+	}
+
+	mux.Handle("/admin/", killswitch.AdminHandler(os.Getenv("ADMIN_TOKEN")))
+	mux.Handle("/.well-known/oauth-protected-resource", auth.ProtectedResourceHandler())
+	mux.Handle("/.well-known/oauth-protected-resource/", auth.ProtectedResourceHandler())
+	endpoints = append(endpoints, "Admin: /admin/halt, /admin/resume")
+
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", healthzHandler())
+	mux.Handle("/readyz", readyzHandler())
+	endpoints = append(endpoints, "Health: /healthz, /readyz, Metrics: /metrics")
+
+	log.Info().Str("port", port).Msgf("Server listening on http://localhost:%s", port)
+	log.Info().Msgf("Endpoint %s", strings.Join(endpoints, " | "))
+
+	return http.ListenAndServe(":"+port, mux)
+}
+
+func printTokenHash() {
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		log.Fatal().Msg("No token provided on stdin")
+	}
+
+	token := strings.TrimSpace(scanner.Text())
+	if token == "" {
+		log.Fatal().Msg("No token provided on stdin")
+	}
+
+	fmt.Println(auth.HashToken(token))
+}
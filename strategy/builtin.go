@@ -0,0 +1,47 @@
+package strategy
+
+// The setups behind detect_pullback_signal and detect_breakout_signal. The
+// tools pass their arguments in as params.
+var builtinSources = []string{`
+name: pullback
+description: Price pulls back to the EMA with RSI in the bounce zone and a reversal candle
+signal: PULLBACK_BUY
+params:
+  ema_period: 20
+  rsi_period: 14
+  rsi_min: 40
+  rsi_max: 50
+when:
+  - near_ema: abs(pct(close, ema(close, ema_period))) <= 2
+  - rsi_bounce: rsi(close, rsi_period) between rsi_min and rsi_max
+  - reversal_bar: close > high - (high - low) * 0.3 and close[1] < high[1] - (high[1] - low[1]) * 0.5
+entry: high * 1.001
+stop: lowest(low, 10) * 0.999
+target: entry + 2 * (entry - stop)
+`, `
+name: breakout
+description: Close above the prior high of the lookback with a volume surge
+signal: BREAKOUT_BUY
+params:
+  lookback: 20
+  volume_threshold: 1.5
+  atr_multiplier: 1.5
+when:
+  - new_high: close > highest(high, lookback)[1]
+  - volume_surge: volume / sma(volume, lookback)[1] >= volume_threshold
+entry: close * 1.001
+stop: close - atr(14) * atr_multiplier
+target: entry + 2 * (entry - stop)
+`}
+
+func builtins() map[string]*Definition {
+	defs := map[string]*Definition{}
+	for _, src := range builtinSources {
+		d, err := Parse([]byte(src), "builtin")
+		if err != nil {
+			panic(err)
+		}
+		defs[d.Name] = d
+	}
+	return defs
+}
@@ -0,0 +1,574 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expressions are evaluated over the whole candle history at once: every
+// node yields a Series aligned with the bars, booleans as 1 and 0, and the
+// rule is read at the last bar. Bars before an indicator has warmed up are
+// NaN.
+
+type node interface {
+	eval(e *env) (Series, error)
+	String() string
+}
+
+type env struct {
+	bars   Bars
+	params map[string]float64
+	vars   map[string]float64
+}
+
+func (e *env) n() int {
+	return len(e.bars.Close)
+}
+
+type number float64
+
+func (c number) eval(e *env) (Series, error) {
+	return constant(e.n(), float64(c)), nil
+}
+
+func (c number) String() string {
+	return strconv.FormatFloat(float64(c), 'f', -1, 64)
+}
+
+type ident string
+
+func (id ident) eval(e *env) (Series, error) {
+	name := string(id)
+	if s := e.bars.series(name); s != nil {
+		return s, nil
+	}
+	if v, ok := e.vars[name]; ok {
+		return constant(e.n(), v), nil
+	}
+	if v, ok := e.params[name]; ok {
+		return constant(e.n(), v), nil
+	}
+	return nil, fmt.Errorf("unknown name %q", name)
+}
+
+func (id ident) String() string {
+	return string(id)
+}
+
+type unary struct {
+	op string
+	x  node
+}
+
+func (u unary) eval(e *env) (Series, error) {
+	x, err := u.x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	out := make(Series, len(x))
+	for i, v := range x {
+		switch {
+		case math.IsNaN(v):
+			out[i] = v
+		case u.op == "-":
+			out[i] = -v
+		default:
+			out[i] = boolean(v == 0)
+		}
+	}
+	return out, nil
+}
+
+func (u unary) String() string {
+	if u.op == "not" {
+		return "not " + u.x.String()
+	}
+	return u.op + u.x.String()
+}
+
+type binary struct {
+	op   string
+	l, r node
+}
+
+func (b binary) eval(e *env) (Series, error) {
+	l, err := b.l.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	r, err := b.r.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	out := make(Series, len(l))
+	for i := range l {
+		x, y := l[i], r[i]
+		if math.IsNaN(x) || math.IsNaN(y) {
+			out[i] = math.NaN()
+			continue
+		}
+		switch b.op {
+		case "+":
+			out[i] = x + y
+		case "-":
+			out[i] = x - y
+		case "*":
+			out[i] = x * y
+		case "/":
+			// A zero divisor, e.g. an average volume of 0, yields 0
+			// rather than failing the whole rule.
+			if y != 0 {
+				out[i] = x / y
+			}
+		case ">":
+			out[i] = boolean(x > y)
+		case ">=":
+			out[i] = boolean(x >= y)
+		case "<":
+			out[i] = boolean(x < y)
+		case "<=":
+			out[i] = boolean(x <= y)
+		case "==":
+			out[i] = boolean(x == y)
+		case "!=":
+			out[i] = boolean(x != y)
+		case "and":
+			out[i] = boolean(x != 0 && y != 0)
+		case "or":
+			out[i] = boolean(x != 0 || y != 0)
+		}
+	}
+	return out, nil
+}
+
+func (b binary) String() string {
+	return "(" + b.l.String() + " " + b.op + " " + b.r.String() + ")"
+}
+
+type between struct {
+	x, lo, hi node
+}
+
+func (b between) eval(e *env) (Series, error) {
+	x, err := b.x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	lo, err := b.lo.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := b.hi.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	out := make(Series, len(x))
+	for i := range x {
+		if math.IsNaN(x[i]) || math.IsNaN(lo[i]) || math.IsNaN(hi[i]) {
+			out[i] = math.NaN()
+			continue
+		}
+		out[i] = boolean(x[i] >= lo[i] && x[i] <= hi[i])
+	}
+	return out, nil
+}
+
+func (b between) String() string {
+	return b.x.String() + " between " + b.lo.String() + " and " + b.hi.String()
+}
+
+// index is x[k], the value k bars ago.
+type index struct {
+	x node
+	k int
+}
+
+func (ix index) eval(e *env) (Series, error) {
+	x, err := ix.x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	return shift(x, ix.k), nil
+}
+
+func (ix index) String() string {
+	return fmt.Sprintf("%s[%d]", ix.x, ix.k)
+}
+
+type call struct {
+	fn   *function
+	args []node
+}
+
+func (c call) eval(e *env) (Series, error) {
+	return c.fn.eval(e, c.args)
+}
+
+func (c call) String() string {
+	args := make([]string, len(c.args))
+	for i, a := range c.args {
+		args[i] = a.String()
+	}
+	return c.fn.name + "(" + strings.Join(args, ", ") + ")"
+}
+
+type token struct {
+	kind string // num, ident, op, end
+	text string
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{"num", src[start:i], start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(src) && (unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])) || src[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{"ident", strings.ToLower(src[start:i]), start})
+		default:
+			if i+1 < len(src) {
+				if two := src[i : i+2]; two == ">=" || two == "<=" || two == "==" || two == "!=" || two == "&&" || two == "||" {
+					op := two
+					switch two {
+					case "&&":
+						op = "and"
+					case "||":
+						op = "or"
+					}
+					tokens = append(tokens, token{"op", op, i})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("+-*/()[],<>!", c) {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i+1)
+			}
+			op := string(c)
+			if op == "!" {
+				op = "not"
+			}
+			tokens = append(tokens, token{"op", op, i})
+			i++
+		}
+	}
+	return append(tokens, token{"end", "", len(src)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse compiles one expression. Grammar, loosest first:
+//
+//	or:      and ("or" and)*
+//	and:     not ("and" not)*
+//	not:     "not" not | compare
+//	compare: sum [(> >= < <= == !=) sum | "between" sum "and" sum]
+//	sum:     product ((+ -) product)*
+//	product: unary ((* /) unary)*
+//	unary:   "-" unary | postfix
+//	postfix: primary ("[" integer "]")*
+//	primary: number | name | name "(" args ")" | "(" or ")"
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != "end" {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos+1)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != "end" {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or
+// keywords.
+func (p *parser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != "op" && t.kind != "ident" {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); ok {
+		return nil
+	}
+	t := p.peek()
+	if t.kind == "end" {
+		return fmt.Errorf("expected %q at end of expression", text)
+	}
+	return fmt.Errorf("expected %q at %d, got %q", text, t.pos+1, t.text)
+}
+
+func (p *parser) or() (node, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or"); !ok {
+			return l, nil
+		}
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = binary{"or", l, r}
+	}
+}
+
+func (p *parser) and() (node, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and"); !ok {
+			return l, nil
+		}
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l = binary{"and", l, r}
+	}
+}
+
+func (p *parser) not() (node, error) {
+	if _, ok := p.accept("not"); ok {
+		x, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return unary{"not", x}, nil
+	}
+	return p.compare()
+}
+
+func (p *parser) compare() (node, error) {
+	l, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept(">", ">=", "<", "<=", "==", "!="); ok {
+		r, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		return binary{op, l, r}, nil
+	}
+	if _, ok := p.accept("between"); ok {
+		lo, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("and"); err != nil {
+			return nil, err
+		}
+		hi, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		return between{l, lo, hi}, nil
+	}
+	return l, nil
+}
+
+func (p *parser) sum() (node, error) {
+	l, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		l = binary{op, l, r}
+	}
+}
+
+func (p *parser) product() (node, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = binary{op, l, r}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{"-", x}, nil
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (node, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("["); !ok {
+			return x, nil
+		}
+		t := p.next()
+		k, err := strconv.Atoi(t.text)
+		if t.kind != "num" || err != nil || k < 0 {
+			return nil, fmt.Errorf("bar offset at %d must be a whole number, e.g. close[1]", t.pos+1)
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		x = index{x, k}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case "num":
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos+1)
+		}
+		return number(v), nil
+	case "ident":
+		if _, ok := p.accept("("); !ok {
+			switch t.text {
+			case "true":
+				return number(1), nil
+			case "false":
+				return number(0), nil
+			}
+			return ident(t.text), nil
+		}
+		fn, ok := functions[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q at %d", t.text, t.pos+1)
+		}
+		var args []node
+		if _, ok := p.accept(")"); !ok {
+			for {
+				arg, err := p.or()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+				if _, ok := p.accept(","); ok {
+					continue
+				}
+				if err := p.expect(")"); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+		if err := fn.check(args); err != nil {
+			return nil, fmt.Errorf("%s at %d: %w", t.text, t.pos+1, err)
+		}
+		return call{fn, args}, nil
+	case "op":
+		if t.text == "(" {
+			x, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos+1)
+	}
+	return nil, fmt.Errorf("expression ends too early")
+}
+
+// names lists the bare names an expression reads, so unknown ones can be
+// reported when the definition is loaded rather than when it runs.
+func names(n node, out map[string]bool) {
+	switch x := n.(type) {
+	case ident:
+		out[string(x)] = true
+	case unary:
+		names(x.x, out)
+	case binary:
+		names(x.l, out)
+		names(x.r, out)
+	case between:
+		names(x.x, out)
+		names(x.lo, out)
+		names(x.hi, out)
+	case index:
+		names(x.x, out)
+	case call:
+		for _, a := range x.args {
+			names(a, out)
+		}
+	}
+}
+
+func boolean(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,348 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+type Series []float64
+
+// Bars are candles as columns, oldest first. Open is optional; a rule that
+// reads it without it fails as insufficient data.
+type Bars struct {
+	Open   Series
+	High   Series
+	Low    Series
+	Close  Series
+	Volume Series
+}
+
+// Fields lists the candle columns rules can read.
+var Fields = []string{"open", "high", "low", "close", "volume"}
+
+func (b Bars) series(name string) Series {
+	switch name {
+	case "open":
+		if len(b.Open) != len(b.Close) {
+			return constant(len(b.Close), math.NaN())
+		}
+		return b.Open
+	case "high":
+		return b.High
+	case "low":
+		return b.Low
+	case "close":
+		return b.Close
+	case "volume":
+		if len(b.Volume) != len(b.Close) {
+			return constant(len(b.Close), 0)
+		}
+		return b.Volume
+	}
+	return nil
+}
+
+func constant(n int, v float64) Series {
+	s := make(Series, n)
+	for i := range s {
+		s[i] = v
+	}
+	return s
+}
+
+func nans(n int) Series {
+	return constant(n, math.NaN())
+}
+
+func shift(x Series, k int) Series {
+	out := nans(len(x))
+	for i := k; i < len(x); i++ {
+		out[i] = x[i-k]
+	}
+	return out
+}
+
+// warm returns where a series starts having values, skipping the NaNs an
+// inner indicator leaves at the front.
+func warm(x Series) int {
+	for i, v := range x {
+		if !math.IsNaN(v) {
+			return i
+		}
+	}
+	return len(x)
+}
+
+type function struct {
+	name  string
+	usage string
+	min   int
+	max   int
+	eval  func(e *env, args []node) (Series, error)
+}
+
+func (f *function) check(args []node) error {
+	if len(args) < f.min || len(args) > f.max {
+		return fmt.Errorf("expects %s", f.usage)
+	}
+	return nil
+}
+
+var functions = func() map[string]*function {
+	m := map[string]*function{}
+	for _, f := range []*function{
+		{name: "sma", usage: "sma(series, period) or sma(period) over close", min: 1, max: 2, eval: withPeriod(sma)},
+		{name: "ema", usage: "ema(series, period) or ema(period) over close", min: 1, max: 2, eval: withPeriod(ema)},
+		{name: "rsi", usage: "rsi(series, period) or rsi(period) over close", min: 1, max: 2, eval: withPeriod(rsi)},
+		{name: "highest", usage: "highest(series, period)", min: 2, max: 2, eval: withPeriod(highest)},
+		{name: "lowest", usage: "lowest(series, period)", min: 2, max: 2, eval: withPeriod(lowest)},
+		{name: "atr", usage: "atr(period)", min: 1, max: 1, eval: atrFunc},
+		{name: "abs", usage: "abs(x)", min: 1, max: 1, eval: pointwise(func(v []float64) float64 { return math.Abs(v[0]) })},
+		{name: "min", usage: "min(a, b)", min: 2, max: 2, eval: pointwise(func(v []float64) float64 { return math.Min(v[0], v[1]) })},
+		{name: "max", usage: "max(a, b)", min: 2, max: 2, eval: pointwise(func(v []float64) float64 { return math.Max(v[0], v[1]) })},
+		{name: "pct", usage: "pct(a, b), the percent a is above b", min: 2, max: 2, eval: pointwise(func(v []float64) float64 {
+			if v[1] == 0 {
+				return 0
+			}
+			return (v[0] - v[1]) / v[1] * 100
+		})},
+		{name: "crosses_above", usage: "crosses_above(a, b)", min: 2, max: 2, eval: cross(true)},
+		{name: "crosses_below", usage: "crosses_below(a, b)", min: 2, max: 2, eval: cross(false)},
+	} {
+		m[f.name] = f
+	}
+	return m
+}()
+
+// FunctionUsage lists the functions rules can call.
+func FunctionUsage() []string {
+	list := make([]string, 0, len(functions))
+	for _, f := range functions {
+		list = append(list, f.usage)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// withPeriod adapts an indicator over (series, period), defaulting the
+// series to close when only a period is given.
+func withPeriod(calc func(x Series, period int) Series) func(e *env, args []node) (Series, error) {
+	return func(e *env, args []node) (Series, error) {
+		x := e.bars.Close
+		if len(args) == 2 {
+			var err error
+			if x, err = args[0].eval(e); err != nil {
+				return nil, err
+			}
+		}
+		period, err := periodArg(e, args[len(args)-1])
+		if err != nil {
+			return nil, err
+		}
+		return calc(x, period), nil
+	}
+}
+
+// periodArg reads a lookback, which must be the same whole number on every
+// bar: a literal or a param.
+func periodArg(e *env, n node) (int, error) {
+	s, err := n.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	if len(s) == 0 {
+		return 1, nil
+	}
+	v := s[len(s)-1]
+	if v != s[0] || v < 1 || v != math.Trunc(v) || v > 10000 {
+		return 0, fmt.Errorf("period %s must be a whole number between 1 and 10000", strings.Trim(n.String(), "()"))
+	}
+	return int(v), nil
+}
+
+func pointwise(f func(v []float64) float64) func(e *env, args []node) (Series, error) {
+	return func(e *env, args []node) (Series, error) {
+		cols := make([]Series, len(args))
+		for i, a := range args {
+			s, err := a.eval(e)
+			if err != nil {
+				return nil, err
+			}
+			cols[i] = s
+		}
+		out := make(Series, e.n())
+		v := make([]float64, len(cols))
+	bars:
+		for i := range out {
+			for j, c := range cols {
+				if math.IsNaN(c[i]) {
+					out[i] = math.NaN()
+					continue bars
+				}
+				v[j] = c[i]
+			}
+			out[i] = f(v)
+		}
+		return out, nil
+	}
+}
+
+func cross(above bool) func(e *env, args []node) (Series, error) {
+	return func(e *env, args []node) (Series, error) {
+		a, err := args[0].eval(e)
+		if err != nil {
+			return nil, err
+		}
+		b, err := args[1].eval(e)
+		if err != nil {
+			return nil, err
+		}
+		out := nans(len(a))
+		for i := 1; i < len(a); i++ {
+			if math.IsNaN(a[i]) || math.IsNaN(b[i]) || math.IsNaN(a[i-1]) || math.IsNaN(b[i-1]) {
+				continue
+			}
+			if above {
+				out[i] = boolean(a[i] > b[i] && a[i-1] <= b[i-1])
+			} else {
+				out[i] = boolean(a[i] < b[i] && a[i-1] >= b[i-1])
+			}
+		}
+		return out, nil
+	}
+}
+
+func sma(x Series, period int) Series {
+	out := nans(len(x))
+	start := warm(x)
+	sum := 0.0
+	for i := start; i < len(x); i++ {
+		sum += x[i]
+		if i-start >= period {
+			sum -= x[i-period]
+		}
+		if i-start >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+func highest(x Series, period int) Series {
+	return extreme(x, period, math.Max)
+}
+
+func lowest(x Series, period int) Series {
+	return extreme(x, period, math.Min)
+}
+
+func extreme(x Series, period int, pick func(a, b float64) float64) Series {
+	out := nans(len(x))
+	for i := warm(x) + period - 1; i < len(x); i++ {
+		v := x[i]
+		for j := i - period + 1; j < i; j++ {
+			v = pick(v, x[j])
+		}
+		out[i] = v
+	}
+	return out
+}
+
+// The indicators below step through the same calculations as the
+// calculate_ema, calculate_rsi and calculate_atr tools, so a rule and the
+// tool agree on the last bar.
+
+func ema(x Series, period int) Series {
+	out := nans(len(x))
+	start := warm(x)
+	if len(x)-start < period {
+		return out
+	}
+	multiplier := 2.0 / float64(period+1)
+	sum := 0.0
+	for i := start; i < start+period; i++ {
+		sum += x[i]
+	}
+	out[start+period-1] = sum / float64(period)
+	for i := start + period; i < len(x); i++ {
+		out[i] = (x[i]-out[i-1])*multiplier + out[i-1]
+	}
+	return out
+}
+
+func rsi(x Series, period int) Series {
+	out := nans(len(x))
+	start := warm(x)
+	if len(x)-start <= period {
+		return out
+	}
+
+	avgGain, avgLoss := 0.0, 0.0
+	for i := start + 1; i <= start+period; i++ {
+		gain, loss := change(x[i] - x[i-1])
+		avgGain += gain
+		avgLoss += loss
+	}
+	avgGain /= float64(period)
+	avgLoss /= float64(period)
+	out[start+period] = rsiValue(avgGain, avgLoss)
+
+	for i := start + period + 1; i < len(x); i++ {
+		gain, loss := change(x[i] - x[i-1])
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		out[i] = rsiValue(avgGain, avgLoss)
+	}
+	return out
+}
+
+func change(d float64) (gain, loss float64) {
+	if d > 0 {
+		return d, 0
+	}
+	return 0, -d
+}
+
+func rsiValue(avgGain, avgLoss float64) float64 {
+	if avgLoss == 0 {
+		return 100
+	}
+	return 100 - (100 / (1 + avgGain/avgLoss))
+}
+
+func atrFunc(e *env, args []node) (Series, error) {
+	period, err := periodArg(e, args[0])
+	if err != nil {
+		return nil, err
+	}
+	return atr(e.bars, period), nil
+}
+
+func atr(b Bars, period int) Series {
+	n := len(b.Close)
+	out := nans(n)
+	if n <= period {
+		return out
+	}
+
+	tr := make(Series, n)
+	for i := range tr {
+		tr[i] = b.High[i] - b.Low[i]
+		if i > 0 {
+			tr[i] = max(tr[i], math.Abs(b.High[i]-b.Close[i-1]), math.Abs(b.Low[i]-b.Close[i-1]))
+		}
+	}
+
+	sum := 0.0
+	for i := 1; i <= period; i++ {
+		sum += tr[i]
+	}
+	out[period] = sum / float64(period)
+	multiplier := 1.0 / float64(period)
+	for i := period + 1; i < n; i++ {
+		out[i] = (out[i-1] * (1 - multiplier)) + (tr[i] * multiplier)
+	}
+	return out
+}
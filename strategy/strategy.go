@@ -0,0 +1,420 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+const NoSignal = "NO_SIGNAL"
+
+// ErrInsufficientData means a rule or price needs more candles than were
+// given.
+var ErrInsufficientData = errors.New("insufficient data")
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Rule is one condition of a strategy. Named rules can be looked up in the
+// result, e.g. the reversal bar of the pullback setup.
+type Rule struct {
+	Name string `json:"name,omitempty"`
+	Expr string `json:"rule"`
+	node node
+}
+
+// Rules accepts a single expression, or a list whose items are expressions
+// or one-key maps of name to expression.
+type Rules []Rule
+
+func (r *Rules) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*r = Rules{{Expr: value.Value}}
+		return nil
+	}
+	if value.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: when must be an expression or a list of them", value.Line)
+	}
+	for _, item := range value.Content {
+		switch {
+		case item.Kind == yaml.ScalarNode:
+			*r = append(*r, Rule{Expr: item.Value})
+		case item.Kind == yaml.MappingNode && len(item.Content) == 2:
+			*r = append(*r, Rule{Name: item.Content[0].Value, Expr: item.Content[1].Value})
+		default:
+			return fmt.Errorf("line %d: each rule must be an expression or name: expression", item.Line)
+		}
+	}
+	return nil
+}
+
+// Definition is a strategy as written in YAML or JSON. Entry defaults to the
+// last close; stop may refer to entry, and target to entry and stop.
+type Definition struct {
+	Name        string             `json:"name" yaml:"name"`
+	Description string             `json:"description,omitempty" yaml:"description"`
+	Signal      string             `json:"signal" yaml:"signal"`
+	Params      map[string]float64 `json:"params,omitempty" yaml:"params"`
+	When        Rules              `json:"when" yaml:"when"`
+	Entry       string             `json:"entry,omitempty" yaml:"entry"`
+	Stop        string             `json:"stop,omitempty" yaml:"stop"`
+	Target      string             `json:"target,omitempty" yaml:"target"`
+	// Source is builtin, inline or the file the definition was loaded from.
+	Source string `json:"source" yaml:"-"`
+
+	entry, stop, target node
+	uses                map[string]bool
+}
+
+// Parse reads and checks a definition. JSON is accepted as the YAML subset
+// it is.
+func Parse(data []byte, source string) (*Definition, error) {
+	var d Definition
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&d); err != nil {
+		return nil, fmt.Errorf("decode strategy: %w", err)
+	}
+	d.Source = source
+	if err := d.compile(); err != nil {
+		if d.Name != "" {
+			return nil, fmt.Errorf("strategy %s: %w", d.Name, err)
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (d *Definition) compile() error {
+	d.Name = strings.ToLower(strings.TrimSpace(d.Name))
+	if !namePattern.MatchString(d.Name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits and underscores", d.Name)
+	}
+	if d.Signal == "" {
+		d.Signal = strings.ToUpper(d.Name) + "_BUY"
+	}
+	d.Signal = strings.ToUpper(d.Signal)
+	if len(d.When) == 0 {
+		return errors.New("when needs at least one rule")
+	}
+
+	reserved := map[string]bool{"entry": true, "stop": true, "and": true, "or": true, "not": true, "between": true, "true": true, "false": true}
+	for _, f := range Fields {
+		reserved[f] = true
+	}
+	for name := range d.Params {
+		if !namePattern.MatchString(name) || reserved[name] || functions[name] != nil {
+			return fmt.Errorf("param %q is not a usable name", name)
+		}
+	}
+
+	known := func(extra ...string) map[string]bool {
+		m := map[string]bool{}
+		for _, f := range Fields {
+			m[f] = true
+		}
+		for name := range d.Params {
+			m[name] = true
+		}
+		for _, e := range extra {
+			m[e] = true
+		}
+		return m
+	}
+	compile := func(label, src string, allowed map[string]bool) (node, error) {
+		n, err := parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %w", label, src, err)
+		}
+		used := map[string]bool{}
+		names(n, used)
+		for name := range used {
+			if !allowed[name] {
+				return nil, fmt.Errorf("%s %q: unknown name %q", label, src, name)
+			}
+		}
+		return n, nil
+	}
+
+	d.uses = map[string]bool{}
+	seen := map[string]bool{}
+	if len(d.When) == 1 && d.When[0].Name == "" {
+		// A single "a and b and c" is checked as separate rules so the result
+		// shows which part failed.
+		n, err := compile("rule", d.When[0].Expr, known())
+		if err != nil {
+			return err
+		}
+		d.When = d.When[:0]
+		for _, part := range conjuncts(n) {
+			d.When = append(d.When, Rule{Expr: strings.TrimSuffix(strings.TrimPrefix(part.String(), "("), ")")})
+		}
+	}
+	for i := range d.When {
+		r := &d.When[i]
+		if r.Name != "" {
+			if seen[r.Name] {
+				return fmt.Errorf("rule name %q is used twice", r.Name)
+			}
+			seen[r.Name] = true
+		}
+		n, err := compile("rule", r.Expr, known())
+		if err != nil {
+			return err
+		}
+		r.node = n
+		names(n, d.uses)
+	}
+
+	var err error
+	if d.Entry != "" {
+		if d.entry, err = compile("entry", d.Entry, known()); err != nil {
+			return err
+		}
+		names(d.entry, d.uses)
+	}
+	if d.Stop != "" {
+		if d.stop, err = compile("stop", d.Stop, known("entry")); err != nil {
+			return err
+		}
+		names(d.stop, d.uses)
+	}
+	if d.Target != "" {
+		if d.target, err = compile("target", d.Target, known("entry", "stop")); err != nil {
+			return err
+		}
+		names(d.target, d.uses)
+	}
+	return nil
+}
+
+func conjuncts(n node) []node {
+	if b, ok := n.(binary); ok && b.op == "and" {
+		return append(conjuncts(b.l), conjuncts(b.r)...)
+	}
+	return []node{n}
+}
+
+// Known keeps the params this definition declares, so tools can pass their
+// own settings to a definition a user may have replaced.
+func (d *Definition) Known(params map[string]float64) map[string]float64 {
+	out := map[string]float64{}
+	for name, v := range params {
+		if _, ok := d.Params[name]; ok {
+			out[name] = v
+		}
+	}
+	return out
+}
+
+type RuleResult struct {
+	Name   string `json:"name,omitempty"`
+	Rule   string `json:"rule"`
+	Passed bool   `json:"passed"`
+	// Value is the left side of a comparison on the last bar.
+	Value *float64 `json:"value,omitempty"`
+}
+
+type Result struct {
+	Strategy string             `json:"strategy"`
+	Signal   string             `json:"signal"`
+	Matched  bool               `json:"matched"`
+	Rules    []RuleResult       `json:"rules"`
+	Entry    float64            `json:"entry"`
+	Stop     float64            `json:"stop,omitempty"`
+	Target   float64            `json:"target,omitempty"`
+	Params   map[string]float64 `json:"params"`
+}
+
+// Passed reports whether the named rule held on the last bar.
+func (r *Result) Passed(name string) bool {
+	for _, rule := range r.Rules {
+		if rule.Name == name {
+			return rule.Passed
+		}
+	}
+	return false
+}
+
+// Evaluate runs the definition on the last bar. params override the
+// definition's defaults and must all be declared.
+func (d *Definition) Evaluate(b Bars, params map[string]float64) (*Result, error) {
+	n := len(b.Close)
+	if n == 0 {
+		return nil, fmt.Errorf("%w: no candles", ErrInsufficientData)
+	}
+	if len(b.High) != n || len(b.Low) != n {
+		return nil, errors.New("high, low and close must have the same length")
+	}
+	if d.uses["open"] && len(b.Open) != n {
+		return nil, fmt.Errorf("%w: strategy %s reads open but the candles have no open prices", ErrInsufficientData, d.Name)
+	}
+
+	e := &env{bars: b, params: map[string]float64{}, vars: map[string]float64{}}
+	for name, v := range d.Params {
+		e.params[name] = v
+	}
+	for name, v := range params {
+		if _, ok := d.Params[name]; !ok {
+			return nil, fmt.Errorf("strategy %s has no param %q", d.Name, name)
+		}
+		e.params[name] = v
+	}
+
+	last := func(label string, node node) (float64, error) {
+		s, err := node.eval(e)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", label, err)
+		}
+		v := s[len(s)-1]
+		if math.IsNaN(v) {
+			return 0, fmt.Errorf("%w: %s needs more than %d candles", ErrInsufficientData, label, n)
+		}
+		return v, nil
+	}
+
+	res := &Result{Strategy: d.Name, Signal: NoSignal, Matched: true, Rules: []RuleResult{}, Params: e.params}
+	for _, r := range d.When {
+		v, err := last(fmt.Sprintf("rule %q", r.Expr), r.node)
+		if err != nil {
+			return nil, err
+		}
+		rr := RuleResult{Name: r.Name, Rule: r.Expr, Passed: v != 0}
+		var lhs node
+		switch x := r.node.(type) {
+		case binary:
+			if x.op != "and" && x.op != "or" {
+				lhs = x.l
+			}
+		case between:
+			lhs = x.x
+		}
+		if lhs != nil {
+			if s, err := lhs.eval(e); err == nil && !math.IsNaN(s[n-1]) {
+				value := s[n-1]
+				rr.Value = &value
+			}
+		}
+		res.Rules = append(res.Rules, rr)
+		res.Matched = res.Matched && rr.Passed
+	}
+	if res.Matched {
+		res.Signal = d.Signal
+	}
+
+	var err error
+	res.Entry = b.Close[n-1]
+	if d.entry != nil {
+		if res.Entry, err = last("entry", d.entry); err != nil {
+			return nil, err
+		}
+	}
+	e.vars["entry"] = res.Entry
+	if d.stop != nil {
+		if res.Stop, err = last("stop", d.stop); err != nil {
+			return nil, err
+		}
+	}
+	e.vars["stop"] = res.Stop
+	if d.target != nil {
+		if res.Target, err = last("target", d.target); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+var (
+	mu  sync.RWMutex
+	dir = "strategies"
+	// defs starts with the built-in setups so the signal tools work before
+	// Init runs.
+	defs = builtins()
+)
+
+// Init loads every .yaml, .yml and .json file in STRATEGY_DIR. A file named
+// like a built-in strategy replaces it.
+func Init() {
+	if path := os.Getenv("STRATEGY_DIR"); path != "" {
+		dir = path
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		log.Error().Err(err).Str("dir", dir).Msg("Failed to list strategy files")
+		return
+	}
+
+	loaded := builtins()
+	count := 0
+	for _, file := range files {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Error().Err(err).Str("file", file).Msg("Failed to read strategy")
+			continue
+		}
+		d, err := Parse(data, file)
+		if err != nil {
+			log.Error().Err(err).Str("file", file).Msg("Invalid strategy, skipped")
+			continue
+		}
+		if prev, ok := loaded[d.Name]; ok {
+			log.Info().Str("strategy", d.Name).Str("file", file).Str("replaces", prev.Source).Msg("Strategy overridden")
+		}
+		loaded[d.Name] = d
+		count++
+	}
+
+	mu.Lock()
+	defs = loaded
+	mu.Unlock()
+
+	if count > 0 {
+		log.Info().Int("strategies", count).Str("dir", dir).Msg("Strategies loaded")
+	}
+}
+
+func Get(name string) (*Definition, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	d, ok := defs[strings.ToLower(name)]
+	return d, ok
+}
+
+// List returns the strategies by name.
+func List() []*Definition {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]*Definition, 0, len(defs))
+	for _, d := range defs {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func Names() []string {
+	list := List()
+	names := make([]string, len(list))
+	for i, d := range list {
+		names[i] = d.Name
+	}
+	return names
+}
@@ -0,0 +1,100 @@
+package symbols
+
+import (
+	"gokub/utils"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Bitkub THB market rules, used when a pair's own rules are not loaded.
+const (
+	DefaultTickSize     = 0.01
+	DefaultLotSize      = 0.00000001
+	DefaultMinQuoteSize = 10.0
+)
+
+type Rounding int
+
+const (
+	RoundNearest Rounding = iota
+	RoundDown
+	RoundUp
+)
+
+func (p *Pair) PriceStep() float64 {
+	if p.TickSize > 0 {
+		return p.TickSize
+	}
+	return DefaultTickSize
+}
+
+func (p *Pair) AmountStep() float64 {
+	if p.LotSize > 0 {
+		return p.LotSize
+	}
+	return DefaultLotSize
+}
+
+func (p *Pair) MinOrderValue() float64 {
+	if p.MinQuoteSize > 0 {
+		return p.MinQuoteSize
+	}
+	return DefaultMinQuoteSize
+}
+
+// SnapPrice moves a price onto the pair's tick grid.
+func (p *Pair) SnapPrice(price float64, mode Rounding) float64 {
+	return snap(price, p.PriceStep(), mode)
+}
+
+// SnapAmount rounds an amount down to the lot size, so a snapped order never
+// costs more than the amount it was derived from.
+func (p *Pair) SnapAmount(amount float64) float64 {
+	return snap(amount, p.AmountStep(), RoundDown)
+}
+
+// CheckOrder reports an order the exchange would reject for its size or
+// because the pair is not accepting orders on that side.
+func (p *Pair) CheckOrder(side string, price, amount float64) *utils.ToolError {
+	if !p.Active() {
+		return utils.NewError(utils.CodeExchangeRejected, "%s is not trading (status %s)", strings.ToUpper(p.Symbol), p.Status).
+			With("symbol", p.Symbol)
+	}
+	if (side == "buy" && p.FreezeBuy) || (side == "sell" && p.FreezeSell) {
+		return utils.NewError(utils.CodeExchangeRejected, "%s orders are frozen on %s", side, strings.ToUpper(p.Symbol)).
+			With("symbol", p.Symbol)
+	}
+	if amount < p.AmountStep() {
+		return utils.InvalidField("amount", "is smaller than the lot size "+strconv.FormatFloat(p.AmountStep(), 'f', -1, 64)).
+			With("lot_size", p.AmountStep())
+	}
+	if value := price * amount; value < p.MinOrderValue() {
+		return utils.NewError(utils.CodeInvalidArgument, "order value %.2f THB is below the minimum of %.2f THB", value, p.MinOrderValue()).
+			With("min_quote_size", p.MinOrderValue())
+	}
+	return nil
+}
+
+func snap(value, step float64, mode Rounding) float64 {
+	n := value / step
+	switch mode {
+	case RoundDown:
+		n = math.Floor(n + 1e-9)
+	case RoundUp:
+		n = math.Ceil(n - 1e-9)
+	default:
+		n = math.Round(n)
+	}
+	return utils.Round(n*step, decimals(step))
+}
+
+func decimals(step float64) int {
+	for d := 0; d < 12; d++ {
+		scaled := step * math.Pow10(d)
+		if math.Abs(scaled-math.Round(scaled)) < 1e-9 {
+			return d
+		}
+	}
+	return 12
+}
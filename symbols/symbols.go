@@ -0,0 +1,305 @@
+package symbols
+
+import (
+	"fmt"
+	"gokub/metrics"
+	"gokub/utils"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const QuoteAsset = "thb"
+
+// Pair is a listed trading pair. Symbol is always the canonical lowercase
+// form, e.g. btc_thb, which is what the go-bitkub market calls expect.
+type Pair struct {
+	Symbol          string  `json:"symbol"`
+	Base            string  `json:"base"`
+	Quote           string  `json:"quote"`
+	Status          string  `json:"status"`
+	PricePrecision  int     `json:"price_precision"`
+	AmountPrecision int     `json:"amount_precision"`
+	TickSize        float64 `json:"tick_size"`
+	LotSize         float64 `json:"lot_size"`
+	MinQuoteSize    float64 `json:"min_quote_size"`
+	FreezeBuy       bool    `json:"freeze_buy"`
+	FreezeSell      bool    `json:"freeze_sell"`
+}
+
+func (p *Pair) Active() bool {
+	return p.Status == "" || strings.EqualFold(p.Status, "active")
+}
+
+// listedSymbol mirrors the /api/v3/market/symbols payload. go-bitkub decodes
+// the same payload, so its market.Symbol round-trips into this shape.
+type listedSymbol struct {
+	Symbol             string  `json:"symbol"`
+	BaseAsset          string  `json:"base_asset"`
+	QuoteAsset         string  `json:"quote_asset"`
+	Status             string  `json:"status"`
+	BuyPricePrecision  int     `json:"buy_price_precision"`
+	BuySizePrecision   int     `json:"buy_size_precision"`
+	SellPricePrecision int     `json:"sell_price_precision"`
+	SellSizePrecision  int     `json:"sell_size_precision"`
+	PriceStep          string  `json:"price_step"`
+	QuantityStep       string  `json:"quantity_step"`
+	MinQuoteSize       float64 `json:"min_quote_size"`
+	FreezeBuy          bool    `json:"freeze_buy"`
+	FreezeSell         bool    `json:"freeze_sell"`
+}
+
+var (
+	mu       sync.RWMutex
+	pairs    = map[string]*Pair{}
+	loadedAt time.Time
+	refresh  = time.Hour
+)
+
+// Init loads the registry in the background and keeps it fresh. Until the
+// first load succeeds symbols are normalized but accepted unvalidated.
+func Init() {
+	if d, err := time.ParseDuration(os.Getenv("SYMBOLS_REFRESH_INTERVAL")); err == nil && d > 0 {
+		refresh = d
+	}
+
+	go func() {
+		if err := Refresh(); err != nil {
+			log.Warn().Err(err).Msg("Failed to load symbol registry, symbols will not be validated until the next refresh")
+		}
+		for range time.Tick(refresh) {
+			if err := Refresh(); err != nil {
+				log.Warn().Err(err).Msg("Failed to refresh symbol registry")
+			}
+		}
+	}()
+}
+
+func Refresh() error {
+	listed, err := market.GetSymbols()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(listed)
+	if err != nil {
+		return err
+	}
+	var raw []listedSymbol
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("decode symbols: %w", err)
+	}
+
+	loaded := make(map[string]*Pair, len(raw))
+	for _, s := range raw {
+		pair := newPair(s)
+		if pair.Base == "" {
+			continue
+		}
+		loaded[pair.Symbol] = pair
+	}
+	if len(loaded) == 0 {
+		return fmt.Errorf("symbols endpoint returned no pairs")
+	}
+
+	mu.Lock()
+	pairs = loaded
+	loadedAt = time.Now()
+	mu.Unlock()
+
+	log.Debug().Int("pairs", len(loaded)).Msg("Symbol registry loaded")
+	return nil
+}
+
+func newPair(s listedSymbol) *Pair {
+	symbol := Normalize(s.Symbol)
+	base, quote, _ := strings.Cut(symbol, "_")
+	if s.BaseAsset != "" {
+		base = strings.ToLower(s.BaseAsset)
+	}
+	if s.QuoteAsset != "" {
+		quote = strings.ToLower(s.QuoteAsset)
+	}
+
+	pricePrecision := min(s.BuyPricePrecision, s.SellPricePrecision)
+	amountPrecision := min(s.BuySizePrecision, s.SellSizePrecision)
+
+	tickSize := parseStep(s.PriceStep)
+	if tickSize == 0 && pricePrecision > 0 {
+		tickSize = math.Pow10(-pricePrecision)
+	}
+	lotSize := parseStep(s.QuantityStep)
+	if lotSize == 0 && amountPrecision > 0 {
+		lotSize = math.Pow10(-amountPrecision)
+	}
+
+	return &Pair{
+		Symbol:          base + "_" + quote,
+		Base:            base,
+		Quote:           quote,
+		Status:          strings.ToLower(s.Status),
+		PricePrecision:  pricePrecision,
+		AmountPrecision: amountPrecision,
+		TickSize:        tickSize,
+		LotSize:         lotSize,
+		MinQuoteSize:    s.MinQuoteSize,
+		FreezeBuy:       s.FreezeBuy,
+		FreezeSell:      s.FreezeSell,
+	}
+}
+
+// Normalize turns any accepted spelling (btc, BTC, btc_thb, THB_BTC, BTC/THB,
+// btc-thb) into the canonical base_quote form. It does not check the listing.
+func Normalize(input string) string {
+	s := strings.ToLower(strings.TrimSpace(input))
+	s = strings.NewReplacer("/", "_", "-", "_", " ", "").Replace(s)
+
+	base, quote, found := strings.Cut(s, "_")
+	if !found {
+		return s + "_" + QuoteAsset
+	}
+	if base == QuoteAsset && quote != QuoteAsset {
+		base, quote = quote, base
+	}
+	return base + "_" + quote
+}
+
+// Resolve normalizes the symbol and checks it against the listed pairs. An
+// unknown symbol is reported as NOT_FOUND_SYMBOL with close matches.
+func Resolve(input string) (*Pair, *utils.ToolError) {
+	symbol := Normalize(input)
+
+	mu.RLock()
+	pair, ok := pairs[symbol]
+	loaded := len(pairs) > 0
+	mu.RUnlock()
+
+	if ok {
+		metrics.CacheHit("symbols")
+		return pair, nil
+	}
+	metrics.CacheMiss("symbols")
+	if !loaded {
+		base, quote, _ := strings.Cut(symbol, "_")
+		return &Pair{Symbol: symbol, Base: base, Quote: quote}, nil
+	}
+
+	e := utils.NewError(utils.CodeNotFoundSymbol, "%s is not a listed trading pair", strings.ToUpper(symbol)).
+		With("symbol", symbol)
+	if suggestions := Suggest(symbol, 3); len(suggestions) > 0 {
+		e.Message += fmt.Sprintf(", did you mean %s?", strings.Join(suggestions, ", "))
+		e.With("suggestions", suggestions)
+	}
+	return nil, e
+}
+
+// Get returns the listed pair for a symbol in any accepted spelling.
+func Get(input string) (*Pair, bool) {
+	mu.RLock()
+	pair, ok := pairs[Normalize(input)]
+	mu.RUnlock()
+
+	if ok {
+		metrics.CacheHit("symbols")
+	} else {
+		metrics.CacheMiss("symbols")
+	}
+	return pair, ok
+}
+
+func List() []*Pair {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]*Pair, 0, len(pairs))
+	for _, pair := range pairs {
+		list = append(list, pair)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Symbol < list[j].Symbol
+	})
+	return list
+}
+
+func LoadedAt() time.Time {
+	mu.RLock()
+	defer mu.RUnlock()
+	return loadedAt
+}
+
+// Suggest returns up to n listed symbols closest to the given one by edit
+// distance of the base asset, ties broken alphabetically.
+func Suggest(symbol string, n int) []string {
+	base, _, _ := strings.Cut(Normalize(symbol), "_")
+
+	type candidate struct {
+		symbol   string
+		distance int
+	}
+
+	mu.RLock()
+	candidates := make([]candidate, 0, len(pairs))
+	for _, pair := range pairs {
+		d := levenshtein(base, pair.Base)
+		if strings.HasPrefix(pair.Base, base) || strings.HasPrefix(base, pair.Base) {
+			d = min(d, 1)
+		}
+		if d <= max(1, len(base)/3) {
+			candidates = append(candidates, candidate{pair.Symbol, d})
+		}
+	}
+	mu.RUnlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].symbol < candidates[j].symbol
+	})
+
+	out := make([]string, 0, n)
+	for i := 0; i < len(candidates) && i < n; i++ {
+		out = append(out, candidates[i].symbol)
+	}
+	return out
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func parseStep(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f <= 0 {
+		return 0
+	}
+	return f
+}
@@ -0,0 +1,27 @@
+package tools
+
+import (
+	"context"
+	"gokub/accounts"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type AccountInput struct {
+	Account string `json:"account"`
+}
+
+func withAccountArg() mcp.ToolOption {
+	return mcp.WithString("account",
+		mcp.Description("Bitkub account profile to use (e.g. personal, fund). Defaults to the session's or server's default account"),
+	)
+}
+
+func useAccount(ctx context.Context, requested string, fn func() error) (string, error) {
+	account, err := accounts.Resolve(ctx, requested)
+	if err != nil {
+		return "", err
+	}
+
+	return account, accounts.Use(account, fn)
+}
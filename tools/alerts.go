@@ -0,0 +1,432 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/alerts"
+	"gokub/tracing"
+	"gokub/utils"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+type CreateAlertInput struct {
+	Symbol        string  `json:"symbol"`
+	Condition     string  `json:"condition"`
+	Threshold     float64 `json:"threshold"`
+	WindowMinutes int     `json:"window_minutes"`
+	Resolution    int     `json:"resolution"`
+	Period        int     `json:"period"`
+	Repeat        bool    `json:"repeat"`
+	Note          string  `json:"note"`
+}
+
+type ListAlertsInput struct {
+	Symbol string `json:"symbol"`
+}
+
+type DeleteAlertInput struct {
+	ID string `json:"id"`
+}
+
+type AlertOutput struct {
+	Alert alerts.Alert `json:"alert"`
+}
+
+type ListAlertsOutput struct {
+	PollInterval string           `json:"poll_interval"`
+	Alerts       []alerts.Alert   `json:"alerts"`
+	Recent       []alerts.Trigger `json:"recent_triggers"`
+}
+
+func conditionNames() []string {
+	names := make([]string, len(alerts.Conditions))
+	for i, c := range alerts.Conditions {
+		names[i] = string(c)
+	}
+	return names
+}
+
+func NewCreateAlertTool() mcp.Tool {
+	return mcp.NewTool("create_alert",
+		mcp.WithDescription("Create a price alert that is checked in the background and pushed to connected clients as a notification when it fires. "+
+			"Conditions: price_above/price_below (threshold = price), percent_move (threshold = % move within window_minutes, either direction), "+
+			"spread_above (threshold = spread % of mid), rsi_above/rsi_below (threshold = RSI level), breakout (detect_breakout_signal fires; threshold = volume ratio, default 1.5)"),
+		withSymbolArg(),
+		mcp.WithString("condition",
+			mcp.Required(),
+			mcp.Enum(conditionNames()...),
+			mcp.Description("Condition that fires the alert"),
+		),
+		mcp.WithNumber("threshold",
+			mcp.Min(0),
+			mcp.Description("Level for the condition: price, percent, RSI level or breakout volume ratio"),
+		),
+		utils.WithInteger("window_minutes",
+			mcp.DefaultNumber(60),
+			mcp.Min(1),
+			mcp.Max(1440),
+			mcp.Description("Look-back window for percent_move in minutes (default: 60)"),
+		),
+		utils.WithInteger("resolution",
+			mcp.DefaultNumber(60),
+			utils.EnumNumbers(1, 5, 15, 60, 240, 1440),
+			mcp.Description("Candle timeframe in minutes for rsi and breakout conditions (default: 60)"),
+		),
+		utils.WithInteger("period",
+			mcp.Min(2),
+			mcp.Max(200),
+			mcp.Description("RSI period (default: 14) or breakout lookback (default: 20)"),
+		),
+		mcp.WithBoolean("repeat",
+			mcp.DefaultBool(false),
+			mcp.Description("Keep the alert active after it fires; it fires again each time the condition starts holding (default: false)"),
+		),
+		mcp.WithString("note",
+			mcp.MaxLength(200),
+			mcp.Description("Free text included in the notification"),
+		),
+		mcp.WithOutputSchema[AlertOutput](),
+	)
+}
+
+func CreateAlertHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input CreateAlertInput
+	if err := utils.BindArgs(NewCreateAlertTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for create alert")
+		return err.Result()
+	}
+
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+
+	alert := alerts.Alert{
+		Symbol:    pair.Symbol,
+		Condition: alerts.Condition(input.Condition),
+		Threshold: input.Threshold,
+		Repeat:    input.Repeat,
+		Note:      input.Note,
+	}
+
+	switch alert.Condition {
+	case alerts.PriceAbove, alerts.PriceBelow, alerts.PercentMove, alerts.SpreadAbove:
+		if alert.Threshold <= 0 {
+			return utils.InvalidField("threshold", "is required and must be greater than 0 for "+input.Condition).Result()
+		}
+		if alert.Condition == alerts.PercentMove {
+			alert.WindowMinutes = input.WindowMinutes
+		}
+	case alerts.RSIAbove, alerts.RSIBelow:
+		if alert.Threshold <= 0 || alert.Threshold >= 100 {
+			return utils.InvalidField("threshold", "must be an RSI level between 0 and 100").Result()
+		}
+		alert.Resolution, alert.Period = input.Resolution, input.Period
+		if alert.Period == 0 {
+			alert.Period = 14
+		}
+	case alerts.Breakout:
+		if alert.Threshold == 0 {
+			alert.Threshold = 1.5
+		}
+		alert.Resolution, alert.Period = input.Resolution, input.Period
+		if alert.Period == 0 {
+			alert.Period = 20
+		}
+	}
+
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		alert.CreatedBy = session.SessionID()
+	}
+	alert.Owner = alerts.Owner(ctx)
+
+	created, err := alerts.Add(alert)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Msg("Failed to save alert")
+		return utils.NewError(utils.CodeInternal, "failed to save alert: %v", err).Result()
+	}
+
+	result := fmt.Sprintf("🔔 Alert %s created: %s\n", created.ID, describeAlert(created))
+	result += fmt.Sprintf("Checked every %s", alerts.Interval())
+	if created.Repeat {
+		result += ", fires each time the condition starts holding"
+	} else {
+		result += ", fires once"
+	}
+
+	return utils.ArtifactsResult(result, AlertOutput{Alert: created})
+}
+
+func NewListAlertsTool() mcp.Tool {
+	return mcp.NewTool("list_alerts",
+		mcp.WithDescription("List price alerts with their status and last evaluated value, plus the most recent alerts that fired"),
+		mcp.WithString("symbol",
+			mcp.Description("Only list alerts for this trading pair"),
+			mcp.Pattern(symbolPattern),
+		),
+		mcp.WithOutputSchema[ListAlertsOutput](),
+	)
+}
+
+func ListAlertsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input ListAlertsInput
+	if err := utils.BindArgs(NewListAlertsTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for list alerts")
+		return err.Result()
+	}
+
+	symbol := ""
+	if input.Symbol != "" {
+		pair, symErr := resolveSymbol(ctx, input.Symbol)
+		if symErr != nil {
+			return symErr.Result()
+		}
+		symbol = pair.Symbol
+	}
+
+	output := ListAlertsOutput{
+		PollInterval: alerts.Interval().String(),
+		Alerts:       alerts.List(alerts.Owner(ctx), symbol),
+		Recent:       []alerts.Trigger{},
+	}
+	for _, t := range alerts.Recent(alerts.Owner(ctx)) {
+		if symbol == "" || t.Symbol == symbol {
+			output.Recent = append(output.Recent, t)
+		}
+	}
+
+	if len(output.Alerts) == 0 {
+		return utils.ArtifactsResult("No alerts", output)
+	}
+
+	result := fmt.Sprintf("🔔 %d alerts (checked every %s)\n", len(output.Alerts), output.PollInterval)
+	for _, a := range output.Alerts {
+		result += fmt.Sprintf("- %s [%s] %s | last %.8g", a.ID, a.Status, describeAlert(a), a.LastValue)
+		if a.TriggerCount > 0 {
+			result += fmt.Sprintf(" | fired %dx, last %s", a.TriggerCount, time.Unix(a.TriggeredAt, 0).UTC().Format(time.RFC3339))
+		}
+		if a.LastError != "" {
+			result += " | error: " + a.LastError
+		}
+		result += "\n"
+	}
+
+	return utils.ArtifactsResult(strings.TrimSuffix(result, "\n"), output)
+}
+
+func NewDeleteAlertTool() mcp.Tool {
+	return mcp.NewTool("delete_alert",
+		mcp.WithDescription("Delete a price alert by id"),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Alert id as returned by create_alert or list_alerts"),
+		),
+		mcp.WithOutputSchema[AlertOutput](),
+	)
+}
+
+func DeleteAlertHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input DeleteAlertInput
+	if err := utils.BindArgs(NewDeleteAlertTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for delete alert")
+		return err.Result()
+	}
+
+	if a, ok := alerts.Get(input.ID); !ok || !alerts.Sees(alerts.Owner(ctx), a.Owner) {
+		return utils.NewError(utils.CodeNotFound, "alert %s not found", input.ID).With("id", input.ID).Result()
+	}
+	deleted, ok, err := alerts.Delete(input.ID)
+	if !ok {
+		return utils.NewError(utils.CodeNotFound, "alert %s not found", input.ID).With("id", input.ID).Result()
+	}
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("alert", input.ID).Msg("Failed to delete alert")
+		return utils.NewError(utils.CodeInternal, "failed to delete alert: %v", err).Result()
+	}
+
+	return utils.ArtifactsResult(fmt.Sprintf("🗑️ Alert %s deleted: %s", deleted.ID, describeAlert(deleted)), AlertOutput{Alert: deleted})
+}
+
+func describeAlert(a alerts.Alert) string {
+	symbol := strings.ToUpper(a.Symbol)
+	desc := ""
+	switch a.Condition {
+	case alerts.PriceAbove:
+		desc = fmt.Sprintf("%s price above %.8g", symbol, a.Threshold)
+	case alerts.PriceBelow:
+		desc = fmt.Sprintf("%s price below %.8g", symbol, a.Threshold)
+	case alerts.PercentMove:
+		desc = fmt.Sprintf("%s moves %.2f%% within %dm", symbol, a.Threshold, a.WindowMinutes)
+	case alerts.SpreadAbove:
+		desc = fmt.Sprintf("%s spread above %.2f%%", symbol, a.Threshold)
+	case alerts.RSIAbove:
+		desc = fmt.Sprintf("%s RSI(%d, %dm) above %.2f", symbol, a.Period, a.Resolution, a.Threshold)
+	case alerts.RSIBelow:
+		desc = fmt.Sprintf("%s RSI(%d, %dm) below %.2f", symbol, a.Period, a.Resolution, a.Threshold)
+	case alerts.Breakout:
+		desc = fmt.Sprintf("%s breakout over %d %dm candles with %.2fx volume", symbol, a.Period, a.Resolution, a.Threshold)
+	default:
+		desc = fmt.Sprintf("%s %s %.8g", symbol, a.Condition, a.Threshold)
+	}
+	if a.Note != "" {
+		desc += " (" + a.Note + ")"
+	}
+	return desc
+}
+
+// EvaluateAlert checks one alert against live market data. It is the
+// evaluator the alerts poller runs in the background.
+func EvaluateAlert(ctx context.Context, a alerts.Alert) (alerts.Result, error) {
+	symbol := strings.ToUpper(a.Symbol)
+
+	switch a.Condition {
+	case alerts.PriceAbove, alerts.PriceBelow:
+		ticker, err := alertTicker(ctx, a.Symbol)
+		if err != nil {
+			return alerts.Result{}, err
+		}
+		if a.Condition == alerts.PriceAbove {
+			return alerts.Result{
+				Matched: ticker.Last >= a.Threshold,
+				Value:   ticker.Last,
+				Message: fmt.Sprintf("%s crossed above %.8g, last %.8g", symbol, a.Threshold, ticker.Last),
+			}, nil
+		}
+		return alerts.Result{
+			Matched: ticker.Last <= a.Threshold,
+			Value:   ticker.Last,
+			Message: fmt.Sprintf("%s crossed below %.8g, last %.8g", symbol, a.Threshold, ticker.Last),
+		}, nil
+
+	case alerts.PercentMove:
+		history, err := alertCandles(ctx, a.Symbol, 1, a.WindowMinutes)
+		if err != nil {
+			return alerts.Result{}, err
+		}
+		first, last := history.Close[0], history.Close[len(history.Close)-1]
+		if first <= 0 {
+			return alerts.Result{}, fmt.Errorf("no reference price for %s", symbol)
+		}
+		move := utils.Round((last-first)/first*100, 2)
+		return alerts.Result{
+			Matched: math.Abs(move) >= a.Threshold,
+			Value:   move,
+			Message: fmt.Sprintf("%s moved %+.2f%% in %dm (%.8g → %.8g)", symbol, move, a.WindowMinutes, first, last),
+		}, nil
+
+	case alerts.SpreadAbove:
+		ticker, err := alertTicker(ctx, a.Symbol)
+		if err != nil {
+			return alerts.Result{}, err
+		}
+		if ticker.HighestBid <= 0 || ticker.LowestAsk <= 0 {
+			return alerts.Result{}, fmt.Errorf("invalid bid/ask prices for %s", symbol)
+		}
+		mid := (ticker.HighestBid + ticker.LowestAsk) / 2
+		spread := utils.Round((ticker.LowestAsk-ticker.HighestBid)/mid*100, 4)
+		return alerts.Result{
+			Matched: spread >= a.Threshold,
+			Value:   spread,
+			Message: fmt.Sprintf("%s spread widened to %.4f%% (bid %.8g / ask %.8g)", symbol, spread, ticker.HighestBid, ticker.LowestAsk),
+		}, nil
+
+	case alerts.RSIAbove, alerts.RSIBelow:
+		history, err := alertCandles(ctx, a.Symbol, a.Resolution, a.Period*3)
+		if err != nil {
+			return alerts.Result{}, err
+		}
+		if len(history.Close) < a.Period+1 {
+			return alerts.Result{}, fmt.Errorf("need at least %d candles for RSI(%d), got %d", a.Period+1, a.Period, len(history.Close))
+		}
+		rsi := utils.Round(calculateRSI(history.Close, a.Period), 2)
+		if a.Condition == alerts.RSIAbove {
+			return alerts.Result{
+				Matched: rsi >= a.Threshold,
+				Value:   rsi,
+				Message: fmt.Sprintf("%s RSI(%d) crossed above %.2f: %.2f", symbol, a.Period, a.Threshold, rsi),
+			}, nil
+		}
+		return alerts.Result{
+			Matched: rsi <= a.Threshold,
+			Value:   rsi,
+			Message: fmt.Sprintf("%s RSI(%d) crossed below %.2f: %.2f", symbol, a.Period, a.Threshold, rsi),
+		}, nil
+
+	case alerts.Breakout:
+		history, err := alertCandles(ctx, a.Symbol, a.Resolution, a.Period+15)
+		if err != nil {
+			return alerts.Result{}, err
+		}
+		candles := historyCandles(history)
+		res, err := DetectBreakoutSignalHandler(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+			Name: "detect_breakout_signal",
+			Arguments: map[string]any{
+				"candles":          candles,
+				"lookback":         a.Period,
+				"volume_threshold": a.Threshold,
+				"symbol":           a.Symbol,
+			},
+		}})
+		if err != nil {
+			return alerts.Result{}, err
+		}
+		signal, ok := res.StructuredContent.(*BreakoutSignal)
+		if res.IsError || !ok {
+			reason := "no signal output"
+			if len(res.Content) > 0 {
+				if text, isText := res.Content[0].(mcp.TextContent); isText {
+					reason = text.Text
+				}
+			}
+			return alerts.Result{}, fmt.Errorf("breakout check failed for %s: %s", symbol, reason)
+		}
+		return alerts.Result{
+			Matched: signal.Signal == "BREAKOUT_BUY",
+			Value:   signal.CurrentPrice,
+			Message: fmt.Sprintf("%s breakout: close %.8g above %d-candle high %.8g on %.2fx volume, entry %.8g stop %.8g",
+				symbol, signal.CurrentPrice, a.Period, signal.High20, signal.VolumeRatio, signal.SuggestedEntry, signal.SuggestedStop),
+		}, nil
+	}
+
+	return alerts.Result{}, fmt.Errorf("unknown alert condition %q", a.Condition)
+}
+
+func alertTicker(ctx context.Context, symbol string) (market.Ticker, error) {
+	tickers, err := tracing.Exchange(ctx, "market.GetTicker", symbol, func() ([]market.Ticker, error) {
+		return market.GetTicker(symbol)
+	})
+	if err != nil {
+		return market.Ticker{}, err
+	}
+	if len(tickers) == 0 {
+		return market.Ticker{}, fmt.Errorf("no ticker data for %s", symbol)
+	}
+	return tickers[0], nil
+}
+
+func alertCandles(ctx context.Context, symbol string, resolution, count int) (*market.History, error) {
+	now := time.Now().Unix()
+	history, err := tracing.Exchange(ctx, "market.GetHistory", symbol, func() (*market.History, error) {
+		return market.GetHistory(market.HistoryRequest{
+			Symbol:     strings.ToUpper(symbol),
+			Resolution: validResolutions[resolution],
+			From:       now - int64(count*resolution*60),
+			To:         now,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if history == nil || len(history.Close) == 0 {
+		return nil, fmt.Errorf("no candle data for %s", symbol)
+	}
+	return history, nil
+}
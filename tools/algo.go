@@ -0,0 +1,487 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/accounts"
+	"gokub/algo"
+	"gokub/orders"
+	"gokub/symbols"
+	"gokub/tracing"
+	"gokub/utils"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+// algoMinInterval keeps slices far enough apart for the book to refill.
+const algoMinInterval = 10
+
+type AlgoCreateInput struct {
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"`
+	Amount          float64 `json:"amount"`
+	Strategy        string  `json:"strategy"`
+	DurationMinutes int     `json:"duration_minutes"`
+	Slices          int     `json:"slices"`
+	Randomize       float64 `json:"randomize_percent"`
+	Participation   float64 `json:"participation_percent"`
+	IntervalSeconds int     `json:"interval_seconds"`
+	LimitPrice      float64 `json:"limit_price"`
+	Note            string  `json:"note"`
+	AccountInput
+}
+
+type AlgoStatusInput struct {
+	ID            string `json:"id"`
+	IncludeClosed bool   `json:"include_closed"`
+	HistoryLimit  int    `json:"history_limit"`
+}
+
+type AlgoPauseInput struct {
+	ID     string `json:"id"`
+	Resume bool   `json:"resume"`
+}
+
+type AlgoCancelInput struct {
+	ID string `json:"id"`
+}
+
+// AlgoImpact is what sending the whole order at once would cost.
+type AlgoImpact struct {
+	AvgPrice    float64 `json:"avg_price"`
+	WorstPrice  float64 `json:"worst_price"`
+	SlippageBps float64 `json:"slippage_bps"`
+	Levels      int     `json:"levels"`
+	Filled      bool    `json:"book_covers_order"`
+}
+
+type AlgoOutput struct {
+	Order  algo.Order  `json:"order"`
+	Report algo.Report `json:"report"`
+	Impact *AlgoImpact `json:"single_order_impact,omitempty"`
+}
+
+type AlgoListOutput struct {
+	PollInterval string        `json:"poll_interval"`
+	Orders       []*AlgoOutput `json:"orders"`
+}
+
+func algoOutput(o algo.Order) *AlgoOutput {
+	return &AlgoOutput{Order: o, Report: o.Report()}
+}
+
+func NewAlgoCreateTool() mcp.Tool {
+	return mcp.NewTool("algo_create",
+		mcp.WithDescription("Work a large order in slices instead of hitting the book at once, for sizes calculate_liquidity_depth or estimate_fill show would move the price. "+
+			"twap splits amount over duration_minutes with randomized slice sizes and timing; vwap sends participation_percent of the market volume traded since the last slice. "+
+			"Each slice is a market order that only takes liquidity inside limit_price. Progress and the final report compare the average fill with the arrival (mid) price"),
+		utils.WithTrading(),
+		withSymbolArg(),
+		mcp.WithString("side",
+			mcp.Required(),
+			mcp.Enum(orders.Buy, orders.Sell),
+			mcp.Description("Order side"),
+		),
+		mcp.WithNumber("amount",
+			mcp.Required(),
+			utils.ExclusiveMin(0),
+			mcp.Description("Total order size in coin"),
+		),
+		mcp.WithString("strategy",
+			mcp.Required(),
+			mcp.Enum(algo.TWAP, algo.VWAP),
+			mcp.Description("twap: time-sliced over duration_minutes; vwap: a share of traded volume"),
+		),
+		utils.WithInteger("duration_minutes",
+			mcp.Min(1),
+			mcp.Max(10080),
+			mcp.Description("How long to work the order. Required for twap; for vwap the order expires unfilled after it, or runs until filled when omitted"),
+		),
+		utils.WithInteger("slices",
+			mcp.DefaultNumber(10),
+			mcp.Min(1),
+			mcp.Max(1000),
+			mcp.Description("twap: number of child orders (default: 10)"),
+		),
+		mcp.WithNumber("randomize_percent",
+			mcp.DefaultNumber(20),
+			mcp.Min(0),
+			mcp.Max(50),
+			mcp.Description("twap: jitter applied to each slice's size and timing, so the pattern is harder to spot (default: 20)"),
+		),
+		mcp.WithNumber("participation_percent",
+			mcp.DefaultNumber(10),
+			utils.ExclusiveMin(0),
+			mcp.Max(50),
+			mcp.Description("vwap: share of the market volume to trade (default: 10)"),
+		),
+		utils.WithInteger("interval_seconds",
+			mcp.DefaultNumber(60),
+			mcp.Min(algoMinInterval),
+			mcp.Max(3600),
+			mcp.Description("vwap: seconds between volume checks (default: 60)"),
+		),
+		mcp.WithNumber("limit_price",
+			utils.ExclusiveMin(0),
+			mcp.Description("Worst price to trade at: slices are sent as limit orders at it, so buys pay no more and sells get no less. Slices with nothing inside the limit are skipped"),
+		),
+		mcp.WithString("note",
+			mcp.MaxLength(200),
+			mcp.Description("Free text kept with the order"),
+		),
+		withAccountArg(),
+		mcp.WithOutputSchema[AlgoOutput](),
+	)
+}
+
+func AlgoCreateHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input AlgoCreateInput
+	if err := utils.BindArgs(NewAlgoCreateTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for algo create")
+		return err.Result()
+	}
+
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+	symbol := pair.Symbol
+
+	amount := pair.SnapAmount(input.Amount)
+	if input.Strategy == algo.TWAP && input.DurationMinutes == 0 {
+		return utils.InvalidField("duration_minutes", "is required for twap").Result()
+	}
+
+	account, err := accounts.Resolve(ctx, input.Account)
+	if err != nil {
+		return utils.ExchangeErrorResult(err)
+	}
+
+	depth, err := tracing.Exchange(ctx, "market.GetDepth", symbol, func() (*market.Depth, error) {
+		return market.GetDepth(symbol, 100)
+	})
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", symbol).Msg("Failed to get market depth for algo order")
+		return utils.ExchangeErrorResult(err)
+	}
+	if len(depth.Bids) == 0 || len(depth.Asks) == 0 {
+		return utils.NewError(utils.CodeInsufficientData, "order book for %s is empty", symbol).With("symbol", symbol).Result()
+	}
+	mid := (depth.Bids[0][0] + depth.Asks[0][0]) / 2
+
+	levels := depth.Asks
+	if input.Side == orders.Sell {
+		levels = depth.Bids
+	}
+	fill := orders.WalkBook(levels, amount, false)
+	impact := &AlgoImpact{
+		AvgPrice:    utils.Round(fill.VWAP()),
+		WorstPrice:  fill.Worst,
+		SlippageBps: utils.Round(orders.SlippageBps(input.Side, fill.VWAP(), mid), 2),
+		Levels:      fill.Levels,
+		Filled:      fill.Filled,
+	}
+
+	if terr := pair.CheckOrder(input.Side, mid, amount); terr != nil {
+		return terr.Result()
+	}
+
+	o := algo.Order{
+		Strategy:     input.Strategy,
+		Symbol:       symbol,
+		Side:         input.Side,
+		Amount:       amount,
+		ArrivalPrice: utils.Round(mid),
+		Account:      account,
+		Note:         input.Note,
+	}
+	if input.LimitPrice > 0 {
+		o.LimitPrice = pair.SnapPrice(input.LimitPrice, symbols.RoundNearest)
+	}
+	if input.DurationMinutes > 0 {
+		o.EndAt = time.Now().Add(time.Duration(input.DurationMinutes) * time.Minute).Unix()
+	}
+
+	switch input.Strategy {
+	case algo.TWAP:
+		duration := input.DurationMinutes * 60
+		step := duration / input.Slices
+		minStep := max(algoMinInterval, int(algo.Interval()/time.Second))
+		if step < minStep {
+			return utils.InvalidField("slices", fmt.Sprintf("%d slices over %d minutes are %ds apart; use at most %d so they are at least %ds apart", input.Slices, input.DurationMinutes, step, duration/minStep, minStep)).Result()
+		}
+		minValue := pair.MinOrderValue()
+		if sliceValue := amount / float64(input.Slices) * mid; sliceValue < minValue {
+			most := max(int(math.Floor(amount*mid/minValue)), 1)
+			return utils.InvalidField("slices", fmt.Sprintf("each slice would be %.2f THB, below the %.2f THB minimum order; use at most %d", sliceValue, minValue, most)).Result()
+		}
+		o.Slices, o.Randomize, o.Interval = input.Slices, input.Randomize, int64(step)
+	case algo.VWAP:
+		o.Participation, o.Interval = input.Participation, int64(input.IntervalSeconds)
+	}
+
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		o.CreatedBy = session.SessionID()
+	}
+
+	created, err := algo.Create(o)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Msg("Failed to create algo order")
+		return utils.NewError(utils.CodeInternal, "failed to save algo order: %v", err).Result()
+	}
+
+	output := algoOutput(created)
+	output.Impact = impact
+
+	result := fmt.Sprintf("🧮 %s %s started on %s: %s %.8g %s, arrival %.8g\n", strings.ToUpper(created.Strategy), created.ID, created.Account, created.Side, created.Amount, strings.ToUpper(symbol), created.ArrivalPrice)
+	result += describeAlgo(created) + "\n"
+	if impact.Filled {
+		result += fmt.Sprintf("A single market order would fill at ~%.8g (%.2f bps from mid, %d levels, worst %.8g)", impact.AvgPrice, impact.SlippageBps, impact.Levels, impact.WorstPrice)
+	} else {
+		result += "The visible book cannot fill the whole order at once"
+	}
+
+	return utils.ArtifactsResult(result, output)
+}
+
+func NewAlgoStatusTool() mcp.Tool {
+	return mcp.NewTool("algo_status",
+		mcp.WithDescription("Show TWAP/VWAP order progress: filled amount, average price, slippage against the arrival price and recent slices. Pass id for one order"),
+		utils.WithScope(utils.ScopeAccount),
+		mcp.WithString("id",
+			mcp.Description("Algo order id as returned by algo_create"),
+		),
+		mcp.WithBoolean("include_closed",
+			mcp.DefaultBool(false),
+			mcp.Description("Include completed, cancelled and expired orders (default: false)"),
+		),
+		utils.WithInteger("history_limit",
+			mcp.DefaultNumber(10),
+			mcp.Min(0),
+			mcp.Max(500),
+			mcp.Description("Most recent slices to include per order (default: 10)"),
+		),
+		mcp.WithOutputSchema[AlgoListOutput](),
+	)
+}
+
+func AlgoStatusHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input AlgoStatusInput
+	if err := utils.BindArgs(NewAlgoStatusTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for algo status")
+		return err.Result()
+	}
+
+	var list []algo.Order
+	if input.ID != "" {
+		o, terr := callerAlgo(ctx, input.ID)
+		if terr != nil {
+			return terr.Result()
+		}
+		list = append(list, o)
+	} else {
+		for _, o := range algo.List(!input.IncludeClosed) {
+			if accounts.Permits(ctx, o.Account) {
+				list = append(list, o)
+			}
+		}
+	}
+
+	output := AlgoListOutput{PollInterval: algo.Interval().String(), Orders: []*AlgoOutput{}}
+	for _, o := range list {
+		a := algoOutput(o)
+		if len(o.History) > input.HistoryLimit {
+			a.Order.History = o.History[len(o.History)-input.HistoryLimit:]
+		}
+		output.Orders = append(output.Orders, a)
+	}
+
+	if len(output.Orders) == 0 {
+		return utils.ArtifactsResult("No algo orders", output)
+	}
+
+	result := ""
+	for _, a := range output.Orders {
+		o := a.Order
+		result += fmt.Sprintf("🧮 %s [%s] %s %s %.8g %s (%s)\n", o.ID, o.Status, strings.ToUpper(o.Strategy), o.Side, o.Amount, strings.ToUpper(o.Symbol), o.Account)
+		result += describeAlgo(o) + "\n"
+		result += describeAlgoReport(a.Report) + "\n"
+		if o.Status == algo.StatusRunning && o.NextSliceAt > 0 {
+			result += "Next slice: " + time.Unix(o.NextSliceAt, 0).Format(time.RFC3339) + "\n"
+		}
+		if o.Reason != "" {
+			result += "Reason: " + o.Reason + "\n"
+		}
+		for i := len(o.History) - 1; i >= 0; i-- {
+			s := o.History[i]
+			result += fmt.Sprintf("  %s %s %.8g", time.Unix(s.At, 0).Format("15:04:05"), s.Status, s.Amount)
+			switch {
+			case s.Filled > 0:
+				result += fmt.Sprintf(", filled %.8g @ %.8g", s.Filled, s.Price)
+			case s.Estimate > 0:
+				result += fmt.Sprintf(" @ ~%.8g", s.Estimate)
+			}
+			if s.OrderID != "" {
+				result += " | order " + s.OrderID
+			}
+			if s.Note != "" {
+				result += " | " + s.Note
+			}
+			result += "\n"
+		}
+	}
+
+	return utils.ArtifactsResult(strings.TrimSuffix(result, "\n"), output)
+}
+
+func NewAlgoPauseTool() mcp.Tool {
+	return mcp.NewTool("algo_pause",
+		mcp.WithDescription("Pause a TWAP/VWAP order, or resume it with resume: true. A resumed twap spreads what is left over the time left; a resumed vwap counts volume from the moment it resumes"),
+		utils.WithScope(utils.ScopeTrade),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Algo order id as returned by algo_create"),
+		),
+		mcp.WithBoolean("resume",
+			mcp.DefaultBool(false),
+			mcp.Description("Resume a paused order instead of pausing it (default: false)"),
+		),
+		mcp.WithOutputSchema[AlgoOutput](),
+	)
+}
+
+func AlgoPauseHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input AlgoPauseInput
+	if err := utils.BindArgs(NewAlgoPauseTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for algo pause")
+		return err.Result()
+	}
+
+	if _, terr := callerAlgo(ctx, input.ID); terr != nil {
+		return terr.Result()
+	}
+
+	o, ok, err := algo.SetPaused(input.ID, !input.Resume)
+	if !ok {
+		return utils.NewError(utils.CodeNotFound, "algo order %s not found", input.ID).With("id", input.ID).Result()
+	}
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Str("algo", input.ID).Msg("Failed to update algo order")
+		return utils.NewError(utils.CodeInvalidArgument, "%v", err).With("id", input.ID).Result()
+	}
+
+	output := algoOutput(o)
+	result := fmt.Sprintf("⏸️ Algo order %s paused at %.8g of %.8g filled", o.ID, o.Filled, o.Amount)
+	if o.Status == algo.StatusRunning {
+		result = fmt.Sprintf("▶️ Algo order %s resumed, %.8g left", o.ID, o.Remaining())
+	}
+
+	return utils.ArtifactsResult(result, output)
+}
+
+func NewAlgoCancelTool() mcp.Tool {
+	return mcp.NewTool("algo_cancel",
+		mcp.WithDescription("Cancel a TWAP/VWAP order and return its execution report. Filled slices stay filled; an open limit slice is cancelled on the next poll"),
+		utils.WithScope(utils.ScopeTrade),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Algo order id as returned by algo_create"),
+		),
+		mcp.WithOutputSchema[AlgoOutput](),
+	)
+}
+
+func AlgoCancelHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input AlgoCancelInput
+	if err := utils.BindArgs(NewAlgoCancelTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for algo cancel")
+		return err.Result()
+	}
+
+	if _, terr := callerAlgo(ctx, input.ID); terr != nil {
+		return terr.Result()
+	}
+
+	o, ok, err := algo.Cancel(input.ID, "cancelled by algo_cancel")
+	if !ok {
+		return utils.NewError(utils.CodeNotFound, "algo order %s not found", input.ID).With("id", input.ID).Result()
+	}
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Str("algo", input.ID).Msg("Failed to cancel algo order")
+		return utils.NewError(utils.CodeInvalidArgument, "%v", err).With("id", input.ID).Result()
+	}
+
+	output := algoOutput(o)
+	result := fmt.Sprintf("🛑 Algo order %s cancelled\n%s", o.ID, describeAlgoReport(output.Report))
+	return utils.ArtifactsResult(result, output)
+}
+
+// callerAlgo looks up an algo order on an account the caller may use. Orders
+// on other accounts are reported as not found.
+func callerAlgo(ctx context.Context, id string) (algo.Order, *utils.ToolError) {
+	o, ok := algo.Get(id)
+	if !ok || !accounts.Permits(ctx, o.Account) {
+		return algo.Order{}, utils.NewError(utils.CodeNotFound, "algo order %s not found", id).With("id", id)
+	}
+	return o, nil
+}
+
+// AlgoVolume sums the one-minute candle volume traded on symbol since t.
+func AlgoVolume(ctx context.Context, symbol string, since time.Time) (float64, error) {
+	from := since.Truncate(time.Minute).Unix()
+	history, err := tracing.Exchange(ctx, "market.GetHistory", symbol, func() (*market.History, error) {
+		return market.GetHistory(market.HistoryRequest{
+			Symbol:     strings.ToUpper(symbol),
+			Resolution: validResolutions[1],
+			From:       from,
+			To:         time.Now().Unix(),
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	if history == nil {
+		return 0, nil
+	}
+
+	volume := 0.0
+	for i, t := range history.Time {
+		if t >= from && i < len(history.Volume) {
+			volume += history.Volume[i]
+		}
+	}
+	return volume, nil
+}
+
+func describeAlgo(o algo.Order) string {
+	var desc string
+	switch o.Strategy {
+	case algo.TWAP:
+		desc = fmt.Sprintf("%d slices ~%s apart, ±%.0f%% randomized", o.Slices, time.Duration(o.Interval)*time.Second, o.Randomize)
+	case algo.VWAP:
+		desc = fmt.Sprintf("%.2f%% of market volume, checked every %s", o.Participation, time.Duration(o.Interval)*time.Second)
+	}
+	if o.EndAt > 0 {
+		desc += ", ends " + time.Unix(o.EndAt, 0).Format(time.RFC3339)
+	}
+	if o.LimitPrice > 0 {
+		desc += fmt.Sprintf(" | limit %.8g", o.LimitPrice)
+	}
+	return desc
+}
+
+func describeAlgoReport(r algo.Report) string {
+	desc := fmt.Sprintf("Filled %.8g (%.1f%%), %.8g left | %d slices filled, %d skipped, %d failed | %s elapsed", r.Filled, r.FilledPercent, r.Remaining, r.SlicesDone, r.Skipped, r.Failed, r.Elapsed)
+	if r.Filled > 0 {
+		desc += fmt.Sprintf("\nAvg ~%.8g vs arrival %.8g: %+.2f bps (%.2f THB)", r.AvgPrice, r.ArrivalPrice, r.SlippageBps, r.CostTHB)
+	}
+	return desc
+}
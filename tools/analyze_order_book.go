@@ -0,0 +1,274 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/tracing"
+	"gokub/utils"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+type OrderBookInput struct {
+	Symbol          string  `json:"symbol"`
+	DepthLimit      int     `json:"depth_limit"`
+	ImbalanceLevels []int   `json:"imbalance_levels"`
+	WallMultiplier  float64 `json:"wall_multiplier"`
+	ClusterPercent  float64 `json:"cluster_percent"`
+}
+
+type BookImbalance struct {
+	Levels    int     `json:"levels"`
+	BidAmount float64 `json:"bid_amount"`
+	AskAmount float64 `json:"ask_amount"`
+	BidTHB    float64 `json:"bid_thb"`
+	AskTHB    float64 `json:"ask_thb"`
+	Imbalance float64 `json:"imbalance"`
+}
+
+type BookWall struct {
+	Side        string  `json:"side"`
+	Price       float64 `json:"price"`
+	Amount      float64 `json:"amount"`
+	ValueTHB    float64 `json:"value_thb"`
+	Multiple    float64 `json:"multiple"`
+	DistanceBps float64 `json:"distance_bps"`
+}
+
+type BookCluster struct {
+	Side      string  `json:"side"`
+	PriceFrom float64 `json:"price_from"`
+	PriceTo   float64 `json:"price_to"`
+	Amount    float64 `json:"amount"`
+	ValueTHB  float64 `json:"value_thb"`
+	Levels    int     `json:"levels"`
+}
+
+type DepthPoint struct {
+	Price       float64 `json:"price"`
+	DistanceBps float64 `json:"distance_bps"`
+	CumAmount   float64 `json:"cum_amount"`
+	CumTHB      float64 `json:"cum_thb"`
+}
+
+type OrderBookAnalysis struct {
+	Symbol        string           `json:"symbol"`
+	BestBid       float64          `json:"best_bid"`
+	BestAsk       float64          `json:"best_ask"`
+	Mid           float64          `json:"mid"`
+	SpreadBps     float64          `json:"spread_bps"`
+	Microprice    float64          `json:"microprice"`
+	MicropriceBps float64          `json:"microprice_offset_bps"`
+	Imbalance     []*BookImbalance `json:"imbalance"`
+	Walls         []*BookWall      `json:"walls"`
+	Clusters      []*BookCluster   `json:"clusters"`
+	BidCurve      []*DepthPoint    `json:"bid_curve"`
+	AskCurve      []*DepthPoint    `json:"ask_curve"`
+}
+
+func NewAnalyzeOrderBookTool() mcp.Tool {
+	return mcp.NewTool("analyze_order_book",
+		mcp.WithDescription("Analyze the order book: bid/ask imbalance at several depths, microprice, large resting walls, price clusters and cumulative depth curves"),
+		withSymbolArg(),
+		utils.WithInteger("depth_limit",
+			mcp.DefaultNumber(100),
+			mcp.Min(1),
+			mcp.Max(1000),
+			mcp.Description("Order book levels to fetch per side (default: 100)"),
+		),
+		mcp.WithArray("imbalance_levels",
+			mcp.Description("Book depths, in levels, to compute imbalance at (default: [5, 10, 20])"),
+			mcp.Items(map[string]any{"type": "integer", "minimum": 1}),
+			mcp.MinItems(1),
+			mcp.DefaultArray([]int{5, 10, 20}),
+		),
+		mcp.WithNumber("wall_multiplier",
+			mcp.DefaultNumber(3),
+			utils.ExclusiveMin(1),
+			mcp.Description("A level is a wall when its size is at least this multiple of the median level size (default: 3)"),
+		),
+		mcp.WithNumber("cluster_percent",
+			mcp.DefaultNumber(0.25),
+			utils.ExclusiveMin(0),
+			mcp.Max(10),
+			mcp.Description("Width of the price buckets used to find clusters, as a percentage of mid (default: 0.25)"),
+		),
+		mcp.WithOutputSchema[OrderBookAnalysis](),
+	)
+}
+
+func AnalyzeOrderBookHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input OrderBookInput
+	if err := utils.BindArgs(NewAnalyzeOrderBookTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for analyze order book")
+		return err.Result()
+	}
+
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+	symbol := pair.Symbol
+
+	log.Debug().Ctx(ctx).Str("symbol", symbol).Int("limit", input.DepthLimit).Msg("Analyzing order book")
+
+	depth, err := tracing.Exchange(ctx, "market.GetDepth", symbol, func() (*market.Depth, error) {
+		return market.GetDepth(symbol, input.DepthLimit)
+	})
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", symbol).Msg("Failed to get market depth for analysis")
+		return utils.ExchangeErrorResult(err)
+	}
+
+	bids, asks := depthLevels(depth.Bids), depthLevels(depth.Asks)
+	if len(bids) == 0 || len(asks) == 0 {
+		return utils.NewError(utils.CodeInsufficientData, "order book for %s is empty", symbol).With("symbol", symbol).Result()
+	}
+
+	bestBid, bestAsk := bids[0], asks[0]
+	mid := (bestBid.Price + bestAsk.Price) / 2
+	microprice := (bestBid.Price*bestAsk.Amount + bestAsk.Price*bestBid.Amount) / (bestBid.Amount + bestAsk.Amount)
+
+	output := OrderBookAnalysis{
+		Symbol:        symbol,
+		BestBid:       bestBid.Price,
+		BestAsk:       bestAsk.Price,
+		Mid:           utils.Round(mid),
+		SpreadBps:     utils.Round((bestAsk.Price-bestBid.Price)/mid*10000, 2),
+		Microprice:    utils.Round(microprice),
+		MicropriceBps: utils.Round((microprice-mid)/mid*10000, 2),
+		Imbalance:     bookImbalance(bids, asks, input.ImbalanceLevels),
+		Walls:         append(findWalls("bid", bids, mid, input.WallMultiplier), findWalls("ask", asks, mid, input.WallMultiplier)...),
+		Clusters:      append(findClusters("bid", bids, mid, input.ClusterPercent), findClusters("ask", asks, mid, input.ClusterPercent)...),
+		BidCurve:      depthCurve(bids, mid),
+		AskCurve:      depthCurve(asks, mid),
+	}
+
+	result := fmt.Sprintf("📚 %s Order Book (%d bids / %d asks)\n", strings.ToUpper(symbol), len(bids), len(asks))
+	result += fmt.Sprintf("Bid %.8g | Ask %.8g | Spread %.2f bps\n", output.BestBid, output.BestAsk, output.SpreadBps)
+	result += fmt.Sprintf("Mid %.8g | Microprice %.8g (%+.2f bps)\n", output.Mid, output.Microprice, output.MicropriceBps)
+	result += "Imbalance:"
+	for _, im := range output.Imbalance {
+		result += fmt.Sprintf(" L%d %+.2f", im.Levels, im.Imbalance)
+	}
+	result += "\n"
+	for _, w := range output.Walls {
+		result += fmt.Sprintf("Wall: %s %.8g x %.8g (%.0f THB, %.1fx median, %.1f bps from mid)\n", strings.ToUpper(w.Side), w.Price, w.Amount, w.ValueTHB, w.Multiple, w.DistanceBps)
+	}
+	for _, c := range output.Clusters {
+		result += fmt.Sprintf("Cluster: %s %.8g-%.8g %.0f THB over %d levels\n", strings.ToUpper(c.Side), c.PriceFrom, c.PriceTo, c.ValueTHB, c.Levels)
+	}
+
+	return utils.ArtifactsResult(strings.TrimSuffix(result, "\n"), output)
+}
+
+// bookImbalance is (bid - ask) / (bid + ask) by THB value over the top n
+// levels: +1 means only bids, -1 only asks.
+func bookImbalance(bids, asks []*DepthLevel, depths []int) []*BookImbalance {
+	out := make([]*BookImbalance, 0, len(depths))
+	for _, n := range depths {
+		im := &BookImbalance{Levels: n}
+		for i := 0; i < n && i < len(bids); i++ {
+			im.BidAmount += bids[i].Amount
+			im.BidTHB += bids[i].Amount * bids[i].Price
+		}
+		for i := 0; i < n && i < len(asks); i++ {
+			im.AskAmount += asks[i].Amount
+			im.AskTHB += asks[i].Amount * asks[i].Price
+		}
+		if total := im.BidTHB + im.AskTHB; total > 0 {
+			im.Imbalance = utils.Round((im.BidTHB-im.AskTHB)/total, 4)
+		}
+		im.BidAmount, im.AskAmount = utils.Round(im.BidAmount), utils.Round(im.AskAmount)
+		im.BidTHB, im.AskTHB = utils.Round(im.BidTHB, 2), utils.Round(im.AskTHB, 2)
+		out = append(out, im)
+	}
+	return out
+}
+
+func findWalls(side string, levels []*DepthLevel, mid, multiplier float64) []*BookWall {
+	sizes := make([]float64, len(levels))
+	for i, l := range levels {
+		sizes[i] = l.Amount
+	}
+	sort.Float64s(sizes)
+	median := sizes[len(sizes)/2]
+
+	walls := []*BookWall{}
+	if median <= 0 {
+		return walls
+	}
+	for _, l := range levels {
+		if l.Amount < median*multiplier {
+			continue
+		}
+		walls = append(walls, &BookWall{
+			Side:        side,
+			Price:       l.Price,
+			Amount:      l.Amount,
+			ValueTHB:    utils.Round(l.Price*l.Amount, 2),
+			Multiple:    utils.Round(l.Amount/median, 2),
+			DistanceBps: utils.Round(math.Abs(l.Price-mid)/mid*10000, 2),
+		})
+	}
+	return walls
+}
+
+// findClusters buckets levels into bands of bandPercent of mid, measured
+// away from mid, and returns the three heaviest bands by THB value.
+func findClusters(side string, levels []*DepthLevel, mid, bandPercent float64) []*BookCluster {
+	width := mid * bandPercent / 100
+	buckets := map[int]*BookCluster{}
+
+	for _, l := range levels {
+		idx := int(math.Abs(l.Price-mid) / width)
+		c, ok := buckets[idx]
+		if !ok {
+			c = &BookCluster{Side: side, PriceFrom: l.Price, PriceTo: l.Price}
+			buckets[idx] = c
+		}
+		c.PriceFrom = math.Min(c.PriceFrom, l.Price)
+		c.PriceTo = math.Max(c.PriceTo, l.Price)
+		c.Amount += l.Amount
+		c.ValueTHB += l.Amount * l.Price
+		c.Levels++
+	}
+
+	clusters := make([]*BookCluster, 0, len(buckets))
+	for _, c := range buckets {
+		if c.Levels < 2 {
+			continue
+		}
+		c.Amount = utils.Round(c.Amount)
+		c.ValueTHB = utils.Round(c.ValueTHB, 2)
+		clusters = append(clusters, c)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].ValueTHB > clusters[j].ValueTHB
+	})
+	if len(clusters) > 3 {
+		clusters = clusters[:3]
+	}
+	return clusters
+}
+
+func depthCurve(levels []*DepthLevel, mid float64) []*DepthPoint {
+	curve := make([]*DepthPoint, 0, len(levels))
+	cumAmount, cumTHB := 0.0, 0.0
+	for _, l := range levels {
+		cumAmount += l.Amount
+		cumTHB += l.Amount * l.Price
+		curve = append(curve, &DepthPoint{
+			Price:       l.Price,
+			DistanceBps: utils.Round(math.Abs(l.Price-mid)/mid*10000, 2),
+			CumAmount:   utils.Round(cumAmount),
+			CumTHB:      utils.Round(cumTHB, 2),
+		})
+	}
+	return curve
+}
@@ -0,0 +1,297 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/accounts"
+	"gokub/conditional"
+	"gokub/orders"
+	"gokub/tracing"
+	"gokub/utils"
+	"strings"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	entryAuto   = "auto"
+	entryLimit  = "limit"
+	entryStop   = "stop"
+	entryMarket = "market"
+)
+
+// BracketSignal is the part of a detect_breakout_signal or
+// detect_pullback_signal result a bracket needs.
+type BracketSignal struct {
+	Signal         string  `json:"signal"`
+	SuggestedEntry float64 `json:"suggested_entry"`
+	SuggestedStop  float64 `json:"suggested_stop"`
+}
+
+type BracketOrderInput struct {
+	Symbol      string         `json:"symbol"`
+	Signal      *BracketSignal `json:"signal"`
+	Entry       float64        `json:"entry"`
+	Stop        float64        `json:"stop"`
+	RiskPercent float64        `json:"risk_percent"`
+	Balance     float64        `json:"balance"`
+	EntryType   string         `json:"entry_type"`
+	MakerFee    float64        `json:"maker_fee"`
+	TakerFee    float64        `json:"taker_fee"`
+	Note        string         `json:"note"`
+	AccountInput
+}
+
+type BracketOrderOutput struct {
+	Position  PositionSizeOutput  `json:"position"`
+	EntryType string              `json:"entry_type"`
+	LastPrice float64             `json:"last_price"`
+	Orders    []conditional.Order `json:"orders"`
+}
+
+func NewPlaceBracketOrderTool() mcp.Tool {
+	return mcp.NewTool("place_bracket_order",
+		mcp.WithDescription("Turn a long signal into a managed trade in one call: sizes the position with the calculate_position_size math so hitting the stop loses risk_percent of the balance, "+
+			"places the entry, and once it fills arms the protective stop and the 2R take-profit as an OCO pair. Pass the output of detect_breakout_signal or detect_pullback_signal as signal, or entry and stop directly"),
+		utils.WithTrading(),
+		withSymbolArg(),
+		mcp.WithObject("signal",
+			mcp.Description("Result of detect_breakout_signal or detect_pullback_signal; its suggested_entry and suggested_stop are used unless entry or stop are given"),
+			mcp.Properties(map[string]any{
+				"signal":          map[string]any{"type": "string"},
+				"suggested_entry": map[string]any{"type": "number", "exclusiveMinimum": 0},
+				"suggested_stop":  map[string]any{"type": "number", "exclusiveMinimum": 0},
+			}),
+		),
+		mcp.WithNumber("entry",
+			utils.ExclusiveMin(0),
+			mcp.Description("Entry price"),
+		),
+		mcp.WithNumber("stop",
+			utils.ExclusiveMin(0),
+			mcp.Description("Stop-loss price, below entry"),
+		),
+		mcp.WithNumber("risk_percent",
+			mcp.Required(),
+			utils.ExclusiveMin(0),
+			mcp.Max(100),
+			mcp.Description("Percent of the balance lost if the stop is hit (e.g. 1 for 1%)"),
+		),
+		mcp.WithNumber("balance",
+			utils.ExclusiveMin(0),
+			mcp.Description("THB balance to size against; defaults to the account's available THB"),
+		),
+		mcp.WithString("entry_type",
+			mcp.DefaultString(entryAuto),
+			mcp.Enum(entryAuto, entryLimit, entryStop, entryMarket),
+			mcp.Description("limit: resting buy at entry; stop: server-held buy stop that fires when price rises to entry; market: buy now and size from the last price; "+
+				"auto: limit when entry is below the last price, stop otherwise (default: auto)"),
+		),
+		mcp.WithNumber("maker_fee",
+			mcp.DefaultNumber(0.25),
+			mcp.Min(0),
+			mcp.Description("Maker fee percentage (default: 0.25%)"),
+		),
+		mcp.WithNumber("taker_fee",
+			mcp.DefaultNumber(0.25),
+			mcp.Min(0),
+			mcp.Description("Taker fee percentage (default: 0.25%)"),
+		),
+		mcp.WithString("note",
+			mcp.MaxLength(200),
+			mcp.Description("Free text kept with the orders"),
+		),
+		withAccountArg(),
+		mcp.WithOutputSchema[BracketOrderOutput](),
+	)
+}
+
+func PlaceBracketOrderHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input BracketOrderInput
+	if err := utils.BindArgs(NewPlaceBracketOrderTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for place bracket order")
+		return err.Result()
+	}
+
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+
+	entry, stop := input.Entry, input.Stop
+	if s := input.Signal; s != nil {
+		if s.Signal != "" && !strings.HasSuffix(s.Signal, "_BUY") {
+			return utils.InvalidField("signal", fmt.Sprintf("is %s, there is no trade to place", s.Signal)).Result()
+		}
+		if entry == 0 {
+			entry = s.SuggestedEntry
+		}
+		if stop == 0 {
+			stop = s.SuggestedStop
+		}
+	}
+	if entry == 0 {
+		return utils.InvalidField("entry", "is required when signal has no suggested_entry").Result()
+	}
+	if stop == 0 {
+		return utils.InvalidField("stop", "is required when signal has no suggested_stop").Result()
+	}
+
+	account, err := accounts.Resolve(ctx, input.Account)
+	if err != nil {
+		return utils.ExchangeErrorResult(err)
+	}
+
+	balance := input.Balance
+	if balance == 0 {
+		var balances map[string]market.Balance
+		err := accounts.Use(account, func() (err error) {
+			balances, err = tracing.Exchange(ctx, "market.GetBalances", "", market.GetBalances)
+			return err
+		})
+		if err != nil {
+			log.Warn().Ctx(ctx).Err(err).Msg("Failed to read balance for bracket order")
+			return utils.ExchangeErrorResult(err)
+		}
+		for currency, b := range balances {
+			if strings.EqualFold(currency, "THB") {
+				balance = b.Available
+			}
+		}
+		if balance <= 0 {
+			return utils.NewError(utils.CodeInsufficientBalance, "no available THB on %s to size the position", account).Result()
+		}
+	}
+
+	ticker, err := alertTicker(ctx, pair.Symbol)
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", pair.Symbol).Msg("Failed to get price for bracket order")
+		return utils.ExchangeErrorResult(err)
+	}
+	last := ticker.Last
+
+	entryType := input.EntryType
+	if entryType == entryAuto {
+		entryType = entryStop
+		if entry < last {
+			entryType = entryLimit
+		}
+	}
+	if entryType == entryMarket {
+		entry = last
+	}
+	if entryType == entryStop && entry <= last {
+		return utils.InvalidField("entry", fmt.Sprintf("a buy stop at %.8g would fire at once, the last price is %.8g; use entry_type limit", entry, last)).With("last_price", last).Result()
+	}
+	if stop >= last && entryType != entryStop {
+		return utils.InvalidField("stop", fmt.Sprintf("%.8g is at or above the last price %.8g", stop, last)).With("last_price", last).Result()
+	}
+
+	entryFee := input.MakerFee
+	if entryType != entryLimit {
+		entryFee = input.TakerFee
+	}
+	position, terr := positionSize(pair, balance, input.RiskPercent, entry, stop, input.MakerFee, input.TakerFee)
+	if terr != nil {
+		return terr.Result()
+	}
+	position.Symbol = pair.Symbol
+	if terr := pair.CheckOrder(orders.Buy, position.Entry, position.Qty); terr != nil {
+		return terr.Result()
+	}
+	if cost := position.Qty * position.Entry * (1 + entryFee/100); cost > balance {
+		return utils.NewError(utils.CodeInsufficientBalance, "position needs %.2f THB but only %.2f THB is available", cost, balance).Result()
+	}
+
+	// The coin received is net of the entry fee, so the exits sell slightly less.
+	exitAmount := position.Qty * (1 - entryFee/100)
+	exits, terr := ocoLegs(pair, orders.Sell, exitAmount, position.TakeProfit2R, position.Stop, 0, 0, input.Note)
+	if terr != nil {
+		return terr.Result()
+	}
+
+	createdBy := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		createdBy = session.SessionID()
+	}
+	for i := range exits {
+		exits[i].Account, exits[i].CreatedBy = account, createdBy
+	}
+
+	entryOrder := conditional.Order{
+		Symbol:       pair.Symbol,
+		Side:         orders.Buy,
+		Amount:       position.Qty,
+		TriggerPrice: position.Entry,
+		Account:      account,
+		Note:         input.Note,
+		CreatedBy:    createdBy,
+		LastPrice:    last,
+	}
+
+	log.Info().Ctx(ctx).Str("symbol", pair.Symbol).Str("entry_type", entryType).Float64("entry", position.Entry).Float64("stop", position.Stop).Float64("qty", position.Qty).Msg("Placing bracket order")
+
+	var placed *orders.Order
+	switch entryType {
+	case entryStop:
+		entryOrder.Type = conditional.StopMarket
+	case entryLimit, entryMarket:
+		req := orders.Request{Symbol: pair.Symbol, Side: orders.Buy, Type: orders.Limit, Rate: position.Entry, Amount: position.Qty}
+		entryOrder.Type, entryOrder.LimitPrice = conditional.Limit, position.Entry
+		if entryType == entryMarket {
+			req.Type = orders.Market
+			entryOrder.Type, entryOrder.LimitPrice = orders.Market, 0
+		}
+		placed, err = orders.Live(account).Place(ctx, req)
+		if err != nil {
+			log.Warn().Ctx(ctx).Err(err).Str("symbol", pair.Symbol).Msg("Failed to place bracket entry")
+			return utils.ExchangeErrorResult(err)
+		}
+		// Even a market entry stays open until its fill is read back, so
+		// the exits are sized to what it bought.
+		entryOrder.OrderID, entryOrder.OrderType = placed.ID, req.Type
+	}
+
+	added, err := conditional.AddBracket(entryOrder, exits...)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Msg("Failed to save bracket order")
+		if placed != nil && placed.Type == orders.Limit {
+			if cerr := orders.Live(account).Cancel(ctx, pair.Symbol, placed.ID, orders.Buy); cerr != nil {
+				log.Error().Ctx(ctx).Err(cerr).Str("order", placed.ID).Msg("Failed to cancel untracked bracket entry")
+			}
+		}
+		return utils.NewError(utils.CodeInternal, "failed to save bracket order: %v", err).Result()
+	}
+
+	output := BracketOrderOutput{Position: position, EntryType: entryType, LastPrice: last, Orders: added}
+
+	result := fmt.Sprintf("🧷 Bracket on %s %s: %s entry %.8g x %.8g (%.2f THB)\n", account, strings.ToUpper(pair.Symbol), entryType, position.Entry, position.Qty, position.PositionValueTHB)
+	result += fmt.Sprintf("Stop %.8g | Target (2R) %.8g | Risk %.2f THB (%.2f%% of %.2f THB)\n", position.Stop, position.TakeProfit2R, position.ActualRiskTHB, position.RiskPercent, position.Balance)
+	switch {
+	case entryType == entryMarket:
+		result += "Market entry " + added[0].OrderID + " sent; stop and target arm for the amount it fills once the fill is confirmed\n"
+	case added[0].Status == conditional.StatusOpen:
+		result += "Entry order " + added[0].OrderID + " is resting; stop and target arm when it fills\n"
+	default:
+		result += fmt.Sprintf("Buy stop armed at %.8g (last %.8g); stop and target arm when it fills\n", position.Entry, last)
+	}
+	result += describeConditionalOrders(added)
+	for _, w := range position.Warnings {
+		result += "⚠️ " + w + "\n"
+	}
+
+	return utils.ArtifactsResult(strings.TrimSuffix(result, "\n"), output)
+}
+
+func (o BracketOrderOutput) OrderIDs() []string {
+	ids := []string{}
+	for _, c := range o.Orders {
+		if c.OrderID != "" {
+			ids = append(ids, c.OrderID)
+		}
+	}
+	return ids
+}
@@ -11,9 +11,16 @@ import (
 )
 
 type OHLCData struct {
-	High  float64 `json:"high"`
-	Low   float64 `json:"low"`
-	Close float64 `json:"close"`
+	Open   float64 `json:"open,omitempty"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume float64 `json:"volume,omitempty"`
+}
+
+type ATRInput struct {
+	Candles []OHLCData `json:"candles"`
+	Period  int        `json:"period"`
 }
 
 type ATRResult struct {
@@ -30,85 +37,30 @@ func NewCalculateATRTool() mcp.Tool {
 		mcp.WithArray("candles",
 			mcp.Required(),
 			mcp.Description("Array of OHLC objects with high, low, close properties"),
+			withCandleItems(),
 		),
-		mcp.WithNumber("period",
-			mcp.Required(),
+		utils.WithInteger("period",
 			mcp.DefaultNumber(14),
+			mcp.Min(1),
 			mcp.Description("ATR period (default: 14)"),
 		),
+		mcp.WithOutputSchema[ATRResult](),
 	)
 }
 
 func CalculateATRHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for calculate ATR")
-		return utils.ErrorResult("invalid arguments")
-	}
-
-	candlesRaw, ok := args["candles"].([]any)
-	if !ok {
-		return utils.ErrorResult("candles must be an array")
-	}
-
-	candles := make([]OHLCData, 0, len(candlesRaw))
-	for _, c := range candlesRaw {
-		candleMap, ok := c.(map[string]any)
-		if !ok {
-			continue
-		}
-
-		high, okH := candleMap["high"].(float64)
-		low, okL := candleMap["low"].(float64)
-		close, okC := candleMap["close"].(float64)
-
-		if !okH || !okL || !okC {
-			var h, l, cl float64
-			if hi, ok := candleMap["high"].(int); ok {
-				h = float64(hi)
-			}
-			if lo, ok := candleMap["low"].(int); ok {
-				l = float64(lo)
-			}
-			if cls, ok := candleMap["close"].(int); ok {
-				cl = float64(cls)
-			}
-			if h > 0 && l > 0 && cl > 0 {
-				high, low, close = h, l, cl
-			} else {
-				continue
-			}
-		}
-
-		candles = append(candles, OHLCData{
-			High:  high,
-			Low:   low,
-			Close: close,
-		})
-	}
-
-	period := utils.GetIntArg(args, "period", 14)
-	if period < 1 {
-		return utils.ErrorResult("period must be greater than 0")
+	var input ATRInput
+	if err := utils.BindArgs(NewCalculateATRTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for calculate ATR")
+		return err.Result()
 	}
+	candles, period := input.Candles, input.Period
 
 	if len(candles) < period+1 {
-		return utils.ErrorResult(fmt.Sprintf("not enough data: need at least %d candles", period+1))
-	}
-
-	trueRanges := make([]float64, len(candles))
-	for i := range candles {
-		if i == 0 {
-			trueRanges[i] = candles[i].High - candles[i].Low
-		} else {
-			highLow := candles[i].High - candles[i].Low
-			highClose := math.Abs(candles[i].High - candles[i-1].Close)
-			lowClose := math.Abs(candles[i].Low - candles[i-1].Close)
-			trueRanges[i] = math.Max(highLow, math.Max(highClose, lowClose))
-		}
+		return utils.NewError(utils.CodeInsufficientData, "not enough data: need at least %d candles", period+1).Result()
 	}
 
-	atr := calculateATR(trueRanges, period)
+	atr := calculateATR(trueRanges(candles), period)
 	currentPrice := candles[len(candles)-1].Close
 	atrPercent := (atr / currentPrice) * 100
 
@@ -127,6 +79,38 @@ func CalculateATRHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp
 	return utils.ArtifactsResult(summary, result)
 }
 
+func withCandleItems() mcp.PropertyOption {
+	price := map[string]any{"type": "number", "exclusiveMinimum": 0}
+	return mcp.Items(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"open":   price,
+			"high":   price,
+			"low":    price,
+			"close":  price,
+			"volume": map[string]any{"type": "number", "minimum": 0},
+		},
+		"required": []string{"high", "low", "close"},
+	})
+}
+
+// trueRanges returns each candle's true range; the first has no previous
+// close and uses its high-low range.
+func trueRanges(candles []OHLCData) []float64 {
+	ranges := make([]float64, len(candles))
+	for i := range candles {
+		if i == 0 {
+			ranges[i] = candles[i].High - candles[i].Low
+		} else {
+			highLow := candles[i].High - candles[i].Low
+			highClose := math.Abs(candles[i].High - candles[i-1].Close)
+			lowClose := math.Abs(candles[i].Low - candles[i-1].Close)
+			ranges[i] = math.Max(highLow, math.Max(highClose, lowClose))
+		}
+	}
+	return ranges
+}
+
 func calculateATR(trueRanges []float64, period int) float64 {
 	sum := 0.0
 	for i := 1; i <= period; i++ {
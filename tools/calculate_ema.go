@@ -9,6 +9,11 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+type EMAInput struct {
+	Prices []float64 `json:"prices"`
+	Period int       `json:"period"`
+}
+
 type EMAResult struct {
 	Period     int       `json:"period"`
 	DataPoints int       `json:"data_points"`
@@ -24,45 +29,27 @@ func NewCalculateEMATool() mcp.Tool {
 		mcp.WithArray("prices",
 			mcp.Required(),
 			mcp.Description("Array of price values (close prices) for EMA calculation"),
+			mcp.WithNumberItems(mcp.Min(0)),
 		),
-		mcp.WithNumber("period",
+		utils.WithInteger("period",
 			mcp.Required(),
+			mcp.Min(1),
 			mcp.Description("EMA period (e.g., 9, 12, 20, 26, 50, 200)"),
 		),
+		mcp.WithOutputSchema[EMAResult](),
 	)
 }
 
 func CalculateEMAHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for calculate EMA")
-		return utils.ErrorResult("invalid arguments")
-	}
-
-	pricesRaw, ok := args["prices"].([]interface{})
-	if !ok {
-		return utils.ErrorResult("prices must be an array")
-	}
-
-	prices := make([]float64, len(pricesRaw))
-	for i, p := range pricesRaw {
-		switch v := p.(type) {
-		case float64:
-			prices[i] = v
-		case int:
-			prices[i] = float64(v)
-		default:
-			return utils.ErrorResult("prices must contain numbers only")
-		}
-	}
-
-	period := utils.GetIntArg(args, "period", 0)
-	if period < 1 {
-		return utils.ErrorResult("period must be greater than 0")
+	var input EMAInput
+	if err := utils.BindArgs(NewCalculateEMATool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for calculate EMA")
+		return err.Result()
 	}
+	prices, period := input.Prices, input.Period
 
-	if len(prices) < period {
-		return utils.ErrorResult(fmt.Sprintf("not enough data: need at least %d prices", period))
+	if len(prices) <= period {
+		return utils.NewError(utils.CodeInsufficientData, "not enough data: need at least %d prices", period+1).Result()
 	}
 
 	emaValues := calculateEMA(prices, period)
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/tracing"
 	"gokub/utils"
 	"strings"
 
@@ -24,50 +25,63 @@ type LiquidityDepthOutput struct {
 	TotalLiquidity float64 `json:"total_liquidity"`
 }
 
+type LiquidityDepthInput struct {
+	Symbol       string  `json:"symbol"`
+	RangePercent float64 `json:"range_percent"`
+}
+
 func NewCalculateLiquidityDepthTool() mcp.Tool {
 	return mcp.NewTool("calculate_liquidity_depth",
 		mcp.WithDescription("Calculate total bid/ask liquidity value (THB) within a percentage range from mid price"),
-		mcp.WithString("symbol",
-			mcp.Required(),
-			mcp.Description("Trading pair symbol (e.g., btc_thb, eth_thb)"),
-		),
+		withSymbolArg(),
 		mcp.WithNumber("range_percent",
 			mcp.Description("Percentage range from mid price (default: 1.0 = ±1%)"),
 			mcp.DefaultNumber(1.0),
+			utils.ExclusiveMin(0),
+			mcp.Max(100),
 		),
+		mcp.WithOutputSchema[LiquidityDepthOutput](),
 	)
 }
 
 func CalculateLiquidityDepthHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for calculate liquidity depth")
-		return utils.ErrorResult("invalid arguments")
+	var input LiquidityDepthInput
+	if err := utils.BindArgs(NewCalculateLiquidityDepthTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for calculate liquidity depth")
+		return err.Result()
 	}
 
-	rangePercent := utils.GetFloat64Arg(args, "range_percent", 1.0)
-	symbol := strings.ToLower(utils.GetStringArg(args, "symbol"))
+	rangePercent := input.RangePercent
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+	symbol := pair.Symbol
 
-	log.Debug().Str("symbol", symbol).Float64("range_percent", rangePercent).Msg("Calculating liquidity depth")
+	log.Debug().Ctx(ctx).Str("symbol", symbol).Float64("range_percent", rangePercent).Msg("Calculating liquidity depth")
 
-	tickers, err := market.GetTicker(symbol)
+	tickers, err := tracing.Exchange(ctx, "market.GetTicker", symbol, func() ([]market.Ticker, error) {
+		return market.GetTicker(symbol)
+	})
 	if err != nil {
-		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to get ticker for liquidity")
-		return utils.ErrorResult(fmt.Sprintf("error: %v", err))
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", symbol).Msg("Failed to get ticker for liquidity")
+		return utils.ExchangeErrorResult(err)
 	}
 
 	if len(tickers) == 0 {
-		log.Warn().Str("symbol", symbol).Msg("No ticker data found")
-		return utils.ErrorResult("no data: " + symbol)
+		log.Warn().Ctx(ctx).Str("symbol", symbol).Msg("No ticker data found")
+		return utils.NewError(utils.CodeNotFoundSymbol, "no data for symbol %s", symbol).With("symbol", symbol).Result()
 	}
 
 	ticker := tickers[0]
 	mid := (ticker.HighestBid + ticker.LowestAsk) / 2
 
-	depth, err := market.GetDepth(symbol, 100)
+	depth, err := tracing.Exchange(ctx, "market.GetDepth", symbol, func() (*market.Depth, error) {
+		return market.GetDepth(symbol, 100)
+	})
 	if err != nil {
-		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to get market depth for liquidity")
-		return utils.ErrorResult(fmt.Sprintf("error: %v", err))
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", symbol).Msg("Failed to get market depth for liquidity")
+		return utils.ExchangeErrorResult(err)
 	}
 
 	upperBound := mid * (1 + rangePercent/100)
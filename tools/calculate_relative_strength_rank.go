@@ -10,6 +10,12 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+type RSRankInput struct {
+	Symbols   map[string][]float64 `json:"symbols"`
+	Period    int                  `json:"period"`
+	Benchmark string               `json:"benchmark"`
+}
+
 type SymbolROC struct {
 	Symbol string  `json:"symbol"`
 	ROC    float64 `json:"roc"`
@@ -29,54 +35,47 @@ func NewCalculateRelativeStrengthRankTool() mcp.Tool {
 		mcp.WithObject("symbols",
 			mcp.Required(),
 			mcp.Description("Object with symbol names as keys and price arrays as values"),
+			mcp.MinProperties(1),
+			mcp.AdditionalProperties(map[string]any{
+				"type":  "array",
+				"items": map[string]any{"type": "number", "exclusiveMinimum": 0},
+			}),
 		),
-		mcp.WithNumber("period",
+		utils.WithInteger("period",
 			mcp.DefaultNumber(14),
+			mcp.Min(1),
 			mcp.Description("ROC period for calculation (default: 14)"),
 		),
 		mcp.WithString("benchmark",
+			mcp.DefaultString("btc_thb"),
 			mcp.Description("Benchmark symbol for comparison"),
 		),
+		mcp.WithOutputSchema[RSRankResult](),
 	)
 }
 
 func CalculateRelativeStrengthRankHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for calculate relative strength rank")
-		return utils.ErrorResult("invalid arguments")
+	var input RSRankInput
+	if err := utils.BindArgs(NewCalculateRelativeStrengthRankTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for calculate relative strength rank")
+		return err.Result()
 	}
+	period, benchmark := input.Period, input.Benchmark
 
-	symbolsRaw, ok := args["symbols"].(map[string]any)
-	if !ok {
-		return utils.ErrorResult("symbols must be an object with symbol:prices pairs")
+	symbols := make([]string, 0, len(input.Symbols))
+	for symbol := range input.Symbols {
+		symbols = append(symbols, symbol)
 	}
+	sort.Strings(symbols)
 
-	period := utils.GetIntArg(args, "period", 14)
-	benchmark := utils.GetStringArg(args, "benchmark", "btc_thb")
-
-	rocList := make([]*SymbolROC, 0, len(symbolsRaw))
-
-	for symbol, pricesRaw := range symbolsRaw {
-		pricesArr, ok := pricesRaw.([]any)
-		if !ok {
-			continue
-		}
-
-		prices := make([]float64, len(pricesArr))
-		for i, p := range pricesArr {
-			switch v := p.(type) {
-			case float64:
-				prices[i] = v
-			case int:
-				prices[i] = float64(v)
-			default:
-				continue
-			}
-		}
+	rocList := make([]*SymbolROC, 0, len(symbols))
 
+	for _, symbol := range symbols {
+		prices := input.Symbols[symbol]
 		if len(prices) <= period {
-			continue
+			return utils.NewError(utils.CodeInsufficientData, "symbols.%s: need at least %d prices", symbol, period+1).
+				With("symbol", symbol).
+				Result()
 		}
 
 		priceNow := prices[len(prices)-1]
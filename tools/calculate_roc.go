@@ -9,6 +9,11 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+type ROCInput struct {
+	Prices []float64 `json:"prices"`
+	Period int       `json:"period"`
+}
+
 type ROCResult struct {
 	Period     int     `json:"period"`
 	DataPoints int     `json:"data_points"`
@@ -23,45 +28,27 @@ func NewCalculateROCTool() mcp.Tool {
 		mcp.WithArray("prices",
 			mcp.Required(),
 			mcp.Description("Array of price values (close prices) for ROC calculation"),
+			mcp.WithNumberItems(utils.ExclusiveMin(0)),
 		),
-		mcp.WithNumber("period",
-			mcp.Required(),
+		utils.WithInteger("period",
+			mcp.DefaultNumber(14),
+			mcp.Min(1),
 			mcp.Description("ROC period (default: 14 for 14-day rate of change)"),
 		),
+		mcp.WithOutputSchema[ROCResult](),
 	)
 }
 
 func CalculateROCHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for calculate ROC")
-		return utils.ErrorResult("invalid arguments")
-	}
-
-	pricesRaw, ok := args["prices"].([]interface{})
-	if !ok {
-		return utils.ErrorResult("prices must be an array")
-	}
-
-	prices := make([]float64, len(pricesRaw))
-	for i, p := range pricesRaw {
-		switch v := p.(type) {
-		case float64:
-			prices[i] = v
-		case int:
-			prices[i] = float64(v)
-		default:
-			return utils.ErrorResult("prices must contain numbers only")
-		}
-	}
-
-	period := utils.GetIntArg(args, "period", 14)
-	if period < 1 {
-		return utils.ErrorResult("period must be greater than 0")
+	var input ROCInput
+	if err := utils.BindArgs(NewCalculateROCTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for calculate ROC")
+		return err.Result()
 	}
+	prices, period := input.Prices, input.Period
 
 	if len(prices) <= period {
-		return utils.ErrorResult(fmt.Sprintf("not enough data: need at least %d prices", period+1))
+		return utils.NewError(utils.CodeInsufficientData, "not enough data: need at least %d prices", period+1).Result()
 	}
 
 	priceNow := prices[len(prices)-1]
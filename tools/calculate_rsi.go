@@ -9,6 +9,11 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+type RSIInput struct {
+	Prices []float64 `json:"prices"`
+	Period int       `json:"period"`
+}
+
 type RSIResult struct {
 	Period     int     `json:"period"`
 	DataPoints int     `json:"data_points"`
@@ -22,46 +27,27 @@ func NewCalculateRSITool() mcp.Tool {
 		mcp.WithArray("prices",
 			mcp.Required(),
 			mcp.Description("Array of price values (close prices) for RSI calculation"),
+			mcp.WithNumberItems(mcp.Min(0)),
 		),
-		mcp.WithNumber("period",
-			mcp.Required(),
+		utils.WithInteger("period",
 			mcp.DefaultNumber(14),
+			mcp.Min(1),
 			mcp.Description("RSI period (default: 14)"),
 		),
+		mcp.WithOutputSchema[RSIResult](),
 	)
 }
 
 func CalculateRSIHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for calculate RSI")
-		return utils.ErrorResult("invalid arguments")
-	}
-
-	pricesRaw, ok := args["prices"].([]interface{})
-	if !ok {
-		return utils.ErrorResult("prices must be an array")
-	}
-
-	prices := make([]float64, len(pricesRaw))
-	for i, p := range pricesRaw {
-		switch v := p.(type) {
-		case float64:
-			prices[i] = v
-		case int:
-			prices[i] = float64(v)
-		default:
-			return utils.ErrorResult("prices must contain numbers only")
-		}
-	}
-
-	period := utils.GetIntArg(args, "period", 14)
-	if period < 1 {
-		return utils.ErrorResult("period must be greater than 0")
+	var input RSIInput
+	if err := utils.BindArgs(NewCalculateRSITool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for calculate RSI")
+		return err.Result()
 	}
+	prices, period := input.Prices, input.Period
 
 	if len(prices) < period+1 {
-		return utils.ErrorResult(fmt.Sprintf("not enough data: need at least %d prices", period+1))
+		return utils.NewError(utils.CodeInsufficientData, "not enough data: need at least %d prices", period+1).Result()
 	}
 
 	rsi := calculateRSI(prices, period)
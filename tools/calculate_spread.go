@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/tracing"
 	"gokub/utils"
 	"strings"
 
@@ -20,35 +21,43 @@ type SpreadOutput struct {
 	SpreadPercent float64 `json:"spread_percent"`
 }
 
+type SpreadInput struct {
+	Symbol string `json:"symbol"`
+}
+
 func NewCalculateSpreadTool() mcp.Tool {
 	return mcp.NewTool("calculate_spread",
 		mcp.WithDescription("Calculate bid-ask spread percentage and mid price for a symbol"),
-		mcp.WithString("symbol",
-			mcp.Required(),
-			mcp.Description("Trading pair symbol (e.g., btc_thb, eth_thb)"),
-		),
+		withSymbolArg(),
+		mcp.WithOutputSchema[SpreadOutput](),
 	)
 }
 
 func CalculateSpreadHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for calculate spread")
-		return utils.ErrorResult("invalid arguments")
+	var input SpreadInput
+	if err := utils.BindArgs(NewCalculateSpreadTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for calculate spread")
+		return err.Result()
 	}
 
-	symbol := strings.ToLower(utils.GetStringArg(args, "symbol"))
-	log.Debug().Str("symbol", symbol).Msg("Calculating spread")
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+	symbol := pair.Symbol
+	log.Debug().Ctx(ctx).Str("symbol", symbol).Msg("Calculating spread")
 
-	tickers, err := market.GetTicker(symbol)
+	tickers, err := tracing.Exchange(ctx, "market.GetTicker", symbol, func() ([]market.Ticker, error) {
+		return market.GetTicker(symbol)
+	})
 	if err != nil {
-		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to get ticker for spread")
-		return utils.ErrorResult(fmt.Sprintf("error: %v", err))
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", symbol).Msg("Failed to get ticker for spread")
+		return utils.ExchangeErrorResult(err)
 	}
 
 	if len(tickers) == 0 {
-		log.Warn().Str("symbol", symbol).Msg("No ticker data found")
-		return utils.ErrorResult("no data: " + symbol)
+		log.Warn().Ctx(ctx).Str("symbol", symbol).Msg("No ticker data found")
+		return utils.NewError(utils.CodeNotFoundSymbol, "no data for symbol %s", symbol).With("symbol", symbol).Result()
 	}
 
 	ticker := tickers[0]
@@ -56,8 +65,8 @@ func CalculateSpreadHandler(ctx context.Context, request mcp.CallToolRequest) (*
 	ask := ticker.LowestAsk
 
 	if bid <= 0 || ask <= 0 {
-		log.Warn().Str("symbol", symbol).Msg("Invalid bid/ask prices")
-		return utils.ErrorResult("invalid bid/ask prices")
+		log.Warn().Ctx(ctx).Str("symbol", symbol).Msg("Invalid bid/ask prices")
+		return utils.ErrorResult(utils.CodeInsufficientData, "invalid bid/ask prices")
 	}
 
 	mid := (bid + ask) / 2
@@ -10,50 +10,38 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+type MarketRegimeInput struct {
+	Prices   []float64 `json:"prices"`
+	Lookback int       `json:"lookback"`
+}
+
 func NewCheckMarketRegimeTool() mcp.Tool {
 	return mcp.NewTool("check_market_regime",
 		mcp.WithDescription(`Analyze market regime (trending vs ranging) using price volatility and trend strength indicators`),
 		mcp.WithArray("prices",
 			mcp.Required(),
 			mcp.Description("Array of price values (close prices) for market regime analysis"),
+			mcp.WithNumberItems(mcp.Min(0)),
 		),
-		mcp.WithNumber("lookback",
+		utils.WithInteger("lookback",
+			mcp.DefaultNumber(20),
+			mcp.Min(5),
 			mcp.Description("Lookback period for analysis. Default: 20"),
 		),
+		mcp.WithOutputSchema[MarketRegime](),
 	)
 }
 
 func CheckMarketRegimeHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for check market regime")
-		return utils.ErrorResult("invalid arguments")
-	}
-
-	pricesRaw, ok := args["prices"].([]interface{})
-	if !ok {
-		return utils.ErrorResult("prices must be an array")
-	}
-
-	prices := make([]float64, len(pricesRaw))
-	for i, p := range pricesRaw {
-		switch v := p.(type) {
-		case float64:
-			prices[i] = v
-		case int:
-			prices[i] = float64(v)
-		default:
-			return utils.ErrorResult("prices must contain numbers only")
-		}
-	}
-
-	lookback := utils.GetIntArg(args, "lookback", 20)
-	if lookback < 5 {
-		return utils.ErrorResult("lookback must be at least 5")
+	var input MarketRegimeInput
+	if err := utils.BindArgs(NewCheckMarketRegimeTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for check market regime")
+		return err.Result()
 	}
+	prices, lookback := input.Prices, input.Lookback
 
 	if len(prices) < lookback {
-		return utils.ErrorResult(fmt.Sprintf("not enough data: need at least %d prices", lookback))
+		return utils.NewError(utils.CodeInsufficientData, "not enough data: need at least %d prices", lookback).Result()
 	}
 
 	regime := analyzeMarketRegime(prices, lookback)
@@ -0,0 +1,452 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/accounts"
+	"gokub/conditional"
+	"gokub/orders"
+	"gokub/symbols"
+	"gokub/utils"
+	"math"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+type ConditionalOrderInput struct {
+	Symbol           string  `json:"symbol"`
+	Side             string  `json:"side"`
+	Type             string  `json:"type"`
+	Amount           float64 `json:"amount"`
+	TriggerPrice     float64 `json:"trigger_price"`
+	LimitPrice       float64 `json:"limit_price"`
+	TrailPercent     float64 `json:"trail_percent"`
+	TrailATRMultiple float64 `json:"trail_atr_multiple"`
+	ATRPeriod        int     `json:"atr_period"`
+	ATRResolution    int     `json:"atr_resolution"`
+	Note             string  `json:"note"`
+	AccountInput
+}
+
+type OCOOrderInput struct {
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"`
+	Amount          float64 `json:"amount"`
+	TakeProfitPrice float64 `json:"take_profit_price"`
+	StopPrice       float64 `json:"stop_price"`
+	StopLimitPrice  float64 `json:"stop_limit_price"`
+	Note            string  `json:"note"`
+	AccountInput
+}
+
+type ListConditionalOrdersInput struct {
+	Symbol        string `json:"symbol"`
+	IncludeClosed bool   `json:"include_closed"`
+}
+
+type CancelConditionalOrderInput struct {
+	ID string `json:"id"`
+}
+
+type ConditionalOrdersOutput struct {
+	PollInterval string              `json:"poll_interval"`
+	LastPrice    float64             `json:"last_price,omitempty"`
+	Orders       []conditional.Order `json:"orders"`
+}
+
+func withConditionalSideArg(description string) mcp.ToolOption {
+	return mcp.WithString("side",
+		mcp.DefaultString(orders.Sell),
+		mcp.Enum(orders.Buy, orders.Sell),
+		mcp.Description(description),
+	)
+}
+
+func NewPlaceConditionalOrderTool() mcp.Tool {
+	return mcp.NewTool("place_conditional_order",
+		mcp.WithDescription("Hold a conditional order on the server and send a real order when the last price crosses its trigger, since Bitkub spot has no native stops. "+
+			"Types: stop_market (market order at trigger_price), stop_limit (limit order at limit_price once trigger_price is hit), take_profit (market, or limit at limit_price), "+
+			"trailing_stop (trigger follows the best price by trail_percent or trail_atr_multiple x ATR). Sell orders protect a long position; buy stops enter on strength. "+
+			"The suggested_stop from detect_breakout_signal or detect_pullback_signal can be used as trigger_price"),
+		utils.WithTrading(),
+		withSymbolArg(),
+		withConditionalSideArg("Side of the order sent when triggered (default: sell)"),
+		mcp.WithString("type",
+			mcp.Required(),
+			mcp.Enum(conditional.Types...),
+			mcp.Description("Conditional order type"),
+		),
+		mcp.WithNumber("amount",
+			mcp.Required(),
+			utils.ExclusiveMin(0),
+			mcp.Description("Coin amount to buy or sell when triggered"),
+		),
+		mcp.WithNumber("trigger_price",
+			utils.ExclusiveMin(0),
+			mcp.Description("Price that fires the order; required except for trailing_stop"),
+		),
+		mcp.WithNumber("limit_price",
+			utils.ExclusiveMin(0),
+			mcp.Description("Limit price of the order sent; required for stop_limit, optional for take_profit"),
+		),
+		mcp.WithNumber("trail_percent",
+			utils.ExclusiveMin(0),
+			mcp.Max(50),
+			mcp.Description("trailing_stop: distance from the best price in percent"),
+		),
+		mcp.WithNumber("trail_atr_multiple",
+			utils.ExclusiveMin(0),
+			mcp.Description("trailing_stop: distance from the best price as a multiple of ATR, measured when the order is created"),
+		),
+		utils.WithInteger("atr_period",
+			mcp.DefaultNumber(14),
+			mcp.Min(1),
+			mcp.Max(100),
+			mcp.Description("ATR period for trail_atr_multiple (default: 14)"),
+		),
+		utils.WithInteger("atr_resolution",
+			mcp.DefaultNumber(60),
+			utils.EnumNumbers(1, 5, 15, 60, 240, 1440),
+			mcp.Description("Candle timeframe in minutes for the ATR (default: 60)"),
+		),
+		mcp.WithString("note",
+			mcp.MaxLength(200),
+			mcp.Description("Free text kept with the order"),
+		),
+		withAccountArg(),
+		mcp.WithOutputSchema[ConditionalOrdersOutput](),
+	)
+}
+
+func PlaceConditionalOrderHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input ConditionalOrderInput
+	if err := utils.BindArgs(NewPlaceConditionalOrderTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for place conditional order")
+		return err.Result()
+	}
+
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+
+	ticker, err := alertTicker(ctx, pair.Symbol)
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", pair.Symbol).Msg("Failed to get price for conditional order")
+		return utils.ExchangeErrorResult(err)
+	}
+	last := ticker.Last
+
+	order := conditional.Order{
+		Symbol: pair.Symbol,
+		Side:   input.Side,
+		Type:   input.Type,
+		Amount: pair.SnapAmount(input.Amount),
+		Note:   input.Note,
+	}
+
+	switch input.Type {
+	case conditional.TrailingStop:
+		switch {
+		case input.TrailPercent > 0 && input.TrailATRMultiple > 0:
+			return utils.InvalidField("trail_percent", "set either trail_percent or trail_atr_multiple, not both").Result()
+		case input.TrailPercent > 0:
+			order.TrailPercent = input.TrailPercent
+		case input.TrailATRMultiple > 0:
+			atr, err := currentATR(ctx, pair.Symbol, input.ATRResolution, input.ATRPeriod)
+			if err != nil {
+				return utils.ExchangeErrorResult(err)
+			}
+			order.TrailDistance = utils.Round(atr*input.TrailATRMultiple, 8)
+			order.Note = strings.TrimSpace(fmt.Sprintf("%s trail %.2fx ATR(%d) = %.8g", order.Note, input.TrailATRMultiple, input.ATRPeriod, order.TrailDistance))
+		default:
+			return utils.InvalidField("trail_percent", "trailing_stop needs trail_percent or trail_atr_multiple").Result()
+		}
+		order.Extreme = last
+
+	default:
+		if input.TriggerPrice <= 0 {
+			return utils.InvalidField("trigger_price", "is required for "+input.Type).Result()
+		}
+		order.TriggerPrice = pair.SnapPrice(input.TriggerPrice, symbols.RoundNearest)
+		if input.Type == conditional.StopLimit && input.LimitPrice <= 0 {
+			return utils.InvalidField("limit_price", "is required for stop_limit").Result()
+		}
+		if input.LimitPrice > 0 {
+			order.LimitPrice = pair.SnapPrice(input.LimitPrice, symbols.RoundNearest)
+		}
+		if order.Crossed(last) {
+			return utils.InvalidField("trigger_price", fmt.Sprintf("%.8g is already crossed, last price is %.8g", order.TriggerPrice, last)).
+				With("last_price", last).
+				Result()
+		}
+	}
+
+	price := order.LimitPrice
+	if price == 0 {
+		price = last
+	}
+	if terr := pair.CheckOrder(order.Side, price, order.Amount); terr != nil {
+		return terr.Result()
+	}
+
+	return addConditionalOrders(ctx, input.Account, last, order)
+}
+
+func NewPlaceOCOOrderTool() mcp.Tool {
+	return mcp.NewTool("place_oco_order",
+		mcp.WithDescription("One-cancels-the-other: a take-profit and a stop-loss for the same amount, held on the server. Whichever price is crossed first sends its order and cancels the other. "+
+			"For a sell OCO take_profit_price must be above and stop_price below the last price; the reverse for buy"),
+		utils.WithTrading(),
+		withSymbolArg(),
+		withConditionalSideArg("Side of both legs: sell to exit a long position (default: sell)"),
+		mcp.WithNumber("amount",
+			mcp.Required(),
+			utils.ExclusiveMin(0),
+			mcp.Description("Coin amount for whichever leg fires"),
+		),
+		mcp.WithNumber("take_profit_price",
+			mcp.Required(),
+			utils.ExclusiveMin(0),
+			mcp.Description("Take-profit trigger; a market order is sent when crossed"),
+		),
+		mcp.WithNumber("stop_price",
+			mcp.Required(),
+			utils.ExclusiveMin(0),
+			mcp.Description("Stop-loss trigger"),
+		),
+		mcp.WithNumber("stop_limit_price",
+			utils.ExclusiveMin(0),
+			mcp.Description("Send the stop as a limit order at this price instead of a market order"),
+		),
+		mcp.WithString("note",
+			mcp.MaxLength(200),
+			mcp.Description("Free text kept with both legs"),
+		),
+		withAccountArg(),
+		mcp.WithOutputSchema[ConditionalOrdersOutput](),
+	)
+}
+
+func PlaceOCOOrderHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input OCOOrderInput
+	if err := utils.BindArgs(NewPlaceOCOOrderTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for place OCO order")
+		return err.Result()
+	}
+
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+
+	ticker, err := alertTicker(ctx, pair.Symbol)
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", pair.Symbol).Msg("Failed to get price for OCO order")
+		return utils.ExchangeErrorResult(err)
+	}
+
+	legs, terr := ocoLegs(pair, input.Side, input.Amount, input.TakeProfitPrice, input.StopPrice, input.StopLimitPrice, ticker.Last, input.Note)
+	if terr != nil {
+		return terr.Result()
+	}
+
+	return addConditionalOrders(ctx, input.Account, ticker.Last, legs...)
+}
+
+// ocoLegs builds a linked take-profit and stop-loss pair and checks that both
+// sit on the right side of the last price.
+func ocoLegs(pair *symbols.Pair, side string, amount, takeProfit, stop, stopLimit, last float64, note string) ([]conditional.Order, *utils.ToolError) {
+	amount = pair.SnapAmount(amount)
+	tp := conditional.Order{
+		Symbol:       pair.Symbol,
+		Side:         side,
+		Type:         conditional.TakeProfit,
+		Amount:       amount,
+		TriggerPrice: pair.SnapPrice(takeProfit, symbols.RoundNearest),
+		Note:         note,
+	}
+	sl := conditional.Order{
+		Symbol:       pair.Symbol,
+		Side:         side,
+		Type:         conditional.StopMarket,
+		Amount:       amount,
+		TriggerPrice: pair.SnapPrice(stop, symbols.RoundNearest),
+		Note:         note,
+	}
+	if stopLimit > 0 {
+		sl.Type, sl.LimitPrice = conditional.StopLimit, pair.SnapPrice(stopLimit, symbols.RoundNearest)
+	}
+
+	if last > 0 {
+		if tp.Crossed(last) {
+			return nil, utils.InvalidField("take_profit_price", fmt.Sprintf("%.8g is already crossed, last price is %.8g", tp.TriggerPrice, last)).With("last_price", last)
+		}
+		if sl.Crossed(last) {
+			return nil, utils.InvalidField("stop_price", fmt.Sprintf("%.8g is already crossed, last price is %.8g", sl.TriggerPrice, last)).With("last_price", last)
+		}
+	}
+	if terr := pair.CheckOrder(side, math.Min(tp.TriggerPrice, sl.TriggerPrice), amount); terr != nil {
+		return nil, terr
+	}
+
+	group, err := conditional.NewGroupID()
+	if err != nil {
+		return nil, utils.NewError(utils.CodeInternal, "%v", err)
+	}
+	tp.OCOGroup, sl.OCOGroup = group, group
+	return []conditional.Order{tp, sl}, nil
+}
+
+func addConditionalOrders(ctx context.Context, requested string, last float64, list ...conditional.Order) (*mcp.CallToolResult, error) {
+	account, err := accounts.Resolve(ctx, requested)
+	if err != nil {
+		return utils.ExchangeErrorResult(err)
+	}
+
+	createdBy := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		createdBy = session.SessionID()
+	}
+	for i := range list {
+		list[i].Account, list[i].CreatedBy, list[i].LastPrice = account, createdBy, last
+	}
+
+	added, err := conditional.Add(list...)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Msg("Failed to save conditional orders")
+		return utils.NewError(utils.CodeInternal, "failed to save conditional orders: %v", err).Result()
+	}
+
+	output := ConditionalOrdersOutput{PollInterval: conditional.Interval().String(), LastPrice: last, Orders: added}
+	result := fmt.Sprintf("🎯 %d conditional order(s) armed on %s, checked every %s (last %.8g)\n", len(added), account, conditional.Interval(), last)
+	result += describeConditionalOrders(added)
+
+	return utils.ArtifactsResult(strings.TrimSuffix(result, "\n"), output)
+}
+
+func NewListConditionalOrdersTool() mcp.Tool {
+	return mcp.NewTool("list_conditional_orders",
+		mcp.WithDescription("List the server-held conditional orders (stops, take-profits, trailing stops, OCO legs) with their current trigger and status"),
+		utils.WithScope(utils.ScopeAccount),
+		mcp.WithString("symbol",
+			mcp.Description("Only orders for this symbol"),
+			mcp.Pattern(symbolPattern),
+		),
+		mcp.WithBoolean("include_closed",
+			mcp.DefaultBool(false),
+			mcp.Description("Include triggered, filled, cancelled and failed orders (default: false)"),
+		),
+		mcp.WithOutputSchema[ConditionalOrdersOutput](),
+	)
+}
+
+func ListConditionalOrdersHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input ListConditionalOrdersInput
+	if err := utils.BindArgs(NewListConditionalOrdersTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for list conditional orders")
+		return err.Result()
+	}
+
+	symbol := ""
+	if input.Symbol != "" {
+		symbol = symbols.Normalize(input.Symbol)
+	}
+
+	list := []conditional.Order{}
+	for _, o := range conditional.List(symbol, !input.IncludeClosed) {
+		if accounts.Permits(ctx, o.Account) {
+			list = append(list, o)
+		}
+	}
+	output := ConditionalOrdersOutput{PollInterval: conditional.Interval().String(), Orders: list}
+	if len(list) == 0 {
+		return utils.ArtifactsResult("No conditional orders", output)
+	}
+
+	return utils.ArtifactsResult(strings.TrimSuffix(describeConditionalOrders(list), "\n"), output)
+}
+
+func NewCancelConditionalOrderTool() mcp.Tool {
+	return mcp.NewTool("cancel_conditional_order",
+		mcp.WithDescription("Cancel a conditional order before it triggers. Cancelling one OCO leg cancels the other; a stop-limit that already placed its limit order has that order cancelled on the exchange"),
+		utils.WithScope(utils.ScopeTrade),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Conditional order id as returned by place_conditional_order or place_oco_order"),
+		),
+		mcp.WithOutputSchema[ConditionalOrdersOutput](),
+	)
+}
+
+func CancelConditionalOrderHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input CancelConditionalOrderInput
+	if err := utils.BindArgs(NewCancelConditionalOrderTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for cancel conditional order")
+		return err.Result()
+	}
+
+	if o, ok := conditional.Get(input.ID); !ok || !accounts.Permits(ctx, o.Account) {
+		return utils.NewError(utils.CodeNotFound, "conditional order %s not found", input.ID).With("id", input.ID).Result()
+	}
+
+	cancelled, err := conditional.Cancel(ctx, input.ID, "cancelled by cancel_conditional_order")
+	if err != nil && len(cancelled) == 0 {
+		log.Warn().Ctx(ctx).Err(err).Str("conditional", input.ID).Msg("Failed to cancel conditional order")
+		return utils.ExchangeErrorResult(err)
+	}
+
+	output := ConditionalOrdersOutput{PollInterval: conditional.Interval().String(), Orders: cancelled}
+	result := fmt.Sprintf("🚫 Cancelled %d conditional order(s)\n", len(cancelled)) + describeConditionalOrders(cancelled)
+	if err != nil {
+		result += "⚠️ " + err.Error()
+	}
+
+	return utils.ArtifactsResult(strings.TrimSuffix(result, "\n"), output)
+}
+
+func describeConditionalOrders(list []conditional.Order) string {
+	result := ""
+	for _, o := range list {
+		result += fmt.Sprintf("%s [%s] %s %s %.8g %s @ trigger %.8g", o.ID, o.Status, strings.ToUpper(o.Side), strings.ReplaceAll(o.Type, "_", " "), o.Amount, strings.ToUpper(o.Symbol), o.TriggerPrice)
+		if o.LimitPrice > 0 {
+			result += fmt.Sprintf(" limit %.8g", o.LimitPrice)
+		}
+		switch {
+		case o.TrailPercent > 0:
+			result += fmt.Sprintf(" | trails %.2f%% from %.8g", o.TrailPercent, o.Extreme)
+		case o.TrailDistance > 0:
+			result += fmt.Sprintf(" | trails %.8g from %.8g", o.TrailDistance, o.Extreme)
+		}
+		if o.OCOGroup != "" {
+			result += " | OCO " + o.OCOGroup
+		}
+		if o.OrderID != "" {
+			result += " | order " + o.OrderID
+		}
+		if o.Filled > 0 {
+			result += fmt.Sprintf(" | filled %.8g @ %.8g", o.Filled, o.FillPrice)
+		}
+		if o.Reason != "" {
+			result += " | " + o.Reason
+		}
+		result += "\n"
+	}
+	return result
+}
+
+func currentATR(ctx context.Context, symbol string, resolution, period int) (float64, error) {
+	history, err := alertCandles(ctx, symbol, resolution, period*3)
+	if err != nil {
+		return 0, err
+	}
+	if len(history.Close) < period+1 {
+		return 0, utils.NewError(utils.CodeInsufficientData, "need at least %d candles for ATR(%d), got %d", period+1, period, len(history.Close))
+	}
+
+	return calculateATR(trueRanges(historyCandles(history)), period), nil
+}
@@ -0,0 +1,402 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/accounts"
+	"gokub/dca"
+	"gokub/utils"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	dcaRSIPeriod    = 14
+	dcaDrawdownDays = 30
+)
+
+type DCACreateInput struct {
+	Symbol        string  `json:"symbol"`
+	AmountTHB     float64 `json:"amount_thb"`
+	Schedule      string  `json:"schedule"`
+	DipIndicator  string  `json:"dip_indicator"`
+	DipThreshold  float64 `json:"dip_threshold"`
+	DipMultiplier float64 `json:"dip_multiplier"`
+	AccountInput
+}
+
+type DCAListInput struct {
+	ID           string `json:"id"`
+	HistoryLimit int    `json:"history_limit"`
+}
+
+type DCAPauseInput struct {
+	ID     string `json:"id"`
+	Resume bool   `json:"resume"`
+}
+
+type DCADeleteInput struct {
+	ID string `json:"id"`
+}
+
+type DCAOutput struct {
+	Plan     dca.Plan `json:"plan"`
+	AvgPrice float64  `json:"avg_price"`
+	NextRuns []string `json:"next_runs"`
+}
+
+type DCAListOutput struct {
+	PollInterval string       `json:"poll_interval"`
+	Timezone     string       `json:"timezone"`
+	Plans        []*DCAOutput `json:"plans"`
+}
+
+func dcaOutput(p dca.Plan) *DCAOutput {
+	output := &DCAOutput{Plan: p, AvgPrice: utils.Round(p.AvgPrice()), NextRuns: []string{}}
+	if p.Status != dca.StatusActive {
+		return output
+	}
+	runs, _ := dca.NextRuns(p.Schedule, 3)
+	for _, t := range runs {
+		output.NextRuns = append(output.NextRuns, t.Format(time.RFC3339))
+	}
+	return output
+}
+
+func NewDCACreateTool() mcp.Tool {
+	return mcp.NewTool("dca_create",
+		mcp.WithDescription("Create a recurring dollar-cost averaging plan that market-buys amount_thb of a symbol on a cron schedule in Asia/Bangkok time. "+
+			"Optionally multiply the buy on dips: dip_indicator rsi buys more when daily RSI(14) is at or below dip_threshold, drawdown when the price is at least dip_threshold % under the 30-day high"),
+		utils.WithTrading(),
+		withSymbolArg(),
+		mcp.WithNumber("amount_thb",
+			mcp.Required(),
+			utils.ExclusiveMin(0),
+			mcp.Description("THB to spend on each scheduled buy"),
+		),
+		mcp.WithString("schedule",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Cron schedule in Asia/Bangkok time: minute hour day month weekday, e.g. '0 9 * * 1' every Monday 09:00, '30 8 1,15 * *' on the 1st and 15th. @daily, @weekly and @monthly also work"),
+		),
+		mcp.WithString("dip_indicator",
+			mcp.Enum(dca.DipRSI, dca.DipDrawdown),
+			mcp.Description("Indicator for dip buying; omit to always buy amount_thb"),
+		),
+		mcp.WithNumber("dip_threshold",
+			mcp.Min(0),
+			mcp.Description("RSI level at or below which (rsi, default 30), or percent under the 30-day high at or beyond which (drawdown, default 10), the buy is multiplied"),
+		),
+		mcp.WithNumber("dip_multiplier",
+			mcp.DefaultNumber(2),
+			mcp.Min(1),
+			mcp.Max(10),
+			mcp.Description("Multiplier applied to amount_thb on a dip (default: 2)"),
+		),
+		withAccountArg(),
+		mcp.WithOutputSchema[DCAOutput](),
+	)
+}
+
+func DCACreateHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input DCACreateInput
+	if err := utils.BindArgs(NewDCACreateTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for dca create")
+		return err.Result()
+	}
+
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+
+	if minValue := pair.MinOrderValue(); input.AmountTHB < minValue {
+		return utils.InvalidField("amount_thb", fmt.Sprintf("must be at least the %.2f THB minimum order value", minValue)).Result()
+	}
+	if _, err := dca.ParseCron(input.Schedule); err != nil {
+		return utils.InvalidField("schedule", err.Error()).Result()
+	}
+
+	account, err := accounts.Resolve(ctx, input.Account)
+	if err != nil {
+		return utils.ExchangeErrorResult(err)
+	}
+
+	plan := dca.Plan{
+		Symbol:    pair.Symbol,
+		AmountTHB: input.AmountTHB,
+		Schedule:  strings.TrimSpace(input.Schedule),
+		Account:   account,
+	}
+	if input.DipIndicator != "" {
+		plan.Dip = &dca.Dip{Indicator: input.DipIndicator, Threshold: input.DipThreshold, Multiplier: input.DipMultiplier}
+		if plan.Dip.Threshold == 0 {
+			plan.Dip.Threshold = 30
+			if plan.Dip.Indicator == dca.DipDrawdown {
+				plan.Dip.Threshold = 10
+			}
+		}
+		if plan.Dip.Indicator == dca.DipRSI && plan.Dip.Threshold > 100 {
+			return utils.InvalidField("dip_threshold", "RSI threshold must be between 0 and 100").Result()
+		}
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		plan.CreatedBy = session.SessionID()
+	}
+
+	created, err := dca.Create(plan)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Msg("Failed to create DCA plan")
+		return utils.NewError(utils.CodeInternal, "failed to save DCA plan: %v", err).Result()
+	}
+
+	output := dcaOutput(created)
+	result := fmt.Sprintf("📅 DCA plan %s created on %s: buy %.2f THB of %s on '%s' (Asia/Bangkok)", created.ID, created.Account, created.AmountTHB, strings.ToUpper(created.Symbol), created.Schedule)
+	if created.Dip != nil {
+		result += "\n" + describeDip(created.Dip)
+	}
+	if len(output.NextRuns) > 0 {
+		result += "\nNext runs: " + strings.Join(output.NextRuns, ", ")
+	}
+
+	return utils.ArtifactsResult(result, output)
+}
+
+func NewDCAListTool() mcp.Tool {
+	return mcp.NewTool("dca_list",
+		mcp.WithDescription("List DCA plans with their next runs, totals, average cost and execution history. Pass id for one plan"),
+		utils.WithScope(utils.ScopeAccount),
+		mcp.WithString("id",
+			mcp.Description("DCA plan id as returned by dca_create"),
+		),
+		utils.WithInteger("history_limit",
+			mcp.DefaultNumber(10),
+			mcp.Min(0),
+			mcp.Max(200),
+			mcp.Description("Most recent executions to include per plan (default: 10)"),
+		),
+		mcp.WithOutputSchema[DCAListOutput](),
+	)
+}
+
+func DCAListHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input DCAListInput
+	if err := utils.BindArgs(NewDCAListTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for dca list")
+		return err.Result()
+	}
+
+	var plans []dca.Plan
+	if input.ID != "" {
+		p, terr := callerPlan(ctx, input.ID)
+		if terr != nil {
+			return terr.Result()
+		}
+		plans = append(plans, p)
+	} else {
+		for _, p := range dca.List() {
+			if accounts.Permits(ctx, p.Account) {
+				plans = append(plans, p)
+			}
+		}
+	}
+
+	output := DCAListOutput{PollInterval: dca.Interval().String(), Timezone: dca.Location.String(), Plans: []*DCAOutput{}}
+	for _, p := range plans {
+		if len(p.History) > input.HistoryLimit {
+			p.History = p.History[len(p.History)-input.HistoryLimit:]
+		}
+		output.Plans = append(output.Plans, dcaOutput(p))
+	}
+
+	if len(output.Plans) == 0 {
+		return utils.ArtifactsResult("No DCA plans", output)
+	}
+
+	result := ""
+	for _, o := range output.Plans {
+		p := o.Plan
+		result += fmt.Sprintf("📅 %s [%s] %.2f THB of %s on '%s' (%s)\n", p.ID, p.Status, p.AmountTHB, strings.ToUpper(p.Symbol), p.Schedule, p.Account)
+		if p.Dip != nil {
+			result += describeDip(p.Dip) + "\n"
+		}
+		result += fmt.Sprintf("Buys: %d | Spent: %.2f THB | Bought: %.8g | Avg price: %.8g\n", p.Buys, p.SpentTHB, p.BoughtCoin, o.AvgPrice)
+		if len(o.NextRuns) > 0 {
+			result += "Next: " + o.NextRuns[0] + "\n"
+		}
+		for i := len(p.History) - 1; i >= 0; i-- {
+			e := p.History[i]
+			result += fmt.Sprintf("  %s %s", time.Unix(e.Slot, 0).In(dca.Location).Format("2006-01-02 15:04"), e.Status)
+			switch e.Status {
+			case dca.ExecFilled:
+				result += fmt.Sprintf(" %.8g @ %.8g for %.2f THB", e.Coin, e.Price, e.SpentTHB)
+			case dca.ExecPlaced:
+				result += fmt.Sprintf(" %.2f THB order %s, fill not confirmed yet", e.AmountTHB, e.OrderID)
+			}
+			if e.Multiplier > 1 && (e.Status == dca.ExecFilled || e.Status == dca.ExecPlaced) {
+				result += fmt.Sprintf(" (x%.2f)", e.Multiplier)
+			}
+			if e.Skipped > 0 {
+				result += fmt.Sprintf(" | %d earlier slots missed", e.Skipped)
+			}
+			if e.Note != "" {
+				result += " | " + e.Note
+			}
+			result += "\n"
+		}
+	}
+
+	return utils.ArtifactsResult(strings.TrimSuffix(result, "\n"), output)
+}
+
+func NewDCAPauseTool() mcp.Tool {
+	return mcp.NewTool("dca_pause",
+		mcp.WithDescription("Pause a DCA plan, or resume it with resume: true. A resumed plan continues at its next slot; slots that passed while paused are not bought"),
+		utils.WithScope(utils.ScopeTrade),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("DCA plan id as returned by dca_create"),
+		),
+		mcp.WithBoolean("resume",
+			mcp.DefaultBool(false),
+			mcp.Description("Resume a paused plan instead of pausing it (default: false)"),
+		),
+		mcp.WithOutputSchema[DCAOutput](),
+	)
+}
+
+func DCAPauseHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input DCAPauseInput
+	if err := utils.BindArgs(NewDCAPauseTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for dca pause")
+		return err.Result()
+	}
+
+	if _, terr := callerPlan(ctx, input.ID); terr != nil {
+		return terr.Result()
+	}
+
+	plan, ok, err := dca.SetPaused(input.ID, !input.Resume)
+	if !ok {
+		return utils.NewError(utils.CodeNotFound, "DCA plan %s not found", input.ID).With("id", input.ID).Result()
+	}
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("dca", input.ID).Msg("Failed to update DCA plan")
+		return utils.NewError(utils.CodeInternal, "failed to update DCA plan: %v", err).Result()
+	}
+
+	output := dcaOutput(plan)
+	result := fmt.Sprintf("⏸️ DCA plan %s paused", plan.ID)
+	if plan.Status == dca.StatusActive {
+		result = fmt.Sprintf("▶️ DCA plan %s resumed", plan.ID)
+		if len(output.NextRuns) > 0 {
+			result += ", next run " + output.NextRuns[0]
+		}
+	}
+
+	return utils.ArtifactsResult(result, output)
+}
+
+func NewDCADeleteTool() mcp.Tool {
+	return mcp.NewTool("dca_delete",
+		mcp.WithDescription("Delete a DCA plan and its execution history"),
+		utils.WithScope(utils.ScopeTrade),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("DCA plan id as returned by dca_create"),
+		),
+		mcp.WithOutputSchema[DCAOutput](),
+	)
+}
+
+func DCADeleteHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input DCADeleteInput
+	if err := utils.BindArgs(NewDCADeleteTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for dca delete")
+		return err.Result()
+	}
+
+	if _, terr := callerPlan(ctx, input.ID); terr != nil {
+		return terr.Result()
+	}
+
+	plan, ok, err := dca.Delete(input.ID)
+	if !ok {
+		return utils.NewError(utils.CodeNotFound, "DCA plan %s not found", input.ID).With("id", input.ID).Result()
+	}
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("dca", input.ID).Msg("Failed to delete DCA plan")
+		return utils.NewError(utils.CodeInternal, "failed to delete DCA plan: %v", err).Result()
+	}
+
+	output := &DCAOutput{Plan: plan, AvgPrice: utils.Round(plan.AvgPrice()), NextRuns: []string{}}
+	result := fmt.Sprintf("🗑️ Deleted DCA plan %s (%d buys, %.2f THB spent)", plan.ID, plan.Buys, plan.SpentTHB)
+	return utils.ArtifactsResult(result, output)
+}
+
+// callerPlan looks up a plan on an account the caller may use. Plans on other
+// accounts are reported as not found.
+func callerPlan(ctx context.Context, id string) (dca.Plan, *utils.ToolError) {
+	p, ok := dca.Get(id)
+	if !ok || !accounts.Permits(ctx, p.Account) {
+		return dca.Plan{}, utils.NewError(utils.CodeNotFound, "DCA plan %s not found", id).With("id", id)
+	}
+	return p, nil
+}
+
+// EvaluateDip reads a plan's dip indicator from daily candles.
+func EvaluateDip(ctx context.Context, p dca.Plan) (dca.DipResult, error) {
+	symbol := strings.ToUpper(p.Symbol)
+
+	switch p.Dip.Indicator {
+	case dca.DipRSI:
+		history, err := alertCandles(ctx, p.Symbol, 1440, dcaRSIPeriod*3)
+		if err != nil {
+			return dca.DipResult{}, err
+		}
+		if len(history.Close) < dcaRSIPeriod+1 {
+			return dca.DipResult{}, fmt.Errorf("need at least %d daily candles for RSI(%d), got %d", dcaRSIPeriod+1, dcaRSIPeriod, len(history.Close))
+		}
+		rsi := utils.Round(calculateRSI(history.Close, dcaRSIPeriod), 2)
+		return dca.DipResult{
+			Triggered: rsi <= p.Dip.Threshold,
+			Value:     rsi,
+			Message:   fmt.Sprintf("%s daily RSI(%d) %.2f at or below %.2f", symbol, dcaRSIPeriod, rsi, p.Dip.Threshold),
+		}, nil
+
+	case dca.DipDrawdown:
+		history, err := alertCandles(ctx, p.Symbol, 1440, dcaDrawdownDays)
+		if err != nil {
+			return dca.DipResult{}, err
+		}
+		high := 0.0
+		for _, h := range history.High {
+			high = max(high, h)
+		}
+		last := history.Close[len(history.Close)-1]
+		if high <= 0 {
+			return dca.DipResult{}, fmt.Errorf("no %d-day high for %s", dcaDrawdownDays, symbol)
+		}
+		drawdown := utils.Round((high-last)/high*100, 2)
+		return dca.DipResult{
+			Triggered: drawdown >= p.Dip.Threshold,
+			Value:     drawdown,
+			Message:   fmt.Sprintf("%s is %.2f%% under its %d-day high %.8g", symbol, drawdown, dcaDrawdownDays, high),
+		}, nil
+	}
+
+	return dca.DipResult{}, fmt.Errorf("unknown dip indicator %q", p.Dip.Indicator)
+}
+
+func describeDip(d *dca.Dip) string {
+	if d.Indicator == dca.DipDrawdown {
+		return fmt.Sprintf("Dip: x%.2f when %.2f%% or more under the %d-day high", d.Multiplier, d.Threshold, dcaDrawdownDays)
+	}
+	return fmt.Sprintf("Dip: x%.2f when daily RSI(%d) ≤ %.2f", d.Multiplier, dcaRSIPeriod, d.Threshold)
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/symbols"
 	"gokub/utils"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -10,15 +11,24 @@ import (
 )
 
 type BreakoutSignal struct {
-	Signal         string  `json:"signal"`
-	CurrentPrice   float64 `json:"current_price"`
-	High20         float64 `json:"high_20"`
-	CurrentVolume  float64 `json:"current_volume"`
-	AvgVolume20    float64 `json:"avg_volume_20"`
-	VolumeRatio    float64 `json:"volume_ratio"`
-	SuggestedEntry float64 `json:"suggested_entry"`
-	SuggestedStop  float64 `json:"suggested_stop"`
-	Lookback       int     `json:"lookback"`
+	Signal         string   `json:"signal"`
+	CurrentPrice   float64  `json:"current_price"`
+	High20         float64  `json:"high_20"`
+	CurrentVolume  float64  `json:"current_volume"`
+	AvgVolume20    float64  `json:"avg_volume_20"`
+	VolumeRatio    float64  `json:"volume_ratio"`
+	SuggestedEntry float64  `json:"suggested_entry"`
+	SuggestedStop  float64  `json:"suggested_stop"`
+	Lookback       int      `json:"lookback"`
+	Warnings       []string `json:"warnings,omitempty"`
+}
+
+type BreakoutInput struct {
+	Candles         []OHLCData `json:"candles"`
+	Lookback        int        `json:"lookback"`
+	VolumeThreshold float64    `json:"volume_threshold"`
+	ATRMultiplier   float64    `json:"atr_multiplier"`
+	Symbol          string     `json:"symbol"`
 }
 
 func NewDetectBreakoutSignalTool() mcp.Tool {
@@ -27,64 +37,45 @@ func NewDetectBreakoutSignalTool() mcp.Tool {
 		mcp.WithArray("candles",
 			mcp.Required(),
 			mcp.Description("Array of OHLCV candles (need at least lookback+1 candles)"),
+			withCandleItems(),
 		),
-		mcp.WithNumber("lookback",
+		utils.WithInteger("lookback",
 			mcp.DefaultNumber(20),
+			mcp.Min(1),
 			mcp.Description("Number of periods to check for new high (default: 20)"),
 		),
 		mcp.WithNumber("volume_threshold",
 			mcp.DefaultNumber(1.5),
+			mcp.Min(0),
 			mcp.Description("Volume multiplier threshold (default: 1.5 = 150% of average)"),
 		),
 		mcp.WithNumber("atr_multiplier",
 			mcp.DefaultNumber(1.5),
+			mcp.Min(0),
 			mcp.Description("ATR multiplier for stop loss (default: 1.5)"),
 		),
+		withRulesSymbolArg(),
+		mcp.WithOutputSchema[BreakoutSignal](),
 	)
 }
 
 func DetectBreakoutSignalHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for detect breakout signal")
-		return utils.ErrorResult("invalid arguments")
+	var input BreakoutInput
+	if err := utils.BindArgs(NewDetectBreakoutSignalTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for detect breakout signal")
+		return err.Result()
 	}
 
-	candlesRaw, ok := args["candles"].([]any)
-	if !ok {
-		return utils.ErrorResult("candles must be an array")
-	}
+	candles := input.Candles
+	lookback, volumeThreshold, atrMultiplier := input.Lookback, input.VolumeThreshold, input.ATRMultiplier
 
-	candles := make([]OHLCData, 0, len(candlesRaw))
-	volumes := make([]float64, 0, len(candlesRaw))
-
-	for _, c := range candlesRaw {
-		candleMap, ok := c.(map[string]any)
-		if !ok {
-			continue
-		}
-
-		high := getFloatFromAny(candleMap["high"])
-		low := getFloatFromAny(candleMap["low"])
-		close := getFloatFromAny(candleMap["close"])
-		volume := getFloatFromAny(candleMap["volume"])
-
-		if high > 0 && low > 0 && close > 0 {
-			candles = append(candles, OHLCData{
-				High:  high,
-				Low:   low,
-				Close: close,
-			})
-			volumes = append(volumes, volume)
-		}
+	volumes := make([]float64, len(candles))
+	for i, c := range candles {
+		volumes[i] = c.Volume
 	}
 
-	lookback := utils.GetIntArg(args, "lookback", 20)
-	volumeThreshold := utils.GetFloat64Arg(args, "volume_threshold", 1.5)
-	atrMultiplier := utils.GetFloat64Arg(args, "atr_multiplier", 1.5)
-
 	if len(candles) < lookback+1 {
-		return utils.ErrorResult(fmt.Sprintf("need at least %d candles", lookback+1))
+		return utils.NewError(utils.CodeInsufficientData, "need at least %d candles", lookback+1).Result()
 	}
 
 	currentCandle := candles[len(candles)-1]
@@ -108,27 +99,27 @@ func DetectBreakoutSignalHandler(ctx context.Context, request mcp.CallToolReques
 		volumeRatio = currentVolume / avgVolume
 	}
 
-	signal := "NO_SIGNAL"
-	isBreakout := currentCandle.Close > high20 && volumeRatio >= volumeThreshold
-
-	if isBreakout {
-		signal = "BREAKOUT_BUY"
+	// The rules and prices come from the breakout strategy definition, which
+	// a file in STRATEGY_DIR can replace.
+	res, stratErr := runSignalStrategy("breakout", candles, map[string]float64{
+		"lookback":         float64(lookback),
+		"volume_threshold": volumeThreshold,
+		"atr_multiplier":   atrMultiplier,
+	})
+	if stratErr != nil {
+		return stratErr.Result()
 	}
+	signal := res.Signal
 
-	trueRanges := make([]float64, len(candles))
-	for i := range candles {
-		if i == 0 {
-			trueRanges[i] = candles[i].High - candles[i].Low
-		} else {
-			highLow := candles[i].High - candles[i].Low
-			highClose := abs(candles[i].High - candles[i-1].Close)
-			lowClose := abs(candles[i].Low - candles[i-1].Close)
-			trueRanges[i] = max(highLow, max(highClose, lowClose))
-		}
+	pair, symErr := rulesFor(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
 	}
 
-	atr := calculateATR(trueRanges, 14)
-	suggestedStop := currentCandle.Close - (atr * atrMultiplier)
+	rawEntry := res.Entry
+	rawStop := res.Stop
+	suggestedEntry := pair.SnapPrice(rawEntry, symbols.RoundUp)
+	suggestedStop := pair.SnapPrice(rawStop, symbols.RoundDown)
 
 	result := &BreakoutSignal{
 		Signal:         signal,
@@ -137,40 +128,24 @@ func DetectBreakoutSignalHandler(ctx context.Context, request mcp.CallToolReques
 		CurrentVolume:  utils.Round(currentVolume, 2),
 		AvgVolume20:    utils.Round(avgVolume, 2),
 		VolumeRatio:    utils.Round(volumeRatio, 2),
-		SuggestedEntry: utils.Round(currentCandle.Close*1.001, 2),
-		SuggestedStop:  utils.Round(suggestedStop, 2),
+		SuggestedEntry: suggestedEntry,
+		SuggestedStop:  suggestedStop,
 		Lookback:       lookback,
+		Warnings:       riskChangeWarning("risk", (rawEntry-rawStop)/rawEntry*100, (suggestedEntry-suggestedStop)/suggestedEntry*100, "%"),
 	}
 
 	summary := fmt.Sprintf("Breakout Signal Detection (lookback: %d)\n", lookback)
 	summary += fmt.Sprintf("Signal: %s\n\n", result.Signal)
 	summary += fmt.Sprintf("Current Price: %.2f | High(%d): %.2f\n", result.CurrentPrice, lookback, result.High20)
 	summary += fmt.Sprintf("Volume Ratio: %.2fx (Current: %.2f | Avg: %.2f)\n", result.VolumeRatio, result.CurrentVolume, result.AvgVolume20)
-	if signal == "BREAKOUT_BUY" {
+	if res.Matched {
 		summary += "\n✅ BREAKOUT CONFIRMED\n"
 		summary += fmt.Sprintf("Suggested Entry: %.2f\n", result.SuggestedEntry)
 		summary += fmt.Sprintf("Suggested Stop: %.2f (%.2f%% below entry)", result.SuggestedStop, ((result.SuggestedEntry-result.SuggestedStop)/result.SuggestedEntry)*100)
+		for _, w := range result.Warnings {
+			summary += "\n⚠️ " + w
+		}
 	}
 
 	return utils.ArtifactsResult(summary, result)
 }
-
-func getFloatFromAny(v any) float64 {
-	switch val := v.(type) {
-	case float64:
-		return val
-	case int:
-		return float64(val)
-	case int64:
-		return float64(val)
-	default:
-		return 0
-	}
-}
-
-func abs(x float64) float64 {
-	if x < 0 {
-		return -x
-	}
-	return x
-}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/symbols"
 	"gokub/utils"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -10,17 +11,27 @@ import (
 )
 
 type PullbackSignal struct {
-	Signal           string  `json:"signal"`
-	CurrentPrice     float64 `json:"current_price"`
-	EMA20            float64 `json:"ema_20"`
-	RSI              float64 `json:"rsi"`
-	PriceToEMA       float64 `json:"price_to_ema_percent"`
-	HasReversalBar   bool    `json:"has_reversal_bar"`
-	ReversalBarClose float64 `json:"reversal_bar_close"`
-	ReversalBarHigh  float64 `json:"reversal_bar_high"`
-	SuggestedEntry   float64 `json:"suggested_entry"`
-	SuggestedStop    float64 `json:"suggested_stop"`
-	SwingLow         float64 `json:"swing_low"`
+	Signal           string   `json:"signal"`
+	CurrentPrice     float64  `json:"current_price"`
+	EMA20            float64  `json:"ema_20"`
+	RSI              float64  `json:"rsi"`
+	PriceToEMA       float64  `json:"price_to_ema_percent"`
+	HasReversalBar   bool     `json:"has_reversal_bar"`
+	ReversalBarClose float64  `json:"reversal_bar_close"`
+	ReversalBarHigh  float64  `json:"reversal_bar_high"`
+	SuggestedEntry   float64  `json:"suggested_entry"`
+	SuggestedStop    float64  `json:"suggested_stop"`
+	SwingLow         float64  `json:"swing_low"`
+	Warnings         []string `json:"warnings,omitempty"`
+}
+
+type PullbackInput struct {
+	Candles   []OHLCData `json:"candles"`
+	EMAPeriod int        `json:"ema_period"`
+	RSIPeriod int        `json:"rsi_period"`
+	RSIMin    float64    `json:"rsi_min"`
+	RSIMax    float64    `json:"rsi_max"`
+	Symbol    string     `json:"symbol"`
 }
 
 func NewDetectPullbackSignalTool() mcp.Tool {
@@ -29,68 +40,56 @@ func NewDetectPullbackSignalTool() mcp.Tool {
 		mcp.WithArray("candles",
 			mcp.Required(),
 			mcp.Description("Array of OHLCV candles (need at least 20+ for EMA and RSI calculation)"),
+			withCandleItems(),
 		),
-		mcp.WithNumber("ema_period",
+		utils.WithInteger("ema_period",
 			mcp.DefaultNumber(20),
+			mcp.Min(1),
 			mcp.Description("EMA period for pullback detection (default: 20)"),
 		),
-		mcp.WithNumber("rsi_period",
+		utils.WithInteger("rsi_period",
 			mcp.DefaultNumber(14),
+			mcp.Min(1),
 			mcp.Description("RSI period (default: 14)"),
 		),
 		mcp.WithNumber("rsi_min",
 			mcp.DefaultNumber(40),
+			mcp.Min(0),
+			mcp.Max(100),
 			mcp.Description("Minimum RSI for bounce zone (default: 40)"),
 		),
 		mcp.WithNumber("rsi_max",
 			mcp.DefaultNumber(50),
+			mcp.Min(0),
+			mcp.Max(100),
 			mcp.Description("Maximum RSI for bounce zone (default: 50)"),
 		),
+		withRulesSymbolArg(),
+		mcp.WithOutputSchema[PullbackSignal](),
 	)
 }
 
 func DetectPullbackSignalHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for detect pullback signal")
-		return utils.ErrorResult("invalid arguments")
+	var input PullbackInput
+	if err := utils.BindArgs(NewDetectPullbackSignalTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for detect pullback signal")
+		return err.Result()
 	}
 
-	candlesRaw, ok := args["candles"].([]any)
-	if !ok {
-		return utils.ErrorResult("candles must be an array")
+	candles := input.Candles
+	emaPeriod, rsiPeriod := input.EMAPeriod, input.RSIPeriod
+	rsiMin, rsiMax := input.RSIMin, input.RSIMax
+	if rsiMin > rsiMax {
+		return utils.InvalidField("rsi_min", "must be <= rsi_max").Result()
 	}
 
-	candles := make([]OHLCData, 0, len(candlesRaw))
-	closes := make([]float64, 0, len(candlesRaw))
-
-	for _, c := range candlesRaw {
-		candleMap, ok := c.(map[string]any)
-		if !ok {
-			continue
-		}
-
-		high := getFloatFromAny(candleMap["high"])
-		low := getFloatFromAny(candleMap["low"])
-		close := getFloatFromAny(candleMap["close"])
-
-		if high > 0 && low > 0 && close > 0 {
-			candles = append(candles, OHLCData{
-				High:  high,
-				Low:   low,
-				Close: close,
-			})
-			closes = append(closes, close)
-		}
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close
 	}
 
-	emaPeriod := utils.GetIntArg(args, "ema_period", 20)
-	rsiPeriod := utils.GetIntArg(args, "rsi_period", 14)
-	rsiMin := utils.GetFloat64Arg(args, "rsi_min", 40)
-	rsiMax := utils.GetFloat64Arg(args, "rsi_max", 50)
-
 	if len(candles) < max(emaPeriod, rsiPeriod)+5 {
-		return utils.ErrorResult(fmt.Sprintf("need at least %d candles", max(emaPeriod, rsiPeriod)+5))
+		return utils.NewError(utils.CodeInsufficientData, "need at least %d candles", max(emaPeriod, rsiPeriod)+5).Result()
 	}
 
 	emaValues := calculateEMA(closes, emaPeriod)
@@ -99,24 +98,12 @@ func DetectPullbackSignalHandler(ctx context.Context, request mcp.CallToolReques
 	rsi := calculateRSI(closes, rsiPeriod)
 
 	currentCandle := candles[len(candles)-1]
-	previousCandle := candles[len(candles)-2]
 
 	priceToEMAPercent := ((currentCandle.Close - currentEMA) / currentEMA) * 100
 
-	nearEMA := abs(priceToEMAPercent) <= 2.0
-
-	rsiInBounceZone := rsi >= rsiMin && rsi <= rsiMax
-
-	hasReversalBar := false
 	reversalClose := currentCandle.Close
 	reversalHigh := currentCandle.High
 
-	if currentCandle.Close > currentCandle.High-(currentCandle.High-currentCandle.Low)*0.3 {
-		if previousCandle.Close < previousCandle.High-(previousCandle.High-previousCandle.Low)*0.5 {
-			hasReversalBar = true
-		}
-	}
-
 	swingLow := currentCandle.Low
 	for i := len(candles) - 10; i < len(candles); i++ {
 		if i >= 0 && candles[i].Low < swingLow {
@@ -124,13 +111,29 @@ func DetectPullbackSignalHandler(ctx context.Context, request mcp.CallToolReques
 		}
 	}
 
-	signal := "NO_SIGNAL"
-	if nearEMA && rsiInBounceZone && hasReversalBar {
-		signal = "PULLBACK_BUY"
+	// The rules and prices come from the pullback strategy definition, which
+	// a file in STRATEGY_DIR can replace.
+	res, stratErr := runSignalStrategy("pullback", candles, map[string]float64{
+		"ema_period": float64(emaPeriod),
+		"rsi_period": float64(rsiPeriod),
+		"rsi_min":    rsiMin,
+		"rsi_max":    rsiMax,
+	})
+	if stratErr != nil {
+		return stratErr.Result()
 	}
+	signal := res.Signal
+	hasReversalBar := res.Passed("reversal_bar")
 
-	suggestedEntry := reversalHigh * 1.001
-	suggestedStop := swingLow * 0.999
+	pair, symErr := rulesFor(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+
+	rawEntry := res.Entry
+	rawStop := res.Stop
+	suggestedEntry := pair.SnapPrice(rawEntry, symbols.RoundUp)
+	suggestedStop := pair.SnapPrice(rawStop, symbols.RoundDown)
 
 	result := &PullbackSignal{
 		Signal:           signal,
@@ -141,9 +144,10 @@ func DetectPullbackSignalHandler(ctx context.Context, request mcp.CallToolReques
 		HasReversalBar:   hasReversalBar,
 		ReversalBarClose: utils.Round(reversalClose, 2),
 		ReversalBarHigh:  utils.Round(reversalHigh, 2),
-		SuggestedEntry:   utils.Round(suggestedEntry, 2),
-		SuggestedStop:    utils.Round(suggestedStop, 2),
+		SuggestedEntry:   suggestedEntry,
+		SuggestedStop:    suggestedStop,
 		SwingLow:         utils.Round(swingLow, 2),
+		Warnings:         riskChangeWarning("risk", (rawEntry-rawStop)/rawEntry*100, (suggestedEntry-suggestedStop)/suggestedEntry*100, "%"),
 	}
 
 	summary := fmt.Sprintf("Pullback Signal Detection (EMA%d, RSI%d)\n", emaPeriod, rsiPeriod)
@@ -152,11 +156,14 @@ func DetectPullbackSignalHandler(ctx context.Context, request mcp.CallToolReques
 	summary += fmt.Sprintf("RSI: %.2f (Bounce Zone: %.0f-%.0f)\n", result.RSI, rsiMin, rsiMax)
 	summary += fmt.Sprintf("Reversal Bar: %v\n", result.HasReversalBar)
 
-	if signal == "PULLBACK_BUY" {
+	if res.Matched {
 		summary += "\n✅ PULLBACK CONFIRMED\n"
 		summary += fmt.Sprintf("Suggested Entry: %.2f (above reversal high)\n", result.SuggestedEntry)
 		summary += fmt.Sprintf("Suggested Stop: %.2f (below swing low %.2f)\n", result.SuggestedStop, result.SwingLow)
 		summary += fmt.Sprintf("Risk: %.2f%%", ((result.SuggestedEntry-result.SuggestedStop)/result.SuggestedEntry)*100)
+		for _, w := range result.Warnings {
+			summary += "\n⚠️ " + w
+		}
 	}
 
 	return utils.ArtifactsResult(summary, result)
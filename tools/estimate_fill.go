@@ -0,0 +1,210 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/orders"
+	"gokub/tracing"
+	"gokub/utils"
+	"math"
+	"strings"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+type EstimateFillInput struct {
+	Symbol         string  `json:"symbol"`
+	Side           string  `json:"side"`
+	Amount         float64 `json:"amount"`
+	Unit           string  `json:"unit"`
+	FeePercent     float64 `json:"fee_percent"`
+	MaxSlippageBps float64 `json:"max_slippage_bps"`
+	DepthLimit     int     `json:"depth_limit"`
+}
+
+type SplitRecommendation struct {
+	Split       bool    `json:"split"`
+	Slices      int     `json:"slices"`
+	SliceAmount float64 `json:"slice_amount"`
+	SliceUnit   string  `json:"slice_unit"`
+	Reason      string  `json:"reason"`
+}
+
+type FillEstimate struct {
+	Symbol         string              `json:"symbol"`
+	Side           string              `json:"side"`
+	Requested      float64             `json:"requested"`
+	Unit           string              `json:"unit"`
+	FilledCoin     float64             `json:"filled_coin"`
+	FilledTHB      float64             `json:"filled_thb"`
+	FullyFilled    bool                `json:"fully_filled"`
+	Mid            float64             `json:"mid"`
+	BestPrice      float64             `json:"best_price"`
+	WorstPrice     float64             `json:"worst_price"`
+	VWAP           float64             `json:"vwap"`
+	SlippageBps    float64             `json:"slippage_bps"`
+	LevelsConsumed int                 `json:"levels_consumed"`
+	FeePercent     float64             `json:"fee_percent"`
+	FeeTHB         float64             `json:"fee_thb"`
+	NetTHB         float64             `json:"net_thb"`
+	Recommendation SplitRecommendation `json:"recommendation"`
+}
+
+// maxSliceWithin finds the largest order, in the same unit as amount, whose
+// fill stays within maxBps of mid.
+func maxSliceWithin(levels [][]float64, side string, amount float64, inTHB bool, mid, maxBps float64) float64 {
+	lo, hi := 0.0, amount
+	for range 40 {
+		size := (lo + hi) / 2
+		fill := orders.WalkBook(levels, size, inTHB)
+		if fill.Filled && orders.SlippageBps(side, fill.VWAP(), mid) <= maxBps {
+			lo = size
+		} else {
+			hi = size
+		}
+	}
+	return lo
+}
+
+func NewEstimateFillTool() mcp.Tool {
+	return mcp.NewTool("estimate_fill",
+		mcp.WithDescription("Estimate the average fill price, slippage and fees of a market order by walking the order book, and suggest how to split it when the impact is too high"),
+		withSymbolArg(),
+		mcp.WithString("side",
+			mcp.Required(),
+			mcp.Enum("buy", "sell"),
+			mcp.Description("Order side: buy takes asks, sell hits bids"),
+		),
+		mcp.WithNumber("amount",
+			mcp.Required(),
+			utils.ExclusiveMin(0),
+			mcp.Description("Order size, in THB or coin depending on unit"),
+		),
+		mcp.WithString("unit",
+			mcp.DefaultString("thb"),
+			mcp.Enum("thb", "coin"),
+			mcp.Description("Unit of amount (default: thb)"),
+		),
+		mcp.WithNumber("fee_percent",
+			mcp.DefaultNumber(0.25),
+			mcp.Min(0),
+			mcp.Description("Taker fee percentage (default: 0.25%)"),
+		),
+		mcp.WithNumber("max_slippage_bps",
+			mcp.DefaultNumber(50),
+			utils.ExclusiveMin(0),
+			mcp.Description("Slippage vs. mid in basis points above which the order should be split (default: 50)"),
+		),
+		utils.WithInteger("depth_limit",
+			mcp.DefaultNumber(100),
+			mcp.Min(1),
+			mcp.Max(1000),
+			mcp.Description("Order book levels to fetch per side (default: 100)"),
+		),
+		mcp.WithOutputSchema[FillEstimate](),
+	)
+}
+
+func EstimateFillHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input EstimateFillInput
+	if err := utils.BindArgs(NewEstimateFillTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for estimate fill")
+		return err.Result()
+	}
+
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+	symbol := pair.Symbol
+
+	log.Debug().Ctx(ctx).Str("symbol", symbol).Str("side", input.Side).Float64("amount", input.Amount).Str("unit", input.Unit).Msg("Estimating fill")
+
+	depth, err := tracing.Exchange(ctx, "market.GetDepth", symbol, func() (*market.Depth, error) {
+		return market.GetDepth(symbol, input.DepthLimit)
+	})
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", symbol).Msg("Failed to get market depth for fill estimate")
+		return utils.ExchangeErrorResult(err)
+	}
+
+	if len(depth.Bids) == 0 || len(depth.Asks) == 0 {
+		return utils.NewError(utils.CodeInsufficientData, "order book for %s is empty", symbol).With("symbol", symbol).Result()
+	}
+
+	bestBid, bestAsk := depth.Bids[0][0], depth.Asks[0][0]
+	mid := (bestBid + bestAsk) / 2
+
+	levels, best := depth.Asks, bestAsk
+	if input.Side == "sell" {
+		levels, best = depth.Bids, bestBid
+	}
+
+	inTHB := input.Unit == "thb"
+	fill := orders.WalkBook(levels, input.Amount, inTHB)
+	vwap := fill.VWAP()
+	slippage := orders.SlippageBps(input.Side, vwap, mid)
+
+	feeTHB := fill.THB * input.FeePercent / 100
+	netTHB := fill.THB + feeTHB
+	if input.Side == "sell" {
+		netTHB = fill.THB - feeTHB
+	}
+
+	output := FillEstimate{
+		Symbol:         symbol,
+		Side:           input.Side,
+		Requested:      input.Amount,
+		Unit:           input.Unit,
+		FilledCoin:     utils.Round(fill.Coin),
+		FilledTHB:      utils.Round(fill.THB, 2),
+		FullyFilled:    fill.Filled,
+		Mid:            utils.Round(mid),
+		BestPrice:      best,
+		WorstPrice:     fill.Worst,
+		VWAP:           utils.Round(vwap),
+		SlippageBps:    utils.Round(slippage, 2),
+		LevelsConsumed: fill.Levels,
+		FeePercent:     input.FeePercent,
+		FeeTHB:         utils.Round(feeTHB, 2),
+		NetTHB:         utils.Round(netTHB, 2),
+		Recommendation: recommendSplit(levels, input, inTHB, mid, fill, slippage),
+	}
+
+	result := fmt.Sprintf("🧮 %s market %s %.8g %s\n", strings.ToUpper(symbol), input.Side, input.Amount, strings.ToUpper(input.Unit))
+	result += fmt.Sprintf("Fill: %.8f coin for %.2f THB over %d levels", output.FilledCoin, output.FilledTHB, output.LevelsConsumed)
+	if !output.FullyFilled {
+		result += " (book too thin, partially filled)"
+	}
+	result += "\n"
+	result += fmt.Sprintf("VWAP: %.8g | Mid: %.8g | Best: %.8g | Worst: %.8g\n", output.VWAP, output.Mid, output.BestPrice, output.WorstPrice)
+	result += fmt.Sprintf("Slippage: %.2f bps | Fee: %.2f THB | Net: %.2f THB\n", output.SlippageBps, output.FeeTHB, output.NetTHB)
+	result += "Recommendation: " + output.Recommendation.Reason
+
+	return utils.ArtifactsResult(result, output)
+}
+
+func recommendSplit(levels [][]float64, input EstimateFillInput, inTHB bool, mid float64, fill orders.BookFill, slippage float64) SplitRecommendation {
+	rec := SplitRecommendation{Slices: 1, SliceAmount: input.Amount, SliceUnit: input.Unit}
+
+	if fill.Filled && slippage <= input.MaxSlippageBps {
+		rec.Reason = fmt.Sprintf("impact %.2f bps is within %.0f bps, a single order is fine", slippage, input.MaxSlippageBps)
+		return rec
+	}
+
+	maxSlice := maxSliceWithin(levels, input.Side, input.Amount, inTHB, mid, input.MaxSlippageBps)
+	if maxSlice <= 0 {
+		rec.Split = true
+		rec.Reason = fmt.Sprintf("even the best level exceeds %.0f bps from mid, use a limit order instead of a market order", input.MaxSlippageBps)
+		return rec
+	}
+
+	rec.Split = true
+	rec.Slices = int(math.Ceil(input.Amount / maxSlice))
+	rec.SliceAmount = utils.Round(input.Amount/float64(rec.Slices), 8)
+	rec.Reason = fmt.Sprintf("impact %.2f bps exceeds %.0f bps, split into %d orders of about %.8g %s and let the book refill between them",
+		slippage, input.MaxSlippageBps, rec.Slices, rec.SliceAmount, strings.ToUpper(input.Unit))
+	return rec
+}
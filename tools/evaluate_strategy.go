@@ -0,0 +1,250 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gokub/strategy"
+	"gokub/symbols"
+	"gokub/utils"
+	"strings"
+
+	"github.com/dvgamerr-app/go-bitkub/market"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+type EvaluateStrategyInput struct {
+	Strategy   string             `json:"strategy"`
+	Definition string             `json:"definition"`
+	Params     map[string]float64 `json:"params"`
+	Candles    []OHLCData         `json:"candles"`
+	Symbol     string             `json:"symbol"`
+	Resolution int                `json:"resolution"`
+	Limit      int                `json:"limit"`
+}
+
+type StrategyEvaluation struct {
+	Strategy        string                `json:"strategy"`
+	Description     string                `json:"description,omitempty"`
+	Source          string                `json:"source"`
+	Symbol          string                `json:"symbol,omitempty"`
+	Signal          string                `json:"signal"`
+	Matched         bool                  `json:"matched"`
+	Rules           []strategy.RuleResult `json:"rules"`
+	CurrentPrice    float64               `json:"current_price"`
+	SuggestedEntry  float64               `json:"suggested_entry"`
+	SuggestedStop   float64               `json:"suggested_stop,omitempty"`
+	SuggestedTarget float64               `json:"suggested_target,omitempty"`
+	Params          map[string]float64    `json:"params"`
+	Candles         int                   `json:"candles"`
+	Warnings        []string              `json:"warnings,omitempty"`
+}
+
+func NewEvaluateStrategyTool() mcp.Tool {
+	return mcp.NewTool("evaluate_strategy",
+		mcp.WithDescription(fmt.Sprintf(`Evaluate a strategy definition on the latest candle. Strategies are YAML or JSON with params, when rules such as "close > ema(20) and rsi(14) between 40 and 50 and volume > 1.5 * sma(volume, 20)", and entry, stop and target prices. The result's signal, suggested_entry and suggested_stop can be passed to place_bracket_order. Available strategies: %s. Functions: %s`,
+			strings.Join(strategy.Names(), ", "), strings.Join(strategy.FunctionUsage(), "; "))),
+		mcp.WithString("strategy",
+			mcp.Description("Name of a built-in or STRATEGY_DIR strategy"),
+		),
+		mcp.WithString("definition",
+			mcp.Description("Inline YAML or JSON definition to evaluate instead of a named strategy"),
+		),
+		mcp.WithObject("params",
+			mcp.Description("Overrides for the strategy's params, e.g. {\"ema_period\": 50}"),
+			mcp.AdditionalProperties(map[string]any{"type": "number"}),
+		),
+		mcp.WithArray("candles",
+			mcp.Description("OHLCV candles, oldest first. When omitted they are fetched for symbol"),
+			withCandleItems(),
+		),
+		withRulesSymbolArg(),
+		utils.WithInteger("resolution",
+			mcp.DefaultNumber(60),
+			utils.EnumNumbers(1, 5, 15, 60, 240, 1440),
+			mcp.Description("Timeframe in minutes for fetched candles (default: 60)"),
+		),
+		utils.WithInteger("limit",
+			mcp.DefaultNumber(200),
+			mcp.Min(1),
+			mcp.Max(1000),
+			mcp.Description("Number of candles to fetch (default: 200)"),
+		),
+		mcp.WithOutputSchema[StrategyEvaluation](),
+	)
+}
+
+func EvaluateStrategyHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input EvaluateStrategyInput
+	if err := utils.BindArgs(NewEvaluateStrategyTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for evaluate strategy")
+		return err.Result()
+	}
+
+	var def *strategy.Definition
+	switch {
+	case input.Definition != "" && input.Strategy != "":
+		return utils.InvalidField("definition", "give either strategy or definition, not both").Result()
+	case input.Definition != "":
+		d, err := strategy.Parse([]byte(input.Definition), "inline")
+		if err != nil {
+			return utils.InvalidField("definition", err.Error()).Result()
+		}
+		def = d
+	case input.Strategy != "":
+		d, ok := strategy.Get(input.Strategy)
+		if !ok {
+			return utils.NewError(utils.CodeNotFound, "strategy %s not found", input.Strategy).
+				With("available", strategy.Names()).Result()
+		}
+		def = d
+	default:
+		return utils.InvalidField("strategy", "give a strategy name or an inline definition").
+			With("available", strategy.Names()).Result()
+	}
+
+	pair, symErr := rulesFor(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+
+	var bars strategy.Bars
+	if len(input.Candles) > 0 {
+		bars = candleBars(input.Candles)
+	} else {
+		if input.Symbol == "" {
+			return utils.InvalidField("candles", "give candles or a symbol to fetch them for").Result()
+		}
+		history, err := alertCandles(ctx, pair.Symbol, input.Resolution, input.Limit)
+		if err != nil {
+			log.Warn().Ctx(ctx).Err(err).Str("symbol", pair.Symbol).Msg("Failed to get candles for strategy")
+			return utils.ExchangeErrorResult(err)
+		}
+		bars = historyBars(history)
+	}
+
+	res, err := def.Evaluate(bars, input.Params)
+	if err != nil {
+		return strategyError(err).Result()
+	}
+
+	result := &StrategyEvaluation{
+		Strategy:     def.Name,
+		Description:  def.Description,
+		Source:       def.Source,
+		Symbol:       pair.Symbol,
+		Signal:       res.Signal,
+		Matched:      res.Matched,
+		Rules:        res.Rules,
+		CurrentPrice: bars.Close[len(bars.Close)-1],
+		Params:       res.Params,
+		Candles:      len(bars.Close),
+	}
+	for i := range result.Rules {
+		if v := result.Rules[i].Value; v != nil {
+			rounded := utils.Round(*v)
+			result.Rules[i].Value = &rounded
+		}
+	}
+
+	result.SuggestedEntry = pair.SnapPrice(res.Entry, symbols.RoundUp)
+	if res.Stop > 0 {
+		result.SuggestedStop = pair.SnapPrice(res.Stop, symbols.RoundDown)
+		if res.Stop < res.Entry {
+			result.Warnings = riskChangeWarning("risk", (res.Entry-res.Stop)/res.Entry*100,
+				(result.SuggestedEntry-result.SuggestedStop)/result.SuggestedEntry*100, "%")
+		}
+	}
+	if res.Target > 0 {
+		result.SuggestedTarget = pair.SnapPrice(res.Target, symbols.RoundDown)
+	}
+
+	summary := fmt.Sprintf("Strategy %s on %d candles", def.Name, result.Candles)
+	if result.Symbol != "" {
+		summary += " of " + result.Symbol
+	}
+	summary += fmt.Sprintf("\nSignal: %s\n\n", result.Signal)
+	for _, r := range result.Rules {
+		mark := "❌"
+		if r.Passed {
+			mark = "✅"
+		}
+		label := r.Rule
+		if r.Name != "" {
+			label = r.Name + ": " + r.Rule
+		}
+		summary += fmt.Sprintf("%s %s", mark, label)
+		if r.Value != nil {
+			summary += fmt.Sprintf(" (%.4g)", *r.Value)
+		}
+		summary += "\n"
+	}
+	if result.Matched {
+		summary += fmt.Sprintf("\nSuggested Entry: %.2f", result.SuggestedEntry)
+		if result.SuggestedStop > 0 {
+			summary += fmt.Sprintf(" | Stop: %.2f", result.SuggestedStop)
+		}
+		if result.SuggestedTarget > 0 {
+			summary += fmt.Sprintf(" | Target: %.2f", result.SuggestedTarget)
+		}
+		for _, w := range result.Warnings {
+			summary += "\n⚠️ " + w
+		}
+	}
+
+	return utils.ArtifactsResult(summary, result)
+}
+
+// runSignalStrategy evaluates the definition behind a signal tool with the
+// tool's own arguments, keeping only those the definition declares.
+func runSignalStrategy(name string, candles []OHLCData, params map[string]float64) (*strategy.Result, *utils.ToolError) {
+	def, ok := strategy.Get(name)
+	if !ok {
+		return nil, utils.NewError(utils.CodeInternal, "strategy %s is not loaded", name)
+	}
+	res, err := def.Evaluate(candleBars(candles), def.Known(params))
+	if err != nil {
+		return nil, strategyError(err)
+	}
+	return res, nil
+}
+
+func strategyError(err error) *utils.ToolError {
+	if errors.Is(err, strategy.ErrInsufficientData) {
+		return utils.NewError(utils.CodeInsufficientData, "%s", err.Error())
+	}
+	return utils.NewError(utils.CodeInvalidArgument, "%s", err.Error())
+}
+
+func candleBars(candles []OHLCData) strategy.Bars {
+	b := strategy.Bars{
+		High:   make(strategy.Series, len(candles)),
+		Low:    make(strategy.Series, len(candles)),
+		Close:  make(strategy.Series, len(candles)),
+		Volume: make(strategy.Series, len(candles)),
+	}
+	open := make(strategy.Series, len(candles))
+	hasOpen := true
+	for i, c := range candles {
+		b.High[i], b.Low[i], b.Close[i], b.Volume[i] = c.High, c.Low, c.Close, c.Volume
+		open[i] = c.Open
+		hasOpen = hasOpen && c.Open > 0
+	}
+	if hasOpen {
+		b.Open = open
+	}
+	return b
+}
+
+func historyBars(h *market.History) strategy.Bars {
+	return strategy.Bars{Open: h.Open, High: h.High, Low: h.Low, Close: h.Close, Volume: h.Volume}
+}
+
+func historyCandles(h *market.History) []OHLCData {
+	candles := make([]OHLCData, len(h.Close))
+	for i := range h.Close {
+		candles[i] = OHLCData{High: h.High[i], Low: h.Low[i], Close: h.Close[i], Volume: h.Volume[i]}
+	}
+	return candles
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/tracing"
 	"gokub/utils"
 
 	"github.com/dvgamerr-app/go-bitkub/market"
@@ -11,6 +12,7 @@ import (
 )
 
 type FeeSchedule struct {
+	Account        string  `json:"account,omitempty"`
 	TradingCredits float64 `json:"trading_credits"`
 	Level          string  `json:"level"`
 	MakerFee       float64 `json:"maker_fee"`
@@ -21,21 +23,36 @@ type FeeSchedule struct {
 func NewFeeScheduleTool() mcp.Tool {
 	return mcp.NewTool("get_fee_schedule",
 		mcp.WithDescription("Get trading fee schedule (maker/taker rates) based on user's trading level and credits"),
+		utils.WithScope(utils.ScopeAccount),
+		withAccountArg(),
+		mcp.WithOutputSchema[FeeSchedule](),
 	)
 }
 
 func FeeScheduleHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	log.Debug().Msg("Getting fee schedule")
+	var input AccountInput
+	if err := utils.BindArgs(NewFeeScheduleTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for fee schedule")
+		return err.Result()
+	}
+
+	log.Debug().Ctx(ctx).Msg("Getting fee schedule")
 
-	credits, err := market.GetTradingCredits()
+	var credits float64
+	account, err := useAccount(ctx, input.Account, func() (err error) {
+		credits, err = tracing.Exchange(ctx, "market.GetTradingCredits", "", market.GetTradingCredits)
+		return err
+	})
 	if err != nil {
-		log.Warn().Err(err).Msg("Failed to get trading credits")
-		return utils.ErrorResult(fmt.Sprintf("error: %v", err))
+		log.Warn().Ctx(ctx).Err(err).Msg("Failed to get trading credits")
+		return utils.ExchangeErrorResult(err)
 	}
 
 	fee := determineFeeSchedule(credits)
+	fee.Account = account
 
-	return utils.ArtifactsResult(fmt.Sprintf(`💰 Fee Schedule: Trading Credits %.2f | Level: %s | Maker Fee: %.2f%% | Taker Fee: %.2f%% | %s`,
+	return utils.ArtifactsResult(fmt.Sprintf(`💰 Fee Schedule (%s): Trading Credits %.2f | Level: %s | Maker Fee: %.2f%% | Taker Fee: %.2f%% | %s`,
+		fee.Account,
 		fee.TradingCredits,
 		fee.Level,
 		fee.MakerFee*100,
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/audit"
+	"gokub/auth"
+	"gokub/utils"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+type AuditLogOutput struct {
+	Count   int            `json:"count"`
+	Entries []*audit.Entry `json:"entries"`
+}
+
+type AuditLogInput struct {
+	Tool         string `json:"tool"`
+	SessionID    string `json:"session_id"`
+	ErrorsOnly   bool   `json:"errors_only"`
+	SinceMinutes int    `json:"since_minutes"`
+	Limit        int    `json:"limit"`
+}
+
+func NewAuditLogTool() mcp.Tool {
+	return mcp.NewTool("get_audit_log",
+		mcp.WithDescription("Query recent entries of the append-only tool call audit log (newest last). Tokens without the admin scope only see their own calls"),
+		utils.WithScope(utils.ScopeAccount),
+		mcp.WithString("tool",
+			mcp.Description("Only return calls to this tool name"),
+		),
+		mcp.WithString("session_id",
+			mcp.Description("Only return calls from this client session"),
+		),
+		mcp.WithBoolean("errors_only",
+			mcp.DefaultBool(false),
+			mcp.Description("Only return calls that failed (default: false)"),
+		),
+		utils.WithInteger("since_minutes",
+			mcp.Min(1),
+			mcp.Description("Only return calls made within the last N minutes"),
+		),
+		utils.WithInteger("limit",
+			mcp.Description("Maximum number of entries to return (default: 50, max: 500)"),
+			mcp.DefaultNumber(50),
+			mcp.Min(1),
+			mcp.Max(500),
+		),
+		mcp.WithOutputSchema[AuditLogOutput](),
+	)
+}
+
+func AuditLogHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input AuditLogInput
+	if err := utils.BindArgs(NewAuditLogTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for audit log")
+		return err.Result()
+	}
+
+	query := audit.Query{
+		Tool:       input.Tool,
+		SessionID:  input.SessionID,
+		ErrorsOnly: input.ErrorsOnly,
+		Limit:      input.Limit,
+	}
+
+	// Callers see their own calls unless their token carries the admin scope.
+	if p := auth.FromContext(ctx); p != nil && !p.HasScope(utils.ScopeAdmin) {
+		query.Principal, query.Accounts = p.Name, p.Accounts
+	}
+
+	if input.SinceMinutes > 0 {
+		query.Since = time.Now().Add(-time.Duration(input.SinceMinutes) * time.Minute).UnixMilli()
+	}
+
+	entries, err := audit.Recent(query)
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Failed to read audit log")
+		return utils.NewError(utils.CodeInternal, "read audit log: %v", err).Result()
+	}
+
+	result := fmt.Sprintf("📜 Audit Log: %d entries\n", len(entries))
+	for _, e := range entries {
+		status := "ok"
+		if e.Error != "" {
+			status = "error: " + e.Error
+		}
+		result += fmt.Sprintf("%s %s (%dms) %s\n",
+			time.UnixMilli(e.Timestamp).Format(time.RFC3339), e.Tool, e.LatencyMS, status)
+	}
+
+	return utils.ArtifactsResult(result, AuditLogOutput{
+		Count:   len(entries),
+		Entries: entries,
+	})
+}
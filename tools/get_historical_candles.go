@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/tracing"
 	"gokub/utils"
 	"strings"
 	"time"
@@ -21,6 +22,12 @@ type Candle struct {
 	Volume    float64 `json:"volume"`
 }
 
+type HistoricalCandlesOutput struct {
+	Symbol     string    `json:"symbol"`
+	Resolution int       `json:"resolution"`
+	Candles    []*Candle `json:"candles"`
+}
+
 var validResolutions map[int]string = map[int]string{
 	1:    "1",
 	5:    "5",
@@ -30,60 +37,68 @@ var validResolutions map[int]string = map[int]string{
 	1440: "1D",
 }
 
+type HistoricalCandlesInput struct {
+	Symbol     string `json:"symbol"`
+	Resolution int    `json:"resolution"`
+	Limit      int    `json:"limit"`
+}
+
 func NewHistoricalCandlesTool() mcp.Tool {
 	return mcp.NewTool("get_historical_candles",
 		mcp.WithDescription(`Get historical candlestick/OHLCV data for a symbol with specified timeframe and limit`),
-		mcp.WithString("symbol",
-			mcp.Required(),
-			mcp.Description("Trading pair symbol (e.g., btc_thb, eth_thb). Use lowercase with underscore."),
-		),
-		mcp.WithNumber("resolution",
+		withSymbolArg(),
+		utils.WithInteger("resolution",
+			mcp.DefaultNumber(60),
+			utils.EnumNumbers(1, 5, 15, 60, 240, 1440),
 			mcp.Description("Timeframe resolution in minutes (1, 5, 15, 60, 240, 1440). Default: 60"),
 		),
-		mcp.WithNumber("limit",
+		utils.WithInteger("limit",
+			mcp.DefaultNumber(100),
+			mcp.Min(1),
+			mcp.Max(1000),
 			mcp.Description("Number of candles to retrieve (1-1000). Default: 100"),
 		),
+		mcp.WithOutputSchema[HistoricalCandlesOutput](),
 	)
 }
 
 func HistoricalCandlesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for historical candles")
-		return utils.ErrorResult("invalid arguments")
+	var input HistoricalCandlesInput
+	if err := utils.BindArgs(NewHistoricalCandlesTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for historical candles")
+		return err.Result()
 	}
 
-	symbol := strings.ToUpper(utils.GetStringArg(args, "symbol"))
-	resolution := utils.GetIntArg(args, "resolution", 60)
-	limit := utils.GetIntArg(args, "limit", 100)
-
-	if limit < 1 || limit > 1000 {
-		return utils.ErrorResult("limit must be between 1 and 1000")
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
 	}
+	symbol := pair.Symbol
+	resolution := input.Resolution
+	limit := input.Limit
 
-	resolutionStr, ok := validResolutions[resolution]
-	if !ok {
-		return utils.ErrorResult("invalid resolution. Use: 1, 5, 15, 60, 240, or 1440")
-	}
+	resolutionStr := validResolutions[resolution]
 
 	now := time.Now().Unix()
 	from := now - int64(limit*resolution*60)
 
-	candles, err := market.GetHistory(market.HistoryRequest{
-		Symbol:     symbol,
-		Resolution: resolutionStr,
-		From:       from,
-		To:         now,
+	candles, err := tracing.Exchange(ctx, "market.GetHistory", symbol, func() (*market.History, error) {
+		return market.GetHistory(market.HistoryRequest{
+			Symbol:     strings.ToUpper(symbol),
+			Resolution: resolutionStr,
+			From:       from,
+			To:         now,
+		})
 	})
 
 	if err != nil {
-		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to get historical candles")
-		return utils.ErrorResult(fmt.Sprintf("error: %v", err))
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", symbol).Msg("Failed to get historical candles")
+		return utils.ExchangeErrorResult(err)
 	}
 
 	if len(candles.Close) == 0 {
-		log.Warn().Str("symbol", symbol).Msg("No candle data found")
-		return utils.ErrorResult("no data: " + symbol)
+		log.Warn().Ctx(ctx).Str("symbol", symbol).Msg("No candle data found")
+		return utils.NewError(utils.CodeNotFoundSymbol, "no data for symbol %s", symbol).With("symbol", symbol).Result()
 	}
 
 	dataLen := min(limit, len(candles.Close))
@@ -100,7 +115,7 @@ func HistoricalCandlesHandler(ctx context.Context, request mcp.CallToolRequest)
 		}
 	}
 
-	summary := fmt.Sprintf("Retrieved %d candles for %s (%dm timeframe)\n\n", dataLen, symbol, resolution)
+	summary := fmt.Sprintf("Retrieved %d candles for %s (%dm timeframe)\n\n", dataLen, strings.ToUpper(symbol), resolution)
 	summary += "Timestamp,Open,High,Low,Close,Volume\n"
 	for _, candle := range result {
 		summary += fmt.Sprintf("%d,%.2f,%.2f,%.2f,%.2f,%.2f\n",
@@ -113,5 +128,9 @@ func HistoricalCandlesHandler(ctx context.Context, request mcp.CallToolRequest)
 		)
 	}
 
-	return utils.ArtifactsResult(summary, map[string]any{"candles": result})
+	return utils.ArtifactsResult(summary, HistoricalCandlesOutput{
+		Symbol:     symbol,
+		Resolution: resolution,
+		Candles:    result,
+	})
 }
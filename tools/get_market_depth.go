@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/tracing"
 	"gokub/utils"
 	"strings"
 
@@ -11,36 +12,58 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+type MarketDepthInput struct {
+	Symbol string `json:"symbol"`
+	Limit  int    `json:"limit"`
+}
+
+type DepthLevel struct {
+	Price  float64 `json:"price"`
+	Amount float64 `json:"amount"`
+}
+
+type MarketDepthOutput struct {
+	Symbol string        `json:"symbol"`
+	Bids   []*DepthLevel `json:"bids"`
+	Asks   []*DepthLevel `json:"asks"`
+}
+
 func NewMarketDepthTool() mcp.Tool {
 	return mcp.NewTool("get_market_depth",
 		mcp.WithDescription("Get market depth (order book) showing bids and asks for a symbol"),
-		mcp.WithString("symbol",
-			mcp.Required(),
-			mcp.Description("Trading pair symbol (e.g., btc_thb, eth_thb)"),
-		),
-		mcp.WithNumber("limit",
+		withSymbolArg(),
+		utils.WithInteger("limit",
 			mcp.Description("Number of orders to return (default: 10, max: 100)"),
 			mcp.DefaultNumber(10),
+			mcp.Min(1),
+			mcp.Max(100),
 		),
+		mcp.WithOutputSchema[MarketDepthOutput](),
 	)
 }
 
 func MarketDepthHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for market depth")
-		return utils.ErrorResult("invalid arguments")
+	var input MarketDepthInput
+	if err := utils.BindArgs(NewMarketDepthTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for market depth")
+		return err.Result()
 	}
 
-	symbol := strings.ToLower(utils.GetStringArg(args, "symbol"))
-	limit := min(utils.GetIntArg(args, "limit", 10), 100)
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+	symbol := pair.Symbol
+	limit := input.Limit
 
-	log.Debug().Str("symbol", symbol).Int("limit", limit).Msg("Getting market depth")
+	log.Debug().Ctx(ctx).Str("symbol", symbol).Int("limit", limit).Msg("Getting market depth")
 
-	depth, err := market.GetDepth(symbol, limit)
+	depth, err := tracing.Exchange(ctx, "market.GetDepth", symbol, func() (*market.Depth, error) {
+		return market.GetDepth(symbol, limit)
+	})
 	if err != nil {
-		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to get market depth")
-		return utils.ErrorResult(fmt.Sprintf("error: %v", err))
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", symbol).Msg("Failed to get market depth")
+		return utils.ExchangeErrorResult(err)
 	}
 
 	result := fmt.Sprintf("📊 %s Depth:\nASK:\n", strings.ToUpper(symbol))
@@ -54,5 +77,20 @@ func MarketDepthHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.
 		result += fmt.Sprintf("%.2f | %.8f\n", depth.Bids[i][0], depth.Bids[i][1])
 	}
 
-	return utils.ArtifactsResult(result, depth)
+	return utils.ArtifactsResult(result, MarketDepthOutput{
+		Symbol: symbol,
+		Bids:   depthLevels(depth.Bids),
+		Asks:   depthLevels(depth.Asks),
+	})
+}
+
+func depthLevels(entries [][]float64) []*DepthLevel {
+	levels := make([]*DepthLevel, 0, len(entries))
+	for _, entry := range entries {
+		if len(entry) < 2 {
+			continue
+		}
+		levels = append(levels, &DepthLevel{Price: entry[0], Amount: entry[1]})
+	}
+	return levels
 }
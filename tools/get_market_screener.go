@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/tracing"
 	"gokub/utils"
 	"sort"
 	"strings"
@@ -10,65 +11,97 @@ import (
 	"github.com/dvgamerr-app/go-bitkub/market"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type ScreenerResult struct {
-	Symbol         string
-	Volume24h      float64
-	Spread         float64
-	SpreadPercent  float64
-	BidLiquidity   float64
-	AskLiquidity   float64
-	TotalLiquidity float64
-	Score          float64
-	LastPrice      float64
+	Symbol         string  `json:"symbol"`
+	Volume24h      float64 `json:"volume_24h"`
+	Spread         float64 `json:"spread"`
+	SpreadPercent  float64 `json:"spread_percent"`
+	BidLiquidity   float64 `json:"bid_liquidity"`
+	AskLiquidity   float64 `json:"ask_liquidity"`
+	TotalLiquidity float64 `json:"total_liquidity"`
+	Score          float64 `json:"score"`
+	LastPrice      float64 `json:"last_price"`
+}
+
+type ScreenerFilters struct {
+	MinVolume24h float64 `json:"min_volume_24h"`
+	MaxSpread    float64 `json:"max_spread"`
+	MinDepth     float64 `json:"min_depth"`
+}
+
+type ScreenerOutput struct {
+	Filters      ScreenerFilters   `json:"filters"`
+	ResultsCount int               `json:"results_count"`
+	Results      []*ScreenerResult `json:"results"`
+}
+
+type ScreenerInput struct {
+	MinVolume24h float64 `json:"min_volume_24h"`
+	MaxSpread    float64 `json:"max_spread"`
+	MinDepth     float64 `json:"min_depth"`
+	Limit        int     `json:"limit"`
 }
 
 func NewGetMarketScreenerTool() mcp.Tool {
 	return mcp.NewTool("get_market_screener",
 		mcp.WithDescription("Screen and rank trading pairs by volume, spread, and liquidity depth. Returns top pairs suitable for trading"),
 		mcp.WithNumber("min_volume_24h",
+			mcp.DefaultNumber(1000000),
+			mcp.Min(0),
 			mcp.Description("Minimum 24h volume in THB (default: 1000000 = 1M THB)"),
 		),
 		mcp.WithNumber("max_spread",
+			mcp.DefaultNumber(2.0),
+			utils.ExclusiveMin(0),
 			mcp.Description("Maximum allowed spread percentage (default: 2.0%)"),
 		),
 		mcp.WithNumber("min_depth",
+			mcp.DefaultNumber(50000),
+			mcp.Min(0),
 			mcp.Description("Minimum liquidity depth in THB within ±1% (default: 50000 THB)"),
 		),
-		mcp.WithNumber("limit",
+		utils.WithInteger("limit",
+			mcp.DefaultNumber(10),
+			mcp.Min(1),
+			mcp.Max(20),
 			mcp.Description("Number of top results to return (default: 10, max: 20)"),
 		),
+		mcp.WithOutputSchema[ScreenerOutput](),
 	)
 }
 
 func GetMarketScreenerHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Err(err).Msg("Failed to validate market screener arguments")
-		return utils.ErrorResult("failed to validate arguments: invalid format or missing required fields")
+	var input ScreenerInput
+	if err := utils.BindArgs(NewGetMarketScreenerTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for market screener")
+		return err.Result()
 	}
 
-	minVolume := utils.GetFloat64Arg(args, "min_volume_24h", 1000000.0)
-	maxSpread := utils.GetFloat64Arg(args, "max_spread", 2.0)
-	minDepth := utils.GetFloat64Arg(args, "min_depth", 50000.0)
-	limit := utils.GetFloat64Arg(args, "limit", 10)
+	minVolume := input.MinVolume24h
+	maxSpread := input.MaxSpread
+	minDepth := input.MinDepth
+	limit := input.Limit
 
-	toolOutput, err := SymbolsHandler(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+	symbolsCtx, span := tracing.Start(ctx, "screener.fetch_symbols")
+	toolOutput, err := SymbolsHandler(symbolsCtx, mcp.CallToolRequest{Params: mcp.CallToolParams{
 		Name:      "get_symbols",
 		Arguments: map[string]any{"limit": limit},
 	}})
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to fetch symbols from market")
-		return utils.ErrorResult("failed to fetch symbols from market")
+	span.End()
+	if err != nil || toolOutput.IsError {
+		log.Error().Ctx(ctx).Err(err).Msg("Failed to fetch symbols from market")
+		return toolOutput, err
 	}
 
-	toolResults, ok := toolOutput.StructuredContent.(map[string][]*SymbolInfo)
+	toolResults, ok := toolOutput.StructuredContent.(SymbolsOutput)
 	if !ok {
-		log.Warn().Msg("Invalid symbol data structure received")
-		return utils.ErrorResult("failed to parse symbol data: unexpected structure format")
+		log.Warn().Ctx(ctx).Msg("Invalid symbol data structure received")
+		return utils.ErrorResult(utils.CodeInternal, "failed to parse symbol data: unexpected structure format")
 	}
-	symbolsInfo := toolResults["symbols"]
+	symbolsInfo := toolResults.Symbols
 
 	results := []*ScreenerResult{}
 	stats := map[string]int{
@@ -82,6 +115,7 @@ func GetMarketScreenerHandler(ctx context.Context, request mcp.CallToolRequest)
 		"passed":      0,
 	}
 
+	filterCtx, span := tracing.Start(ctx, "screener.filter", attribute.Int("screener.symbols", len(symbolsInfo)))
 	for _, sym := range symbolsInfo {
 		if sym.Volume24h < minVolume {
 			stats["low_volume"]++
@@ -101,7 +135,9 @@ func GetMarketScreenerHandler(ctx context.Context, request mcp.CallToolRequest)
 			continue
 		}
 
-		depth, err := market.GetDepth(sym.Symbol, 100)
+		depth, err := tracing.Exchange(filterCtx, "market.GetDepth", sym.Symbol, func() (*market.Depth, error) {
+			return market.GetDepth(sym.Symbol, 100)
+		})
 		if err != nil {
 			stats["depth_fail"]++
 			continue
@@ -157,6 +193,9 @@ func GetMarketScreenerHandler(ctx context.Context, request mcp.CallToolRequest)
 		stats["passed"]++
 	}
 
+	span.SetAttributes(attribute.Int("screener.passed", stats["passed"]))
+	span.End()
+
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].Score > results[j].Score
 	})
@@ -177,15 +216,13 @@ func GetMarketScreenerHandler(ctx context.Context, request mcp.CallToolRequest)
 		}
 	}
 
-	data := map[string]any{
-		"filters": map[string]any{
-			"min_volume_24h": minVolume,
-			"max_spread":     maxSpread,
-			"min_depth":      minDepth,
+	return utils.ArtifactsResult(result, ScreenerOutput{
+		Filters: ScreenerFilters{
+			MinVolume24h: minVolume,
+			MaxSpread:    maxSpread,
+			MinDepth:     minDepth,
 		},
-		"results_count": len(results),
-		"results":       results,
-	}
-
-	return utils.ArtifactsResult(result, data)
+		ResultsCount: len(results),
+		Results:      results,
+	})
 }
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/tracing"
 	"gokub/utils"
 	"strconv"
 	"strings"
@@ -12,50 +13,88 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+type OpenOrdersInput struct {
+	Symbol string `json:"symbol"`
+	AccountInput
+}
+
+type OpenOrder struct {
+	ID        string  `json:"id"`
+	Side      string  `json:"side"`
+	Type      string  `json:"type"`
+	Rate      float64 `json:"rate"`
+	Amount    float64 `json:"amount"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+type OpenOrdersOutput struct {
+	Account string       `json:"account"`
+	Symbol  string       `json:"symbol"`
+	Orders  []*OpenOrder `json:"orders"`
+}
+
 func NewOpenOrdersTool() mcp.Tool {
 	return mcp.NewTool("get_my_open_orders",
 		mcp.WithDescription("Get your currently open orders for a trading pair"),
-		mcp.WithString("symbol",
-			mcp.Required(),
-			mcp.Description("Trading pair symbol (e.g., btc_thb, eth_thb)"),
-		),
+		utils.WithScope(utils.ScopeAccount),
+		withSymbolArg(),
+		withAccountArg(),
+		mcp.WithOutputSchema[OpenOrdersOutput](),
 	)
 }
 
 func OpenOrdersHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for open orders")
-		return utils.ErrorResult("invalid arguments")
+	var input OpenOrdersInput
+	if err := utils.BindArgs(NewOpenOrdersTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for open orders")
+		return err.Result()
 	}
 
-	symbol := strings.ToLower(utils.GetStringArg(args, "symbol"))
-	log.Debug().Str("symbol", symbol).Msg("Getting open orders")
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+	symbol := pair.Symbol
+	log.Debug().Ctx(ctx).Str("symbol", symbol).Msg("Getting open orders")
 
-	orders, err := market.GetOpenOrders(symbol)
+	var orders []market.Order
+	account, err := useAccount(ctx, input.Account, func() (err error) {
+		orders, err = tracing.Exchange(ctx, "market.GetOpenOrders", symbol, func() ([]market.Order, error) {
+			return market.GetOpenOrders(symbol)
+		})
+		return err
+	})
 	if err != nil {
-		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to get open orders")
-		return utils.ErrorResult(fmt.Sprintf("error: %v", err))
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", symbol).Msg("Failed to get open orders")
+		return utils.ExchangeErrorResult(err)
+	}
+
+	output := OpenOrdersOutput{
+		Account: account,
+		Symbol:  symbol,
+		Orders:  []*OpenOrder{},
 	}
 
 	if len(orders) == 0 {
-		log.Debug().Str("symbol", symbol).Msg("No open orders found")
-		return utils.TextResult(fmt.Sprintf("No orders: %s", strings.ToUpper(symbol)))
+		log.Debug().Ctx(ctx).Str("symbol", symbol).Msg("No open orders found")
+		return utils.ArtifactsResult(fmt.Sprintf("No orders: %s (%s)", strings.ToUpper(symbol), account), output)
 	}
 
-	result := fmt.Sprintf("📋 %s Orders:\n", strings.ToUpper(symbol))
+	result := fmt.Sprintf("📋 %s Orders (%s):\n", strings.ToUpper(symbol), account)
 	for i, order := range orders {
-		result += fmt.Sprintf("%d. %s", i+1, order.ID)
-
-		if rate, err := strconv.ParseFloat(order.Rate, 64); err == nil {
-			result += fmt.Sprintf(" | %s %.2f", strings.ToUpper(order.Side), rate)
-		}
-		if amount, err := strconv.ParseFloat(order.Amount, 64); err == nil {
-			result += fmt.Sprintf(" x %.8f", amount)
-		}
+		rate, _ := strconv.ParseFloat(order.Rate, 64)
+		amount, _ := strconv.ParseFloat(order.Amount, 64)
+		output.Orders = append(output.Orders, &OpenOrder{
+			ID:        order.ID,
+			Side:      strings.ToLower(order.Side),
+			Type:      order.Type,
+			Rate:      rate,
+			Amount:    amount,
+			Timestamp: order.Timestamp,
+		})
 
-		result += "\n"
+		result += fmt.Sprintf("%d. %s | %s %.2f x %.8f\n", i+1, order.ID, strings.ToUpper(order.Side), rate, amount)
 	}
 
-	return utils.ArtifactsResult(result, orders)
+	return utils.ArtifactsResult(result, output)
 }
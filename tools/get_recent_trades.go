@@ -0,0 +1,244 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/tracing"
+	"gokub/utils"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const tradesURL = "https://api.bitkub.com/api/v3/market/trades"
+
+type RecentTradesInput struct {
+	Symbol  string `json:"symbol"`
+	Limit   int    `json:"limit"`
+	Largest int    `json:"largest"`
+}
+
+type Trade struct {
+	Timestamp int64   `json:"timestamp"`
+	Price     float64 `json:"price"`
+	Amount    float64 `json:"amount"`
+	ValueTHB  float64 `json:"value_thb"`
+	Side      string  `json:"side"`
+}
+
+type CVDPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Delta     float64 `json:"delta"`
+}
+
+type TradeStats struct {
+	Count         int     `json:"count"`
+	BuyCount      int     `json:"buy_count"`
+	SellCount     int     `json:"sell_count"`
+	BuyVolume     float64 `json:"buy_volume"`
+	SellVolume    float64 `json:"sell_volume"`
+	BuyTHB        float64 `json:"buy_thb"`
+	SellTHB       float64 `json:"sell_thb"`
+	BuyRatio      float64 `json:"buy_ratio"`
+	VWAP          float64 `json:"vwap"`
+	From          int64   `json:"from"`
+	To            int64   `json:"to"`
+	WindowSeconds int64   `json:"window_seconds"`
+}
+
+type RecentTradesOutput struct {
+	Symbol  string      `json:"symbol"`
+	Stats   TradeStats  `json:"stats"`
+	Largest []*Trade    `json:"largest"`
+	CVD     []*CVDPoint `json:"cvd"`
+	Trades  []*Trade    `json:"trades"`
+}
+
+func NewRecentTradesTool() mcp.Tool {
+	return mcp.NewTool("get_recent_trades",
+		mcp.WithDescription("Get the recent trades tape for a symbol with aggressor-side statistics: buy/sell volume, VWAP, largest prints and cumulative volume delta"),
+		withSymbolArg(),
+		utils.WithInteger("limit",
+			mcp.DefaultNumber(100),
+			mcp.Min(1),
+			mcp.Max(1000),
+			mcp.Description("Number of most recent trades to fetch (default: 100)"),
+		),
+		utils.WithInteger("largest",
+			mcp.DefaultNumber(5),
+			mcp.Min(0),
+			mcp.Max(50),
+			mcp.Description("Number of largest prints by THB value to report (default: 5)"),
+		),
+		mcp.WithOutputSchema[RecentTradesOutput](),
+	)
+}
+
+func RecentTradesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input RecentTradesInput
+	if err := utils.BindArgs(NewRecentTradesTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for recent trades")
+		return err.Result()
+	}
+
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+	symbol := pair.Symbol
+
+	log.Debug().Ctx(ctx).Str("symbol", symbol).Int("limit", input.Limit).Msg("Getting recent trades")
+
+	trades, err := tracing.Request(ctx, "market.GetTrades", symbol, func(ctx context.Context) ([]*Trade, error) {
+		return fetchTrades(ctx, symbol, input.Limit)
+	})
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", symbol).Msg("Failed to get recent trades")
+		return utils.ExchangeErrorResult(err)
+	}
+
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Timestamp < trades[j].Timestamp
+	})
+
+	output := RecentTradesOutput{
+		Symbol:  symbol,
+		Stats:   tradeStats(trades),
+		Largest: largestTrades(trades, input.Largest),
+		CVD:     cumulativeDelta(trades),
+		Trades:  trades,
+	}
+
+	if output.Stats.Count == 0 {
+		return utils.ArtifactsResult(fmt.Sprintf("No recent trades: %s", strings.ToUpper(symbol)), output)
+	}
+
+	st := output.Stats
+	result := fmt.Sprintf("🧾 %s Tape: %d trades over %s\n", strings.ToUpper(symbol), st.Count, time.Duration(st.WindowSeconds)*time.Second)
+	result += fmt.Sprintf("Buy: %d trades %.8g (%.2f THB) | Sell: %d trades %.8g (%.2f THB)\n", st.BuyCount, st.BuyVolume, st.BuyTHB, st.SellCount, st.SellVolume, st.SellTHB)
+	result += fmt.Sprintf("Buy Ratio: %.1f%% | VWAP: %.8g | CVD: %+.8g\n", st.BuyRatio*100, st.VWAP, output.CVD[len(output.CVD)-1].Delta)
+	for _, t := range output.Largest {
+		result += fmt.Sprintf("Large: %s %.8g @ %.8g (%.2f THB) %s\n", strings.ToUpper(t.Side), t.Amount, t.Price, t.ValueTHB, time.Unix(t.Timestamp, 0).UTC().Format(time.RFC3339))
+	}
+
+	return utils.ArtifactsResult(strings.TrimSuffix(result, "\n"), output)
+}
+
+// fetchTrades reads the public trades endpoint. Each entry is
+// [timestamp, rate, amount, side] where side is the taker's side.
+func fetchTrades(ctx context.Context, symbol string, limit int) ([]*Trade, error) {
+	query := url.Values{"sym": {symbol}, "lmt": {strconv.Itoa(limit)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tradesURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("trades request failed: %s", resp.Status)
+	}
+
+	var body struct {
+		Error  int     `json:"error"`
+		Result [][]any `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode trades: %w", err)
+	}
+	if body.Error != 0 {
+		return nil, fmt.Errorf("bitkub error %d", body.Error)
+	}
+
+	trades := make([]*Trade, 0, len(body.Result))
+	for _, entry := range body.Result {
+		if len(entry) < 4 {
+			continue
+		}
+		ts, _ := entry[0].(float64)
+		price, _ := entry[1].(float64)
+		amount, _ := entry[2].(float64)
+		side, _ := entry[3].(string)
+
+		trades = append(trades, &Trade{
+			Timestamp: int64(ts),
+			Price:     price,
+			Amount:    amount,
+			ValueTHB:  utils.Round(price*amount, 2),
+			Side:      strings.ToLower(side),
+		})
+	}
+	return trades, nil
+}
+
+func tradeStats(trades []*Trade) TradeStats {
+	st := TradeStats{Count: len(trades)}
+	if len(trades) == 0 {
+		return st
+	}
+
+	totalAmount, totalTHB := 0.0, 0.0
+	for _, t := range trades {
+		totalAmount += t.Amount
+		totalTHB += t.Price * t.Amount
+		if t.Side == "buy" {
+			st.BuyCount++
+			st.BuyVolume += t.Amount
+			st.BuyTHB += t.Price * t.Amount
+		} else {
+			st.SellCount++
+			st.SellVolume += t.Amount
+			st.SellTHB += t.Price * t.Amount
+		}
+	}
+
+	if totalAmount > 0 {
+		st.VWAP = utils.Round(totalTHB / totalAmount)
+	}
+	if totalTHB > 0 {
+		st.BuyRatio = utils.Round(st.BuyTHB/totalTHB, 4)
+	}
+	st.BuyVolume, st.SellVolume = utils.Round(st.BuyVolume), utils.Round(st.SellVolume)
+	st.BuyTHB, st.SellTHB = utils.Round(st.BuyTHB, 2), utils.Round(st.SellTHB, 2)
+	st.From, st.To = trades[0].Timestamp, trades[len(trades)-1].Timestamp
+	st.WindowSeconds = st.To - st.From
+
+	return st
+}
+
+func largestTrades(trades []*Trade, n int) []*Trade {
+	sorted := make([]*Trade, len(trades))
+	copy(sorted, trades)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ValueTHB > sorted[j].ValueTHB
+	})
+	return sorted[:min(n, len(sorted))]
+}
+
+// cumulativeDelta is the running buy minus sell volume, oldest trade first.
+func cumulativeDelta(trades []*Trade) []*CVDPoint {
+	points := make([]*CVDPoint, 0, len(trades))
+	delta := 0.0
+	for _, t := range trades {
+		if t.Side == "buy" {
+			delta += t.Amount
+		} else {
+			delta -= t.Amount
+		}
+		points = append(points, &CVDPoint{Timestamp: t.Timestamp, Delta: utils.Round(delta)})
+	}
+	return points
+}
@@ -3,6 +3,8 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/symbols"
+	"gokub/tracing"
 	"gokub/utils"
 	"sort"
 	"strings"
@@ -12,14 +14,19 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+type SymbolsInput struct {
+	Limit int `json:"limit"`
+}
+
 func NewSymbolsTool() mcp.Tool {
 	return mcp.NewTool("get_symbols",
 		mcp.WithDescription("Get list of available trading pairs sorted by 24h volume (descending), limited to top N symbols"),
-		mcp.WithNumber("limit",
-			mcp.Required(),
+		utils.WithInteger("limit",
 			mcp.DefaultNumber(40),
+			mcp.Min(1),
 			mcp.Description("Maximum number of top symbols to return (sorted by 24h volume)"),
 		),
+		mcp.WithOutputSchema[SymbolsOutput](),
 	)
 }
 
@@ -30,40 +37,60 @@ type SymbolInfo struct {
 	Ask       float64 `json:"ask"`
 	Spread    float64 `json:"spread"`
 	Last      float64 `json:"last"`
+
+	Status       string  `json:"status,omitempty"`
+	TickSize     float64 `json:"tick_size,omitempty"`
+	LotSize      float64 `json:"lot_size,omitempty"`
+	MinQuoteSize float64 `json:"min_quote_size,omitempty"`
+}
+
+type SymbolsOutput struct {
+	Symbols []*SymbolInfo `json:"symbols"`
 }
 
 func SymbolsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	log.Debug().Msg("Getting available symbols")
+	log.Debug().Ctx(ctx).Msg("Getting available symbols")
 
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format")
-		return utils.ErrorResult("invalid arguments")
+	var input SymbolsInput
+	if err := utils.BindArgs(NewSymbolsTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for get symbols")
+		return err.Result()
 	}
 
-	limit := utils.GetIntArg(args, "limit", 40)
+	limit := input.Limit
 
-	tickers, err := market.GetTicker("")
+	tickers, err := tracing.Exchange(ctx, "market.GetTicker", "", func() ([]market.Ticker, error) {
+		return market.GetTicker("")
+	})
 	if err != nil {
-		log.Warn().Err(err).Msg("Failed to get symbols")
-		return utils.ErrorResult(fmt.Sprintf("error: %v", err))
+		log.Warn().Ctx(ctx).Err(err).Msg("Failed to get symbols")
+		return utils.ExchangeErrorResult(err)
 	}
 
 	result := "📋 Symbol: "
 	symbolInfos := []*SymbolInfo{}
 
 	for _, sym := range tickers {
-		symbol := strings.Replace(strings.ToUpper(sym.Symbol), "THB_", "", 1)
-		symbol = symbol + "_THB"
-
-		symbolInfos = append(symbolInfos, &SymbolInfo{
-			Symbol:    symbol,
+		info := &SymbolInfo{
+			Symbol:    symbols.Normalize(sym.Symbol),
 			Volume24h: sym.QuoteVolume,
 			Bid:       sym.HighestBid,
 			Ask:       sym.LowestAsk,
 			Last:      sym.Last,
 			Spread:    utils.Round(sym.LowestAsk - sym.HighestBid),
-		})
+		}
+
+		if pair, ok := symbols.Get(info.Symbol); ok {
+			if !pair.Active() {
+				continue
+			}
+			info.Status = pair.Status
+			info.TickSize = pair.TickSize
+			info.LotSize = pair.LotSize
+			info.MinQuoteSize = pair.MinQuoteSize
+		}
+
+		symbolInfos = append(symbolInfos, info)
 	}
 
 	sort.Slice(symbolInfos, func(i, j int) bool {
@@ -78,7 +105,5 @@ func SymbolsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 		result += fmt.Sprintf("%s ", strings.Replace(strings.ToUpper(sym.Symbol), "_THB", "", 1))
 	}
 
-	return utils.ArtifactsResult(result, map[string][]*SymbolInfo{
-		"symbols": symbolInfos,
-	})
+	return utils.ArtifactsResult(result, SymbolsOutput{Symbols: symbolInfos})
 }
@@ -3,43 +3,63 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/tracing"
 	"gokub/utils"
-	"strings"
 
 	"github.com/dvgamerr-app/go-bitkub/market"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/rs/zerolog/log"
 )
 
+type TickerInput struct {
+	Symbol string `json:"symbol"`
+}
+
+type TickerOutput struct {
+	Symbol        string  `json:"symbol"`
+	Last          float64 `json:"last"`
+	PercentChange float64 `json:"percent_change"`
+	High24h       float64 `json:"high_24h"`
+	Low24h        float64 `json:"low_24h"`
+	BaseVolume    float64 `json:"base_volume"`
+	QuoteVolume   float64 `json:"quote_volume"`
+	HighestBid    float64 `json:"highest_bid"`
+	LowestAsk     float64 `json:"lowest_ask"`
+}
+
 func NewTickerTool() mcp.Tool {
 	return mcp.NewTool("get_ticker",
 		mcp.WithDescription(`Get current market ticker/price for a cryptocurrency symbol (e.g., btc_thb, eth_thb)`),
-		mcp.WithString("symbol",
-			mcp.Required(),
-			mcp.Description("Trading pair symbol (e.g., btc_thb, eth_thb, ada_thb). Use lowercase with underscore."),
-		),
+		withSymbolArg(),
+		mcp.WithOutputSchema[TickerOutput](),
 	)
 }
 
 func TickerHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args, err := utils.ValidateArgs(request.Params.Arguments)
-	if err != nil {
-		log.Warn().Msg("Invalid arguments format for ticker")
-		return utils.ErrorResult("invalid arguments")
+	var input TickerInput
+	if err := utils.BindArgs(NewTickerTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for ticker")
+		return err.Result()
 	}
 
-	symbol := strings.ToLower(utils.GetStringArg(args, "symbol"))
-	log.Debug().Str("symbol", symbol).Msg("Getting ticker")
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+	symbol := pair.Symbol
+	log.Debug().Ctx(ctx).Str("symbol", symbol).Msg("Getting ticker")
 
-	tickers, err := market.GetTicker(symbol)
+	tickers, err := tracing.Exchange(ctx, "market.GetTicker", symbol, func() ([]market.Ticker, error) {
+		return market.GetTicker(symbol)
+	})
 	if err != nil {
-		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to get ticker")
-		return utils.ErrorResult(fmt.Sprintf("error: %v", err))
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", symbol).Msg("Failed to get ticker")
+		return utils.ExchangeErrorResult(err)
 	}
 
 	if len(tickers) == 0 {
-		log.Warn().Str("symbol", symbol).Msg("No ticker data found")
-		return utils.ErrorResult("no data: " + symbol)
+		log.Warn().Ctx(ctx).Str("symbol", symbol).Msg("No ticker data found")
+		return utils.NewError(utils.CodeNotFoundSymbol, "no data for symbol %s", symbol).With("symbol", symbol).Result()
 	}
 
 	ticker := tickers[0]
@@ -47,5 +67,15 @@ func TickerHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallT
 	result += fmt.Sprintf("24h: %.2f%% | H:%.2f L:%.2f ", ticker.PercentChange, ticker.High24hr, ticker.Low24hr)
 	result += fmt.Sprintf("Vol: %.2f | Bid:%.2f Ask:%.2f", ticker.BaseVolume, ticker.HighestBid, ticker.LowestAsk)
 
-	return utils.ArtifactsResult(result, ticker)
+	return utils.ArtifactsResult(result, TickerOutput{
+		Symbol:        symbol,
+		Last:          ticker.Last,
+		PercentChange: ticker.PercentChange,
+		High24h:       ticker.High24hr,
+		Low24h:        ticker.Low24hr,
+		BaseVolume:    ticker.BaseVolume,
+		QuoteVolume:   ticker.QuoteVolume,
+		HighestBid:    ticker.HighestBid,
+		LowestAsk:     ticker.LowestAsk,
+	})
 }
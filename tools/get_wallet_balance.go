@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"gokub/tracing"
 	"gokub/utils"
 	"strings"
 
@@ -19,6 +20,7 @@ type CurrencyBalance struct {
 }
 
 type WalletBalanceOutput struct {
+	Account  string             `json:"account"`
 	Balances []*CurrencyBalance `json:"balances"`
 	TotalTHB float64            `json:"total_thb"`
 }
@@ -26,19 +28,32 @@ type WalletBalanceOutput struct {
 func NewWalletBalanceTool() mcp.Tool {
 	return mcp.NewTool("get_wallet_balance",
 		mcp.WithDescription("Get wallet balance from Bitkub account - returns available and reserved balance for all currencies"),
+		utils.WithScope(utils.ScopeAccount),
+		withAccountArg(),
+		mcp.WithOutputSchema[WalletBalanceOutput](),
 	)
 }
 
 func WalletBalanceHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	log.Debug().Msg("Getting wallet balance")
+	var input AccountInput
+	if err := utils.BindArgs(NewWalletBalanceTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for wallet balance")
+		return err.Result()
+	}
+
+	log.Debug().Ctx(ctx).Msg("Getting wallet balance")
 
-	balances, err := market.GetBalances()
+	var balances map[string]market.Balance
+	account, err := useAccount(ctx, input.Account, func() (err error) {
+		balances, err = tracing.Exchange(ctx, "market.GetBalances", "", market.GetBalances)
+		return err
+	})
 	if err != nil {
-		log.Warn().Err(err).Msg("get_wallet_balance")
-		return utils.ErrorResult(fmt.Sprintf("get_wallet_balance: %v", err))
+		log.Warn().Ctx(ctx).Err(err).Msg("get_wallet_balance")
+		return utils.ExchangeErrorResult(err)
 	}
 
-	var currencyBalances []*CurrencyBalance
+	currencyBalances := []*CurrencyBalance{}
 	totalTHB := 0.0
 
 	for currency, balance := range balances {
@@ -58,11 +73,12 @@ func WalletBalanceHandler(ctx context.Context, request mcp.CallToolRequest) (*mc
 	}
 
 	output := WalletBalanceOutput{
+		Account:  account,
 		Balances: currencyBalances,
 		TotalTHB: utils.Round(totalTHB),
 	}
 
-	result := "Name: Total (Available+Reserved)\n"
+	result := fmt.Sprintf("Account: %s\nName: Total (Available+Reserved)\n", output.Account)
 	for _, cb := range output.Balances {
 		result += fmt.Sprintf("%s: %.8f (%.8f+%.8f)\n",
 			cb.Currency, cb.Total, cb.Available, cb.Reserved)
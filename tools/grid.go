@@ -0,0 +1,287 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/accounts"
+	"gokub/grid"
+	"gokub/killswitch"
+	"gokub/utils"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+type GridCreateInput struct {
+	Symbol          string  `json:"symbol"`
+	LowerPrice      float64 `json:"lower_price"`
+	UpperPrice      float64 `json:"upper_price"`
+	Grids           int     `json:"grids"`
+	TotalTHB        float64 `json:"total_thb"`
+	Spacing         string  `json:"spacing"`
+	FeePercent      float64 `json:"fee_percent"`
+	Simulate        bool    `json:"simulate"`
+	SimulationPrice float64 `json:"simulation_price"`
+	AccountInput
+}
+
+type GridStatusInput struct {
+	ID string `json:"id"`
+}
+
+type GridStopInput struct {
+	ID         string `json:"id"`
+	KeepOrders bool   `json:"keep_orders"`
+}
+
+type GridOutput struct {
+	Grid        *grid.Bot `json:"grid"`
+	CoinHeld    float64   `json:"coin_held"`
+	THBReserved float64   `json:"thb_reserved"`
+}
+
+type GridListOutput struct {
+	PollInterval string        `json:"poll_interval"`
+	Grids        []*GridOutput `json:"grids"`
+}
+
+func gridOutput(b *grid.Bot) *GridOutput {
+	coin, thb := b.Holdings()
+	return &GridOutput{Grid: b, CoinHeld: coin, THBReserved: thb}
+}
+
+func NewGridCreateTool() mcp.Tool {
+	return mcp.NewTool("grid_create",
+		mcp.WithDescription("Start a grid trading bot: splits total_thb across a ladder of limit orders between lower_price and upper_price, buys the coin the levels above the current price need, "+
+			"and re-places the opposite order every time one fills to collect the grid spread. Set simulate to run it against an in-memory order book instead of the exchange"),
+		utils.WithTrading(),
+		utils.WithSimulation(),
+		withSymbolArg(),
+		mcp.WithNumber("lower_price",
+			mcp.Required(),
+			utils.ExclusiveMin(0),
+			mcp.Description("Bottom of the grid range"),
+		),
+		mcp.WithNumber("upper_price",
+			mcp.Required(),
+			utils.ExclusiveMin(0),
+			mcp.Description("Top of the grid range"),
+		),
+		utils.WithInteger("grids",
+			mcp.Required(),
+			mcp.Min(2),
+			mcp.Max(200),
+			mcp.Description("Number of grid cells between the two prices"),
+		),
+		mcp.WithNumber("total_thb",
+			mcp.Required(),
+			utils.ExclusiveMin(0),
+			mcp.Description("THB to commit to the grid, split evenly across cells"),
+		),
+		mcp.WithString("spacing",
+			mcp.DefaultString(grid.Arithmetic),
+			mcp.Enum(grid.Arithmetic, grid.Geometric),
+			mcp.Description("arithmetic: equal price steps; geometric: equal percentage steps (default: arithmetic)"),
+		),
+		mcp.WithNumber("fee_percent",
+			mcp.DefaultNumber(0.25),
+			mcp.Min(0),
+			mcp.Description("Fee percentage per fill used for profit tracking (default: 0.25%)"),
+		),
+		mcp.WithBoolean("simulate",
+			mcp.DefaultBool(false),
+			mcp.Description("Run against a simulated in-memory order book that follows the live price; no real orders are placed (default: false)"),
+		),
+		mcp.WithNumber("simulation_price",
+			utils.ExclusiveMin(0),
+			mcp.Description("Starting price for a simulation; defaults to the live last price"),
+		),
+		withAccountArg(),
+		mcp.WithOutputSchema[GridOutput](),
+	)
+}
+
+func GridCreateHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input GridCreateInput
+	if err := utils.BindArgs(NewGridCreateTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for grid create")
+		return err.Result()
+	}
+
+	pair, symErr := resolveSymbol(ctx, input.Symbol)
+	if symErr != nil {
+		return symErr.Result()
+	}
+
+	if input.UpperPrice <= input.LowerPrice {
+		return utils.InvalidField("upper_price", "must be greater than lower_price").Result()
+	}
+
+	cfg := grid.Config{
+		Symbol:     pair.Symbol,
+		Lower:      input.LowerPrice,
+		Upper:      input.UpperPrice,
+		Grids:      input.Grids,
+		TotalTHB:   input.TotalTHB,
+		Spacing:    input.Spacing,
+		FeePercent: input.FeePercent,
+		Simulated:  input.Simulate,
+	}
+	// Simulations never reach the exchange, so only live grids stop at a halt.
+	if !cfg.Simulated {
+		if err := killswitch.Check(); err != nil {
+			return utils.ClassifyError(err).Result()
+		}
+	}
+
+	account, err := accounts.Resolve(ctx, input.Account)
+	switch {
+	case err == nil:
+		// Simulations are kept on the caller's account too, so that only
+		// callers allowed to use it see them.
+		cfg.Account = account
+	case !cfg.Simulated || len(accounts.Names()) > 0:
+		return utils.ExchangeErrorResult(err)
+	}
+
+	log.Info().Ctx(ctx).Str("symbol", cfg.Symbol).Float64("lower", cfg.Lower).Float64("upper", cfg.Upper).Int("grids", cfg.Grids).Bool("simulated", cfg.Simulated).Msg("Creating grid bot")
+
+	bot, err := grid.Create(ctx, cfg, pair, input.SimulationPrice)
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", cfg.Symbol).Msg("Failed to create grid bot")
+		return utils.ExchangeErrorResult(err)
+	}
+
+	output := gridOutput(bot)
+	mode := "live on " + bot.Account
+	if bot.Simulated {
+		mode = "simulated"
+	}
+
+	result := fmt.Sprintf("🕸️ Grid %s started (%s) on %s\n", bot.ID, mode, strings.ToUpper(bot.Symbol))
+	result += fmt.Sprintf("Range: %.8g - %.8g | %d %s grids | %.2f THB\n", bot.Lower, bot.Upper, bot.Grids, bot.Spacing, bot.TotalTHB)
+	result += fmt.Sprintf("Start price: %.8g | Initial buy: %.8g coin\n", bot.StartPrice, bot.InitialCoin)
+	result += fmt.Sprintf("Step: %.8g → %.8g per cell, %.8g coin each\n", bot.Cells[0].SellPrice-bot.Cells[0].BuyPrice, bot.Cells[len(bot.Cells)-1].SellPrice-bot.Cells[len(bot.Cells)-1].BuyPrice, bot.Cells[0].Amount)
+	result += fmt.Sprintf("Orders placed: %d | Coin held: %.8g | THB on bids: %.2f", len(bot.OrderIDs()), output.CoinHeld, output.THBReserved)
+
+	return utils.ArtifactsResult(result, output)
+}
+
+func NewGridStatusTool() mcp.Tool {
+	return mcp.NewTool("grid_status",
+		mcp.WithDescription("Show grid bots with their ladder, fills, round trips and realized grid profit. Omit id to list every bot"),
+		utils.WithScope(utils.ScopeAccount),
+		mcp.WithString("id",
+			mcp.Description("Grid id as returned by grid_create"),
+		),
+		mcp.WithOutputSchema[GridListOutput](),
+	)
+}
+
+func GridStatusHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input GridStatusInput
+	if err := utils.BindArgs(NewGridStatusTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for grid status")
+		return err.Result()
+	}
+
+	var bots []*grid.Bot
+	if input.ID != "" {
+		bot, ok := grid.Get(input.ID)
+		if !ok || !accounts.Permits(ctx, bot.Account) {
+			return utils.NewError(utils.CodeNotFound, "grid %s not found", input.ID).With("id", input.ID).Result()
+		}
+		bots = append(bots, bot)
+	} else {
+		for _, b := range grid.List() {
+			if accounts.Permits(ctx, b.Account) {
+				bots = append(bots, b)
+			}
+		}
+	}
+
+	output := GridListOutput{PollInterval: grid.Interval().String(), Grids: []*GridOutput{}}
+	for _, b := range bots {
+		output.Grids = append(output.Grids, gridOutput(b))
+	}
+
+	if len(output.Grids) == 0 {
+		return utils.ArtifactsResult("No grid bots", output)
+	}
+
+	result := ""
+	for _, g := range output.Grids {
+		b := g.Grid
+		result += fmt.Sprintf("🕸️ %s [%s] %s %.8g-%.8g x%d", b.ID, b.Status, strings.ToUpper(b.Symbol), b.Lower, b.Upper, b.Grids)
+		if b.Simulated {
+			result += " (simulated)"
+		}
+		result += "\n"
+		result += fmt.Sprintf("Last: %.8g | Round trips: %d | Grid profit: %.2f THB | Fees: %.2f THB\n", b.LastPrice, b.RoundTrips, b.GridProfitTHB, b.FeesTHB)
+		result += fmt.Sprintf("Coin held: %.8g | THB on bids: %.2f\n", g.CoinHeld, g.THBReserved)
+		if b.StopReason != "" {
+			result += "Stopped: " + b.StopReason + "\n"
+		}
+		if b.LastError != "" {
+			result += "⚠️ " + b.LastError + "\n"
+		}
+		if input.ID != "" {
+			for i := len(b.Cells) - 1; i >= 0; i-- {
+				c := b.Cells[i]
+				result += fmt.Sprintf("  %2d. %.8g / %.8g %s %.8g | trips %d | %.2f THB\n", c.Index, c.BuyPrice, c.SellPrice, strings.ToUpper(c.Side), c.Amount, c.RoundTrips, c.ProfitTHB)
+			}
+		}
+	}
+
+	return utils.ArtifactsResult(strings.TrimSuffix(result, "\n"), output)
+}
+
+func NewGridStopTool() mcp.Tool {
+	return mcp.NewTool("grid_stop",
+		mcp.WithDescription("Stop a grid bot and cancel its resting orders. Coin bought by the grid is kept"),
+		utils.WithScope(utils.ScopeTrade),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Grid id as returned by grid_create"),
+		),
+		mcp.WithBoolean("keep_orders",
+			mcp.DefaultBool(false),
+			mcp.Description("Leave the resting orders on the exchange instead of cancelling them (default: false)"),
+		),
+		mcp.WithOutputSchema[GridOutput](),
+	)
+}
+
+func GridStopHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input GridStopInput
+	if err := utils.BindArgs(NewGridStopTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for grid stop")
+		return err.Result()
+	}
+
+	if bot, ok := grid.Get(input.ID); !ok || !accounts.Permits(ctx, bot.Account) {
+		return utils.NewError(utils.CodeNotFound, "grid %s not found", input.ID).With("id", input.ID).Result()
+	}
+
+	bot, err := grid.Stop(ctx, input.ID, "stopped by grid_stop", input.KeepOrders)
+	if err != nil {
+		return utils.ExchangeErrorResult(err)
+	}
+
+	output := gridOutput(bot)
+	result := fmt.Sprintf("⏹️ Grid %s %s | Round trips: %d | Grid profit: %.2f THB | Coin held: %.8g", bot.ID, bot.Status, bot.RoundTrips, bot.GridProfitTHB, output.CoinHeld)
+	if remaining := bot.OrderIDs(); len(remaining) > 0 {
+		result += fmt.Sprintf("\n%d orders left open: %s", len(remaining), strings.Join(remaining, ", "))
+	}
+
+	return utils.ArtifactsResult(result, output)
+}
+
+func (o GridOutput) OrderIDs() []string {
+	if o.Grid == nil {
+		return []string{}
+	}
+	return o.Grid.OrderIDs()
+}
@@ -0,0 +1,203 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/accounts"
+	"gokub/rebalance"
+	"gokub/utils"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+type RebalanceInput struct {
+	Targets        map[string]float64 `json:"targets"`
+	DriftPercent   float64            `json:"drift_percent"`
+	MinTradeTHB    float64            `json:"min_trade_thb"`
+	FeePercent     float64            `json:"fee_percent"`
+	MaxSlippageBps float64            `json:"max_slippage_bps"`
+	AccountInput
+}
+
+type RebalanceOutput struct {
+	Plan     *rebalance.Plan `json:"plan"`
+	Executed bool            `json:"executed"`
+}
+
+func withRebalanceArgs() []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithObject("targets",
+			mcp.Required(),
+			mcp.Description("Target weights in percent keyed by asset, including THB for the cash share, e.g. {\"btc\": 50, \"eth\": 30, \"thb\": 20}. Weights must add up to 100; assets not listed are left alone"),
+			mcp.MinProperties(1),
+			mcp.AdditionalProperties(map[string]any{
+				"type":    "number",
+				"minimum": 0,
+				"maximum": 100,
+			}),
+		),
+		mcp.WithNumber("drift_percent",
+			mcp.DefaultNumber(5),
+			mcp.Min(0),
+			mcp.Description("Only trade assets whose weight is at least this many percentage points off target (default: 5)"),
+		),
+		mcp.WithNumber("min_trade_thb",
+			mcp.DefaultNumber(0),
+			mcp.Min(0),
+			mcp.Description("Skip trades smaller than this THB value; the pair's exchange minimum always applies (default: 0)"),
+		),
+		mcp.WithNumber("fee_percent",
+			mcp.DefaultNumber(0.25),
+			mcp.Min(0),
+			mcp.Description("Fee percentage per trade used for the fee preview (default: 0.25%)"),
+		),
+		withAccountArg(),
+	}
+}
+
+func NewRebalancePreviewTool() mcp.Tool {
+	opts := []mcp.ToolOption{
+		mcp.WithDescription("Preview a portfolio rebalance: prices the account's balances through tickers, compares them to the target weights and lists the market orders needed, " +
+			"with estimated fill price, slippage from the order book and fees. Nothing is traded"),
+		utils.WithScope(utils.ScopeAccount),
+	}
+	opts = append(opts, withRebalanceArgs()...)
+	opts = append(opts, mcp.WithOutputSchema[RebalanceOutput]())
+	return mcp.NewTool("rebalance_preview", opts...)
+}
+
+func NewRebalanceExecuteTool() mcp.Tool {
+	opts := []mcp.ToolOption{
+		mcp.WithDescription("Rebalance the portfolio to the target weights with market orders, sells first so their THB funds the buys. " +
+			"Run rebalance_preview first to check the trades"),
+		utils.WithTrading(),
+	}
+	opts = append(opts, withRebalanceArgs()...)
+	opts = append(opts,
+		mcp.WithNumber("max_slippage_bps",
+			mcp.DefaultNumber(100),
+			mcp.Min(0),
+			mcp.Description("Skip trades whose estimated slippage from mid exceeds this many basis points; 0 disables the check (default: 100)"),
+		),
+		mcp.WithOutputSchema[RebalanceOutput](),
+	)
+	return mcp.NewTool("rebalance_execute", opts...)
+}
+
+func rebalancePlan(ctx context.Context, input RebalanceInput) (*rebalance.Plan, rebalance.Options, error) {
+	opts := rebalance.Options{
+		Targets:        input.Targets,
+		DriftPercent:   input.DriftPercent,
+		MinTradeTHB:    input.MinTradeTHB,
+		FeePercent:     input.FeePercent,
+		MaxSlippageBps: input.MaxSlippageBps,
+	}
+	if err := rebalance.CheckTargets(opts.Targets); err != nil {
+		return nil, opts, utils.InvalidField("targets", err.Error())
+	}
+
+	account, err := accounts.Resolve(ctx, input.Account)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	plan, err := rebalance.Preview(ctx, account, opts)
+	return plan, opts, err
+}
+
+func RebalancePreviewHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input RebalanceInput
+	if err := utils.BindArgs(NewRebalancePreviewTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for rebalance preview")
+		return err.Result()
+	}
+
+	plan, _, err := rebalancePlan(ctx, input)
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Failed to preview rebalance")
+		return utils.ExchangeErrorResult(err)
+	}
+
+	output := RebalanceOutput{Plan: plan}
+	result := "⚖️ Rebalance preview for " + plan.Account + "\n" + describePlan(plan)
+	if s, last := rebalance.Scheduled(); s != nil {
+		result += fmt.Sprintf("\nScheduled rebalance: every %s on %s", s.Interval, s.Account)
+		if last != nil {
+			result += fmt.Sprintf(", last run placed %d orders", len(last.OrderIDs()))
+		}
+	}
+
+	return utils.ArtifactsResult(result, output)
+}
+
+func RebalanceExecuteHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input RebalanceInput
+	if err := utils.BindArgs(NewRebalanceExecuteTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for rebalance execute")
+		return err.Result()
+	}
+
+	plan, opts, err := rebalancePlan(ctx, input)
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Failed to plan rebalance")
+		return utils.ExchangeErrorResult(err)
+	}
+
+	output := RebalanceOutput{Plan: plan, Executed: true}
+	if len(plan.Trades) == 0 {
+		return utils.ArtifactsResult("⚖️ Portfolio is within target, nothing to trade\n"+describePlan(plan), output)
+	}
+
+	log.Info().Ctx(ctx).Str("account", plan.Account).Int("trades", len(plan.Trades)).Msg("Executing rebalance")
+
+	if err := rebalance.Execute(ctx, plan, opts); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Failed to execute rebalance")
+		return utils.ExchangeErrorResult(err)
+	}
+
+	result := fmt.Sprintf("⚖️ Rebalance executed on %s: %d of %d orders placed\n", plan.Account, len(plan.OrderIDs()), len(plan.Trades)) + describePlan(plan)
+	return utils.ArtifactsResult(result, output)
+}
+
+func describePlan(plan *rebalance.Plan) string {
+	result := fmt.Sprintf("Portfolio: %.2f THB\n", plan.TotalTHB)
+	for _, h := range plan.Holdings {
+		result += fmt.Sprintf("  %-6s %6.2f%% → %6.2f%% (%+.2f) | %.2f THB\n", h.Asset, h.Weight, h.Target, h.Drift, h.ValueTHB)
+	}
+
+	if len(plan.Trades) > 0 {
+		result += "Trades:\n"
+		for _, t := range plan.Trades {
+			result += fmt.Sprintf("  %s %s %.8g @ ~%.8g (%.2f THB) | slippage %.2f bps | fee %.2f THB", strings.ToUpper(t.Side), strings.ToUpper(t.Symbol), t.Amount, t.EstPrice, t.ValueTHB, t.SlippageBps, t.FeeTHB)
+			if t.Status != rebalance.StatusPlanned {
+				result += " [" + t.Status + "]"
+			}
+			if t.OrderID != "" {
+				result += " #" + t.OrderID
+			}
+			if t.Error != "" {
+				result += " ⚠️ " + t.Error
+			}
+			result += "\n"
+		}
+		result += fmt.Sprintf("Estimated fees: %.2f THB\n", plan.FeesTHB)
+	}
+
+	for _, s := range plan.Skipped {
+		result += fmt.Sprintf("Skipped %s: %s\n", s.Asset, s.Reason)
+	}
+	if len(plan.Untracked) > 0 {
+		result += "Not in targets, left alone: " + strings.Join(plan.Untracked, ", ") + "\n"
+	}
+
+	return strings.TrimSuffix(result, "\n")
+}
+
+func (o RebalanceOutput) OrderIDs() []string {
+	if o.Plan == nil {
+		return []string{}
+	}
+	return o.Plan.OrderIDs()
+}
@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// testCandles is a fixed series that trends up with a daily swing, so every
+// indicator has enough history and the signal tools have something to read.
+func testCandles(n int) []map[string]any {
+	candles := make([]map[string]any, n)
+	for i := range candles {
+		base := 100 + float64(i)*0.5 + 4*math.Sin(float64(i)/3)
+		candles[i] = map[string]any{
+			"open":   base - 0.3,
+			"high":   base + 1.2,
+			"low":    base - 1.1,
+			"close":  base + 0.4,
+			"volume": 1000 + 300*math.Cos(float64(i)/2),
+		}
+	}
+	return candles
+}
+
+func testPrices(n int) []any {
+	prices := make([]any, n)
+	for i, c := range testCandles(n) {
+		prices[i] = c["close"]
+	}
+	return prices
+}
+
+func TestStructuredOutputMatchesSchema(t *testing.T) {
+	cases := []struct {
+		tool    mcp.Tool
+		handler server.ToolHandlerFunc
+		args    map[string]any
+	}{
+		{NewCalculatePositionSizeTool(), CalculatePositionSizeHandler, map[string]any{"balance": 10000, "risk_percent": 1, "entry": 100, "stop": 95}},
+		{NewCalculateEMATool(), CalculateEMAHandler, map[string]any{"prices": testPrices(60), "period": 20}},
+		{NewCalculateROCTool(), CalculateROCHandler, map[string]any{"prices": testPrices(60), "period": 10}},
+		{NewCalculateRSITool(), CalculateRSIHandler, map[string]any{"prices": testPrices(60), "period": 14}},
+		{NewCalculateATRTool(), CalculateATRHandler, map[string]any{"candles": testCandles(60), "period": 14}},
+		{NewCalculateRelativeStrengthRankTool(), CalculateRelativeStrengthRankHandler, map[string]any{"symbols": map[string]any{"btc_thb": testPrices(60), "eth_thb": testPrices(50)}, "period": 20}},
+		{NewCheckMarketRegimeTool(), CheckMarketRegimeHandler, map[string]any{"prices": testPrices(120)}},
+		{NewDetectBreakoutSignalTool(), DetectBreakoutSignalHandler, map[string]any{"candles": testCandles(60)}},
+		{NewDetectPullbackSignalTool(), DetectPullbackSignalHandler, map[string]any{"candles": testCandles(60)}},
+		{NewEvaluateStrategyTool(), EvaluateStrategyHandler, map[string]any{"strategy": "breakout", "candles": testCandles(60)}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.tool.Name, func(t *testing.T) {
+			if tc.tool.OutputSchema.Type != "object" {
+				t.Fatalf("no output schema declared")
+			}
+
+			request := mcp.CallToolRequest{}
+			request.Params.Name = tc.tool.Name
+			request.Params.Arguments = tc.args
+			result, err := tc.handler(context.Background(), request)
+			if err != nil {
+				t.Fatalf("handler: %v", err)
+			}
+			if result.IsError {
+				t.Fatalf("tool error: %v", result.Content)
+			}
+			if result.StructuredContent == nil {
+				t.Fatalf("no structured content")
+			}
+
+			var schema map[string]any
+			roundTrip(t, tc.tool.OutputSchema, &schema)
+			var output any
+			roundTrip(t, result.StructuredContent, &output)
+
+			for _, e := range conform("", output, schema) {
+				t.Error(e)
+			}
+		})
+	}
+}
+
+func roundTrip(t *testing.T, v any, target any) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+}
+
+// conform checks value against the subset of JSON Schema the generated
+// output schemas use. Keys an object schema does not list are reported too,
+// since they mean a field's JSON tag and the declared type disagree.
+func conform(path string, value any, schema map[string]any) []string {
+	if path == "" {
+		path = "$"
+	}
+	fail := func(format string, a ...any) []string {
+		return []string{path + ": " + fmt.Sprintf(format, a...)}
+	}
+
+	types := schemaStrings(schema["type"])
+	if len(types) == 0 {
+		return nil
+	}
+	if value == nil {
+		if slices.Contains(types, "null") {
+			return nil
+		}
+		return fail("is null, want %v", types)
+	}
+
+	var errs []string
+	switch v := value.(type) {
+	case bool:
+		if !slices.Contains(types, "boolean") {
+			return fail("is a boolean, want %v", types)
+		}
+	case string:
+		if !slices.Contains(types, "string") {
+			return fail("is a string, want %v", types)
+		}
+	case float64:
+		switch {
+		case slices.Contains(types, "number"):
+		case slices.Contains(types, "integer") && v == math.Trunc(v):
+		default:
+			return fail("is the number %v, want %v", v, types)
+		}
+	case []any:
+		if !slices.Contains(types, "array") {
+			return fail("is an array, want %v", types)
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range v {
+			errs = append(errs, conform(fmt.Sprintf("%s[%d]", path, i), item, items)...)
+		}
+	case map[string]any:
+		if !slices.Contains(types, "object") {
+			return fail("is an object, want %v", types)
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, path+"."+name+": is required")
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		extra, _ := schema["additionalProperties"].(map[string]any)
+		for _, k := range keys {
+			switch prop, ok := properties[k].(map[string]any); {
+			case ok:
+				errs = append(errs, conform(path+"."+k, v[k], prop)...)
+			case extra != nil:
+				errs = append(errs, conform(path+"."+k, v[k], extra)...)
+			case properties != nil:
+				errs = append(errs, path+"."+k+": is not in the schema")
+			}
+		}
+	}
+	return errs
+}
+
+func schemaStrings(v any) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []any:
+		out := make([]string, 0, len(s))
+		for _, e := range s {
+			if str, ok := e.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
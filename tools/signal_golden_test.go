@@ -0,0 +1,273 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/symbols"
+	"math"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// The signal tools used to compute their rules in Go before the breakout and
+// pullback strategy definitions took over. These tests keep that code as the
+// reference and check the definitions still give the same signal, entry and
+// stop on fixed candle sets.
+
+type goldenSignal struct {
+	Signal         string  `json:"signal"`
+	SuggestedEntry float64 `json:"suggested_entry"`
+	SuggestedStop  float64 `json:"suggested_stop"`
+}
+
+func legacyBreakout(candles []OHLCData, lookback int, volumeThreshold, atrMultiplier float64) goldenSignal {
+	current := candles[len(candles)-1]
+
+	high := 0.0
+	avgVolume := 0.0
+	for i := len(candles) - 1 - lookback; i < len(candles)-1; i++ {
+		high = max(high, candles[i].High)
+		avgVolume += candles[i].Volume
+	}
+	avgVolume /= float64(lookback)
+
+	volumeRatio := 0.0
+	if avgVolume > 0 {
+		volumeRatio = current.Volume / avgVolume
+	}
+
+	signal := "NO_SIGNAL"
+	if current.Close > high && volumeRatio >= volumeThreshold {
+		signal = "BREAKOUT_BUY"
+	}
+
+	ranges := make([]float64, len(candles))
+	for i := range candles {
+		if i == 0 {
+			ranges[i] = candles[i].High - candles[i].Low
+		} else {
+			highLow := candles[i].High - candles[i].Low
+			highClose := math.Abs(candles[i].High - candles[i-1].Close)
+			lowClose := math.Abs(candles[i].Low - candles[i-1].Close)
+			ranges[i] = max(highLow, max(highClose, lowClose))
+		}
+	}
+	atr := calculateATR(ranges, 14)
+
+	pair := &symbols.Pair{}
+	return goldenSignal{
+		Signal:         signal,
+		SuggestedEntry: pair.SnapPrice(current.Close*1.001, symbols.RoundUp),
+		SuggestedStop:  pair.SnapPrice(current.Close-atr*atrMultiplier, symbols.RoundDown),
+	}
+}
+
+func legacyPullback(candles []OHLCData, emaPeriod, rsiPeriod int, rsiMin, rsiMax float64) goldenSignal {
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close
+	}
+	ema := calculateEMA(closes, emaPeriod)
+	currentEMA := ema[len(ema)-1]
+	rsi := calculateRSI(closes, rsiPeriod)
+
+	current := candles[len(candles)-1]
+	previous := candles[len(candles)-2]
+
+	nearEMA := math.Abs((current.Close-currentEMA)/currentEMA*100) <= 2.0
+	rsiInBounceZone := rsi >= rsiMin && rsi <= rsiMax
+	hasReversalBar := current.Close > current.High-(current.High-current.Low)*0.3 &&
+		previous.Close < previous.High-(previous.High-previous.Low)*0.5
+
+	swingLow := current.Low
+	for i := len(candles) - 10; i < len(candles); i++ {
+		if i >= 0 && candles[i].Low < swingLow {
+			swingLow = candles[i].Low
+		}
+	}
+
+	signal := "NO_SIGNAL"
+	if nearEMA && rsiInBounceZone && hasReversalBar {
+		signal = "PULLBACK_BUY"
+	}
+
+	pair := &symbols.Pair{}
+	return goldenSignal{
+		Signal:         signal,
+		SuggestedEntry: pair.SnapPrice(current.High*1.001, symbols.RoundUp),
+		SuggestedStop:  pair.SnapPrice(swingLow*0.999, symbols.RoundDown),
+	}
+}
+
+// goldenSets are the fixed candle sets: a trending series with a swing cut at
+// many lengths, a flat market, and tails built to trigger each signal.
+func goldenSets() map[string][]OHLCData {
+	sets := map[string][]OHLCData{}
+	for n := 40; n <= 120; n += 4 {
+		sets[fmt.Sprintf("trend/%d", n)] = waveCandles(n, 0.5, 4, 3)
+	}
+	for n := 40; n <= 120; n += 4 {
+		sets[fmt.Sprintf("chop/%d", n)] = waveCandles(n, 0.05, 6, 2.5)
+	}
+	sets["flat"] = waveCandles(60, 0, 0, 1)
+
+	breakout := waveCandles(60, 0.1, 2, 4)
+	last := &breakout[len(breakout)-1]
+	last.High, last.Close, last.Volume = last.High+8, last.High+7.5, last.Volume*3
+	sets["breakout"] = breakout
+
+	weak := waveCandles(60, 0.1, 2, 4)
+	last = &weak[len(weak)-1]
+	last.High, last.Close = last.High+8, last.High+7.5
+	sets["breakout/no_volume"] = weak
+
+	// A rally, a slide back to the EMA and a strong candle off the low.
+	pullback := waveCandles(40, 0.8, 0.5, 3)
+	price := pullback[len(pullback)-1].Close
+	for range 6 {
+		price -= 1.2
+		pullback = append(pullback, OHLCData{Open: price + 1.1, High: price + 1.3, Low: price - 0.2, Close: price, Volume: 900})
+	}
+	pullback = append(pullback, OHLCData{Open: price, High: price + 1.5, Low: price - 0.3, Close: price + 1.4, Volume: 1400})
+	sets["pullback"] = pullback
+
+	return sets
+}
+
+func waveCandles(n int, slope, amplitude, period float64) []OHLCData {
+	candles := make([]OHLCData, n)
+	for i := range candles {
+		base := 100 + float64(i)*slope + amplitude*math.Sin(float64(i)/period)
+		candles[i] = OHLCData{
+			Open:   base - 0.3,
+			High:   base + 1.2,
+			Low:    base - 1.1,
+			Close:  base + 0.4,
+			Volume: 1000 + 300*math.Cos(float64(i)/2),
+		}
+	}
+	return candles
+}
+
+func candleArgs(candles []OHLCData) []any {
+	args := make([]any, len(candles))
+	for i, c := range candles {
+		args[i] = map[string]any{"open": c.Open, "high": c.High, "low": c.Low, "close": c.Close, "volume": c.Volume}
+	}
+	return args
+}
+
+func callSignal(t *testing.T, tool mcp.Tool, handler server.ToolHandlerFunc, args map[string]any) goldenSignal {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool.Name
+	request.Params.Arguments = args
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("%s: %v", tool.Name, err)
+	}
+	if result.IsError {
+		t.Fatalf("%s: tool error: %v", tool.Name, result.Content)
+	}
+	var got goldenSignal
+	roundTrip(t, result.StructuredContent, &got)
+	return got
+}
+
+func TestBreakoutMatchesLegacy(t *testing.T) {
+	params := []struct {
+		lookback                       int
+		volumeThreshold, atrMultiplier float64
+	}{
+		{20, 1.5, 1.5},
+		{10, 1.2, 2},
+	}
+
+	signals := 0
+	for name, candles := range goldenSets() {
+		for _, p := range params {
+			t.Run(fmt.Sprintf("%s/lookback=%d", name, p.lookback), func(t *testing.T) {
+				want := legacyBreakout(candles, p.lookback, p.volumeThreshold, p.atrMultiplier)
+				if want.Signal != "NO_SIGNAL" {
+					signals++
+				}
+				args := map[string]any{
+					"candles":          candleArgs(candles),
+					"lookback":         p.lookback,
+					"volume_threshold": p.volumeThreshold,
+					"atr_multiplier":   p.atrMultiplier,
+				}
+				if got := callSignal(t, NewDetectBreakoutSignalTool(), DetectBreakoutSignalHandler, args); got != want {
+					t.Errorf("detect_breakout_signal = %+v, legacy %+v", got, want)
+				}
+
+				args = map[string]any{
+					"strategy": "breakout",
+					"candles":  candleArgs(candles),
+					"params": map[string]any{
+						"lookback":         p.lookback,
+						"volume_threshold": p.volumeThreshold,
+						"atr_multiplier":   p.atrMultiplier,
+					},
+				}
+				if got := callSignal(t, NewEvaluateStrategyTool(), EvaluateStrategyHandler, args); got != want {
+					t.Errorf("evaluate_strategy = %+v, legacy %+v", got, want)
+				}
+			})
+		}
+	}
+	if signals == 0 {
+		t.Error("no candle set gives a breakout signal")
+	}
+}
+
+func TestPullbackMatchesLegacy(t *testing.T) {
+	params := []struct {
+		emaPeriod, rsiPeriod int
+		rsiMin, rsiMax       float64
+	}{
+		{20, 14, 40, 50},
+		{10, 7, 30, 60},
+	}
+
+	signals := 0
+	for name, candles := range goldenSets() {
+		for _, p := range params {
+			t.Run(fmt.Sprintf("%s/ema=%d", name, p.emaPeriod), func(t *testing.T) {
+				want := legacyPullback(candles, p.emaPeriod, p.rsiPeriod, p.rsiMin, p.rsiMax)
+				if want.Signal != "NO_SIGNAL" {
+					signals++
+				}
+				args := map[string]any{
+					"candles":    candleArgs(candles),
+					"ema_period": p.emaPeriod,
+					"rsi_period": p.rsiPeriod,
+					"rsi_min":    p.rsiMin,
+					"rsi_max":    p.rsiMax,
+				}
+				if got := callSignal(t, NewDetectPullbackSignalTool(), DetectPullbackSignalHandler, args); got != want {
+					t.Errorf("detect_pullback_signal = %+v, legacy %+v", got, want)
+				}
+
+				args = map[string]any{
+					"strategy": "pullback",
+					"candles":  candleArgs(candles),
+					"params": map[string]any{
+						"ema_period": p.emaPeriod,
+						"rsi_period": p.rsiPeriod,
+						"rsi_min":    p.rsiMin,
+						"rsi_max":    p.rsiMax,
+					},
+				}
+				if got := callSignal(t, NewEvaluateStrategyTool(), EvaluateStrategyHandler, args); got != want {
+					t.Errorf("evaluate_strategy = %+v, legacy %+v", got, want)
+				}
+			})
+		}
+	}
+	if signals == 0 {
+		t.Error("no candle set gives a pullback signal")
+	}
+}
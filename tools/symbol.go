@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/symbols"
+	"gokub/utils"
+	"math"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+// symbolPattern accepts the spellings symbols.Normalize understands: btc,
+// btc_thb, THB_BTC, BTC/THB or btc-thb.
+const symbolPattern = `^[A-Za-z0-9]+([_/-][A-Za-z0-9]+)?$`
+
+func withSymbolArg() mcp.ToolOption {
+	return mcp.WithString("symbol",
+		mcp.Required(),
+		mcp.Description("Trading pair symbol (e.g., btc_thb, BTC/THB, THB_BTC, or btc for the THB market)"),
+		mcp.Pattern(symbolPattern),
+	)
+}
+
+func resolveSymbol(ctx context.Context, input string) (*symbols.Pair, *utils.ToolError) {
+	pair, err := symbols.Resolve(input)
+	if err != nil {
+		log.Warn().Ctx(ctx).Err(err).Str("symbol", input).Msg("Unknown symbol")
+	}
+	return pair, err
+}
+
+// materialRiskChange is the relative change in risk, caused by snapping
+// prices and quantities to the exchange grid, worth warning about.
+const materialRiskChange = 0.05
+
+func withRulesSymbolArg() mcp.ToolOption {
+	return mcp.WithString("symbol",
+		mcp.Description("Trading pair the prices belong to (e.g., btc_thb). Prices and quantities are snapped to its tick and lot size; default THB market rules apply when omitted"),
+		mcp.Pattern(symbolPattern),
+	)
+}
+
+// rulesFor resolves the optional symbol argument of calculation tools.
+// Without one the default THB market rules apply.
+func rulesFor(ctx context.Context, input string) (*symbols.Pair, *utils.ToolError) {
+	if input == "" {
+		return &symbols.Pair{}, nil
+	}
+	return resolveSymbol(ctx, input)
+}
+
+func riskChangeWarning(label string, before, after float64, unit string) []string {
+	if before <= 0 || math.Abs(after-before)/before <= materialRiskChange {
+		return nil
+	}
+	return []string{fmt.Sprintf("rounding to exchange tick/lot size changed %s from %.4g%s to %.4g%s (%+.1f%%)",
+		label, before, unit, after, unit, (after-before)/before*100)}
+}
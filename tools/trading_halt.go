@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"gokub/killswitch"
+	"gokub/utils"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+type TradingHaltOutput struct {
+	State  killswitch.State         `json:"state"`
+	Cancel *killswitch.CancelReport `json:"cancel"`
+}
+
+type TradingHaltInput struct {
+	Reason string `json:"reason"`
+}
+
+func NewTradingHaltTool() mcp.Tool {
+	return mcp.NewTool("trading_halt",
+		mcp.WithDescription("Emergency kill switch: immediately blocks every trading tool server-wide and cancels all open orders across symbols. The halt persists across restarts and can only be lifted by an operator (admin endpoint or removing the halt file)"),
+		utils.WithScope(utils.ScopeTrade),
+		mcp.WithString("reason",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Why trading is being halted"),
+		),
+		mcp.WithOutputSchema[TradingHaltOutput](),
+	)
+}
+
+func TradingHaltHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var input TradingHaltInput
+	if err := utils.BindArgs(NewTradingHaltTool(), request, &input); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Msg("Invalid arguments for trading halt")
+		return err.Result()
+	}
+
+	reason := input.Reason
+
+	report, err := killswitch.Halt(reason, "tool")
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Msg("trading_halt")
+	}
+
+	output := TradingHaltOutput{
+		State:  killswitch.Status(),
+		Cancel: report,
+	}
+
+	result := fmt.Sprintf("🛑 Trading halted: %s\n", output.State.Reason)
+	result += fmt.Sprintf("Checked %d symbols | Cancelled %d orders | Failed %d\n",
+		report.SymbolsChecked, len(report.Cancelled), len(report.Failed))
+	for _, order := range report.Cancelled {
+		result += fmt.Sprintf("- [%s] %s %s %s\n", order.Account, order.Symbol, order.Side, order.ID)
+	}
+	if err != nil {
+		result += fmt.Sprintf("⚠️ Halt state not persisted: %v\n", err)
+	}
+
+	return utils.ArtifactsResult(result, output)
+}
+
+func (o TradingHaltOutput) OrderIDs() []string {
+	ids := []string{}
+	if o.Cancel == nil {
+		return ids
+	}
+	for _, order := range o.Cancel.Cancelled {
+		ids = append(ids, order.ID)
+	}
+	return ids
+}
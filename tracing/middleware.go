@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		attrs := []attribute.KeyValue{
+			attribute.String("mcp.method.name", "tools/call"),
+			attribute.String("mcp.tool.name", request.Params.Name),
+		}
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			attrs = append(attrs, attribute.String("mcp.session.id", session.SessionID()))
+		}
+		if account, ok := request.GetArguments()["account"].(string); ok && account != "" {
+			attrs = append(attrs, attribute.String("bitkub.account", account))
+		}
+
+		ctx, span := tracer.Start(ctx, "tools/call "+request.Params.Name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		result, err := next(ctx, request)
+		switch {
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		case result != nil && result.IsError:
+			span.SetStatus(codes.Error, "tool returned an error result")
+		}
+
+		return result, err
+	}
+}
+
+// HTTPMiddleware continues a trace started by the client when the request
+// carries a traceparent header.
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
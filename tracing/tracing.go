@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "gokub-mcp"
+	defaultFile = "logs/traces.jsonl"
+)
+
+var tracer = otel.Tracer("gokub")
+
+// Init configures the global tracer provider from OTEL_TRACES_EXPORTER
+// (otlp, stdout, file or none). stdout writes to standard error, since
+// standard output carries the stdio transport's JSON-RPC stream. When unset, OTLP is used if an
+// OTEL_EXPORTER_OTLP_ENDPOINT is configured and tracing stays off otherwise.
+// The returned function flushes pending spans on shutdown.
+func Init() (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+
+	exporterName := strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER"))
+	if exporterName == "" && (os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "") {
+		exporterName = "otlp"
+	}
+
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	var err error
+
+	switch exporterName {
+	case "", "none":
+		return noop, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background())
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	case "file":
+		path := os.Getenv("OTEL_TRACES_FILE")
+		if path == "" {
+			path = defaultFile
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return noop, err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return noop, err
+		}
+		closer = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			return noop, err
+		}
+	default:
+		return noop, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q, use otlp, stdout, file or none", exporterName)
+	}
+	if err != nil {
+		return noop, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return noop, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	log.Info().Str("exporter", exporterName).Msg("Tracing enabled")
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}, nil
+}
+
+// Start opens a span for an internal step of a tool call.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Exchange wraps a go-bitkub call in a client span. The library does not take
+// a context, so the requests it sends are matched to the span by goroutine.
+func Exchange[T any](ctx context.Context, endpoint, symbol string, fn func() (T, error)) (T, error) {
+	return Request(ctx, endpoint, symbol, func(ctx context.Context) (T, error) {
+		defer bindGoroutine(callFrom(ctx))()
+		return fn()
+	})
+}
+
+// Request wraps an exchange call whose HTTP requests are built with the
+// context passed to fn. Those requests are parented to the call's span and
+// their retries and final status are recorded on it.
+func Request[T any](ctx context.Context, endpoint, symbol string, fn func(ctx context.Context) (T, error)) (T, error) {
+	attrs := []attribute.KeyValue{attribute.String("exchange.endpoint", endpoint)}
+	if symbol != "" {
+		attrs = append(attrs, attribute.String("exchange.symbol", symbol))
+	}
+
+	ctx, span := tracer.Start(ctx, "bitkub "+endpoint, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	defer span.End()
+	ctx, call := withCall(ctx)
+
+	result, err := fn(ctx)
+
+	attempts, status := call.result()
+	if attempts > 0 {
+		span.SetAttributes(attribute.Int("exchange.retry_count", attempts-1))
+	}
+	if status != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("exchange.status", "error"))
+		return result, err
+	}
+
+	span.SetAttributes(attribute.String("exchange.status", "ok"))
+	return result, nil
+}
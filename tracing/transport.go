@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exchangeCall collects what the transport sees of the requests sent for one
+// exchange call. It travels in the request context of requests built with the
+// context Request hands to its callback. go-bitkub builds its requests
+// without a caller context, so Exchange also files the call under the calling
+// goroutine, which is the one http.Client runs the transport on.
+type exchangeCall struct {
+	mu       sync.Mutex
+	parent   trace.SpanContext
+	attempts int
+	status   int
+}
+
+type callKey struct{}
+
+// goroutineCalls maps a goroutine ID to the exchange call it is running.
+var goroutineCalls sync.Map
+
+func withCall(ctx context.Context) (context.Context, *exchangeCall) {
+	call := &exchangeCall{parent: trace.SpanContextFromContext(ctx)}
+	return context.WithValue(ctx, callKey{}, call), call
+}
+
+// bindGoroutine files call under the current goroutine until the returned
+// function is called.
+func bindGoroutine(call *exchangeCall) func() {
+	id := goroutineID()
+	goroutineCalls.Store(id, call)
+	return func() { goroutineCalls.Delete(id) }
+}
+
+// callFor finds the exchange call a request belongs to, from its context or
+// else from the goroutine sending it.
+func callFor(req *http.Request) *exchangeCall {
+	if call := callFrom(req.Context()); call != nil {
+		return call
+	}
+	if call, ok := goroutineCalls.Load(goroutineID()); ok {
+		return call.(*exchangeCall)
+	}
+	return nil
+}
+
+// goroutineID reads the current goroutine's ID from the header of its stack
+// trace, "goroutine 123 [running]:".
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf, _ = bytes.CutPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+func callFrom(ctx context.Context) *exchangeCall {
+	call, _ := ctx.Value(callKey{}).(*exchangeCall)
+	return call
+}
+
+func (c *exchangeCall) attempt() {
+	c.mu.Lock()
+	c.attempts++
+	c.mu.Unlock()
+}
+
+func (c *exchangeCall) respond(status int) {
+	c.mu.Lock()
+	c.status = status
+	c.mu.Unlock()
+}
+
+func (c *exchangeCall) result() (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attempts, c.status
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+// InstrumentTransport records a span for every request sent to the Bitkub API.
+func InstrumentTransport(next http.RoundTripper) http.RoundTripper {
+	return &transport{next: next}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Hostname(), "bitkub.com") {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	call := callFor(req)
+	if call != nil {
+		call.attempt()
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			ctx = trace.ContextWithSpanContext(ctx, call.parent)
+		}
+	}
+
+	ctx, span := tracer.Start(ctx, req.Method+" "+req.URL.Path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.path", req.URL.Path),
+			attribute.String("server.address", req.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+
+	if call != nil {
+		call.respond(resp.StatusCode)
+	}
+
+	return resp, nil
+}
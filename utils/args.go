@@ -49,7 +49,23 @@ func GetIntArg(args map[string]any, key string, defaultValue ...int) int {
 	return defaultValue[0]
 }
 
+func GetBoolArg(args map[string]any, key string, defaultValue ...bool) bool {
+	if val, ok := args[key]; ok {
+		if bval, ok := val.(bool); ok {
+			return bval
+		}
+	}
+	if len(defaultValue) == 0 {
+		return false
+	}
+
+	return defaultValue[0]
+}
+
 func ValidateArgs(args any) (map[string]any, error) {
+	if args == nil {
+		return map[string]any{}, nil
+	}
 	argsMap, ok := args.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("invalid arguments format")
@@ -57,10 +73,6 @@ func ValidateArgs(args any) (map[string]any, error) {
 	return argsMap, nil
 }
 
-func ErrorResult(err string) (*mcp.CallToolResult, error) {
-	return nil, fmt.Errorf("tool: %v", err)
-}
-
 func TextResult(message string) (*mcp.CallToolResult, error) {
 	return mcp.NewToolResultText(message), nil
 }
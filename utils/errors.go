@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type ErrorCode string
+
+const (
+	CodeInvalidArgument     ErrorCode = "INVALID_ARGUMENT"
+	CodeNotFoundSymbol      ErrorCode = "NOT_FOUND_SYMBOL"
+	CodeNotFound            ErrorCode = "NOT_FOUND"
+	CodeRateLimited         ErrorCode = "RATE_LIMITED"
+	CodeExchangeUnavailable ErrorCode = "EXCHANGE_UNAVAILABLE"
+	CodeExchangeRejected    ErrorCode = "EXCHANGE_REJECTED"
+	CodeInsufficientData    ErrorCode = "INSUFFICIENT_DATA"
+	CodeInsufficientBalance ErrorCode = "INSUFFICIENT_BALANCE"
+	CodeAuthRequired        ErrorCode = "AUTH_REQUIRED"
+	CodePermissionDenied    ErrorCode = "PERMISSION_DENIED"
+	CodeTradingHalted       ErrorCode = "TRADING_HALTED"
+	CodeInternal            ErrorCode = "INTERNAL"
+)
+
+// ToolError is returned to the client as an isError tool result so the model
+// can read the code and decide whether to retry, fix its arguments or stop.
+type ToolError struct {
+	Code       ErrorCode      `json:"code"`
+	Message    string         `json:"message"`
+	Retryable  bool           `json:"retryable"`
+	BitkubCode int            `json:"bitkub_code,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+func NewError(code ErrorCode, format string, a ...any) *ToolError {
+	return &ToolError{
+		Code:      code,
+		Message:   fmt.Sprintf(format, a...),
+		Retryable: code == CodeRateLimited || code == CodeExchangeUnavailable,
+	}
+}
+
+func (e *ToolError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *ToolError) With(key string, value any) *ToolError {
+	if e.Details == nil {
+		e.Details = map[string]any{}
+	}
+	e.Details[key] = value
+	return e
+}
+
+func (e *ToolError) Result() (*mcp.CallToolResult, error) {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{Type: "text", Text: e.Error()},
+		},
+		StructuredContent: map[string]any{"error": e},
+		IsError:           true,
+	}, nil
+}
+
+func ErrorResult(code ErrorCode, message string) (*mcp.CallToolResult, error) {
+	return NewError(code, "%s", message).Result()
+}
+
+// ExchangeErrorResult reports a failed go-bitkub call. Errors that are already
+// a *ToolError pass through unchanged.
+func ExchangeErrorResult(err error) (*mcp.CallToolResult, error) {
+	return ClassifyError(err).Result()
+}
+
+// Bitkub API error codes, see
+// https://github.com/bitkub/bitkub-official-api-docs/blob/master/restful-api.md#error-codes
+var bitkubErrorCodes = map[int]struct {
+	code    ErrorCode
+	message string
+}{
+	1:  {CodeInvalidArgument, "invalid JSON payload"},
+	2:  {CodeAuthRequired, "missing X-BTK-APIKEY"},
+	3:  {CodeAuthRequired, "invalid API key"},
+	4:  {CodeAuthRequired, "API key pending activation"},
+	5:  {CodePermissionDenied, "IP not allowed"},
+	6:  {CodeAuthRequired, "missing or invalid signature"},
+	7:  {CodeInvalidArgument, "missing timestamp"},
+	8:  {CodeInvalidArgument, "invalid timestamp"},
+	9:  {CodeAuthRequired, "invalid user"},
+	10: {CodeInvalidArgument, "invalid parameter"},
+	11: {CodeNotFoundSymbol, "invalid symbol"},
+	12: {CodeInvalidArgument, "invalid amount"},
+	13: {CodeInvalidArgument, "invalid rate"},
+	14: {CodeInvalidArgument, "improper rate"},
+	15: {CodeInvalidArgument, "amount too low"},
+	16: {CodeExchangeUnavailable, "failed to get balance"},
+	17: {CodeInsufficientBalance, "wallet is empty"},
+	18: {CodeInsufficientBalance, "insufficient balance"},
+	19: {CodeExchangeUnavailable, "failed to insert order into db"},
+	20: {CodeExchangeUnavailable, "failed to deduct balance"},
+	21: {CodeNotFound, "invalid order for cancellation"},
+	22: {CodeInvalidArgument, "invalid side"},
+	23: {CodeExchangeUnavailable, "failed to update order status"},
+	24: {CodeNotFound, "invalid order for lookup"},
+	25: {CodePermissionDenied, "KYC level 1 is required"},
+	30: {CodeRateLimited, "limit exceeds"},
+	52: {CodePermissionDenied, "invalid permission"},
+	55: {CodeExchangeRejected, "cancel only mode"},
+	56: {CodePermissionDenied, "user suspended from purchasing"},
+	57: {CodePermissionDenied, "user suspended from selling"},
+	90: {CodeExchangeUnavailable, "server error"},
+}
+
+var bitkubCodePattern = regexp.MustCompile(`(?i)\berror(?:\s*code)?\s*[:=]?\s*(\d{1,3})\b`)
+
+// ClassifyError maps a go-bitkub error to a ToolError. The client surfaces
+// Bitkub's numeric error code in the error text, transport failures as net
+// errors and throttling as HTTP 429.
+func ClassifyError(err error) *ToolError {
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		return toolErr
+	}
+
+	msg := err.Error()
+	lower := strings.ToLower(msg)
+
+	if m := bitkubCodePattern.FindStringSubmatch(msg); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if known, ok := bitkubErrorCodes[n]; ok {
+			e := NewError(known.code, "%s (bitkub error %d)", known.message, n)
+			e.BitkubCode = n
+			return e
+		}
+	}
+
+	var netErr net.Error
+	switch {
+	case strings.Contains(lower, "429") || strings.Contains(lower, "too many requests") || strings.Contains(lower, "rate limit"):
+		return NewError(CodeRateLimited, "%s", msg)
+	case errors.As(err, &netErr),
+		strings.Contains(lower, "connection refused"),
+		strings.Contains(lower, "timeout"),
+		strings.Contains(lower, "no such host"),
+		strings.Contains(lower, "502"),
+		strings.Contains(lower, "503"),
+		strings.Contains(lower, "504"):
+		return NewError(CodeExchangeUnavailable, "%s", msg)
+	case strings.Contains(lower, "unauthorized") || strings.Contains(lower, "api key") || strings.Contains(lower, "signature"):
+		return NewError(CodeAuthRequired, "%s", msg)
+	}
+
+	return NewError(CodeInternal, "%s", msg)
+}
@@ -7,8 +7,26 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// traceHook adds the active trace and span IDs to events logged with .Ctx(ctx).
+type traceHook struct{}
+
+func (traceHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	ctx := e.GetCtx()
+	if ctx == nil {
+		return
+	}
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	e.Str("trace_id", sc.TraceID().String()).Str("span_id", sc.SpanID().String())
+}
+
 func InitLogger() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 
@@ -40,5 +58,5 @@ func InitLogger() {
 	}
 
 	zerolog.SetGlobalLevel(level)
-	log.Logger = zerolog.New(output).With().Timestamp().Logger()
+	log.Logger = zerolog.New(output).With().Timestamp().Logger().Hook(traceHook{})
 }
@@ -0,0 +1,311 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// WithInteger adds an integer property. mcp-go only ships a number builder,
+// and the schema type is what BindArgs validates against.
+func WithInteger(name string, opts ...mcp.PropertyOption) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		schema := map[string]any{
+			"type": "integer",
+		}
+
+		for _, opt := range opts {
+			opt(schema)
+		}
+
+		if required, ok := schema["required"].(bool); ok && required {
+			delete(schema, "required")
+			t.InputSchema.Required = append(t.InputSchema.Required, name)
+		}
+
+		t.InputSchema.Properties[name] = schema
+	}
+}
+
+func EnumNumbers(values ...float64) mcp.PropertyOption {
+	return func(schema map[string]any) {
+		schema["enum"] = values
+	}
+}
+
+func ExclusiveMin(min float64) mcp.PropertyOption {
+	return func(schema map[string]any) {
+		schema["exclusiveMinimum"] = min
+	}
+}
+
+// InvalidField reports a constraint the schema cannot express, such as one
+// argument depending on another.
+func InvalidField(field, message string) *ToolError {
+	return NewError(CodeInvalidArgument, "%s: %s", field, message).
+		With("errors", []FieldError{{Field: field, Message: message}})
+}
+
+// BindArgs checks the call arguments against the input schema the tool
+// advertises, fills in schema defaults and decodes the result into target.
+// Every violation is reported with its field path.
+func BindArgs(tool mcp.Tool, request mcp.CallToolRequest, target any) *ToolError {
+	var args map[string]any
+	if err := request.BindArguments(&args); err != nil {
+		return NewError(CodeInvalidArgument, "arguments must be a JSON object")
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": tool.InputSchema.Properties,
+		"required":   tool.InputSchema.Required,
+	}
+
+	var errs []FieldError
+	values := validateValue("", args, schema, &errs)
+
+	if len(errs) > 0 {
+		e := NewError(CodeInvalidArgument, "%s: %s", errs[0].Field, errs[0].Message)
+		if len(errs) > 1 {
+			e.Message += fmt.Sprintf(" (and %d more)", len(errs)-1)
+		}
+		return e.With("errors", errs)
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return NewError(CodeInvalidArgument, "encode arguments: %v", err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return NewError(CodeInvalidArgument, "decode arguments: %v", err)
+	}
+
+	return nil
+}
+
+func validateValue(path string, value any, schema map[string]any, errs *[]FieldError) any {
+	fail := func(format string, a ...any) any {
+		field := path
+		if field == "" {
+			field = "arguments"
+		}
+		*errs = append(*errs, FieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+		return value
+	}
+
+	if enum, ok := schema["enum"]; ok && !inEnum(value, enum) {
+		return fail("must be one of %s", formatEnum(enum))
+	}
+
+	switch schema["type"] {
+	case "number", "integer":
+		n, ok := toFloat(value)
+		if !ok {
+			return fail("must be a number")
+		}
+		if schema["type"] == "integer" && n != math.Trunc(n) {
+			return fail("must be an integer")
+		}
+		if min, ok := toFloat(schema["minimum"]); ok && n < min {
+			return fail("must be >= %v", min)
+		}
+		if max, ok := toFloat(schema["maximum"]); ok && n > max {
+			return fail("must be <= %v", max)
+		}
+		if min, ok := toFloat(schema["exclusiveMinimum"]); ok && n <= min {
+			return fail("must be > %v", min)
+		}
+		if max, ok := toFloat(schema["exclusiveMaximum"]); ok && n >= max {
+			return fail("must be < %v", max)
+		}
+		if step, ok := toFloat(schema["multipleOf"]); ok && step > 0 {
+			if r := math.Abs(math.Remainder(n, step)); r > step*1e-9 {
+				return fail("must be a multiple of %v", step)
+			}
+		}
+		return n
+
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		if min, ok := toFloat(schema["minLength"]); ok && float64(len(s)) < min {
+			return fail("must be at least %v characters", min)
+		}
+		if max, ok := toFloat(schema["maxLength"]); ok && float64(len(s)) > max {
+			return fail("must be at most %v characters", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+				return fail("must match %s", pattern)
+			}
+		}
+		return s
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("must be a boolean")
+		}
+		return value
+
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fail("must be an array")
+		}
+		if min, ok := toFloat(schema["minItems"]); ok && float64(len(items)) < min {
+			return fail("must contain at least %v items", min)
+		}
+		if max, ok := toFloat(schema["maxItems"]); ok && float64(len(items)) > max {
+			return fail("must contain at most %v items", max)
+		}
+
+		itemSchema, _ := schema["items"].(map[string]any)
+		if itemSchema == nil {
+			return items
+		}
+
+		out := make([]any, len(items))
+		for i, item := range items {
+			out[i] = validateValue(fmt.Sprintf("%s[%d]", path, i), item, itemSchema, errs)
+		}
+		return out
+
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fail("must be an object")
+		}
+		if min, ok := toFloat(schema["minProperties"]); ok && float64(len(obj)) < min {
+			return fail("must contain at least %v entries", min)
+		}
+
+		out := make(map[string]any, len(obj))
+		for k, v := range obj {
+			out[k] = v
+		}
+
+		properties, _ := schema["properties"].(map[string]any)
+		required := schemaStrings(schema["required"])
+
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			propSchema, _ := properties[name].(map[string]any)
+			v, present := obj[name]
+			switch {
+			case present && v != nil:
+				out[name] = validateValue(joinPath(path, name), v, propSchema, errs)
+			case slices.Contains(required, name):
+				*errs = append(*errs, FieldError{Field: joinPath(path, name), Message: "is required"})
+			case propSchema["default"] != nil:
+				out[name] = propSchema["default"]
+			}
+		}
+
+		if extra, ok := schema["additionalProperties"].(map[string]any); ok {
+			keys := make([]string, 0, len(obj))
+			for k := range obj {
+				if _, known := properties[k]; !known {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				out[k] = validateValue(joinPath(path, k), obj[k], extra, errs)
+			}
+		}
+
+		return out
+	}
+
+	return value
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func inEnum(value any, enum any) bool {
+	if s, ok := value.(string); ok {
+		return slices.Contains(schemaStrings(enum), s)
+	}
+
+	n, ok := toFloat(value)
+	if !ok {
+		return false
+	}
+	switch values := enum.(type) {
+	case []float64:
+		return slices.Contains(values, n)
+	case []any:
+		for _, v := range values {
+			if f, ok := toFloat(v); ok && f == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func formatEnum(enum any) string {
+	if values, ok := enum.([]float64); ok {
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = fmt.Sprint(v)
+		}
+		return strings.Join(parts, ", ")
+	}
+	return strings.Join(schemaStrings(enum), ", ")
+}
+
+func schemaStrings(v any) []string {
+	switch s := v.(type) {
+	case []string:
+		return s
+	case []any:
+		out := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	ScopeMarket  = "market"
+	ScopeAccount = "account"
+	ScopeTrade   = "trade"
+	// ScopeAdmin gates no tool; it lets get_audit_log read every principal's
+	// calls.
+	ScopeAdmin = "admin"
+)
+
+const (
+	metaTrading   = "trading"
+	metaScope     = "scope"
+	metaSimulates = "simulates"
+)
+
+func setMeta(t *mcp.Tool, key string, value any) {
+	if t.Meta == nil {
+		t.Meta = &mcp.Meta{AdditionalFields: map[string]any{}}
+	}
+	if t.Meta.AdditionalFields == nil {
+		t.Meta.AdditionalFields = map[string]any{}
+	}
+	t.Meta.AdditionalFields[key] = value
+}
+
+func WithScope(scope string) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		setMeta(t, metaScope, scope)
+	}
+}
+
+func WithTrading() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		setMeta(t, metaTrading, true)
+		setMeta(t, metaScope, ScopeTrade)
+		t.Annotations.ReadOnlyHint = mcp.ToBoolPtr(false)
+		t.Annotations.DestructiveHint = mcp.ToBoolPtr(true)
+	}
+}
+
+// WithSimulation marks a trading tool that can also run without touching the
+// exchange. The kill switch middleware lets it through, and its handler checks
+// the halt itself before it trades for real.
+func WithSimulation() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		setMeta(t, metaSimulates, true)
+	}
+}
+
+func Simulates(tool mcp.Tool) bool {
+	if tool.Meta == nil {
+		return false
+	}
+	simulates, _ := tool.Meta.AdditionalFields[metaSimulates].(bool)
+	return simulates
+}
+
+func IsTradingTool(tool mcp.Tool) bool {
+	if tool.Meta == nil {
+		return false
+	}
+	trading, _ := tool.Meta.AdditionalFields[metaTrading].(bool)
+	return trading
+}
+
+func ToolScope(tool mcp.Tool) string {
+	if tool.Meta != nil {
+		if scope, ok := tool.Meta.AdditionalFields[metaScope].(string); ok && scope != "" {
+			return scope
+		}
+	}
+	return ScopeMarket
+}